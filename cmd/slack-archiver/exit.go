@@ -0,0 +1,202 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/deptofdefense/slack-archiver/pkg/container"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/ziputil"
+)
+
+// The exit codes of slack-archiver, which are documented in the README.
+const (
+	ExitError             = 1   // any other error
+	ExitUsage             = 2   // invalid flags, arguments, or configuration
+	ExitMissingFile       = 3   // the source or a file in it is missing
+	ExitInvalidJSON       = 4   // a file in the source is not valid JSON
+	ExitNotEnterpriseGrid = 5   // the source is not an export of an enterprise grid
+	ExitUnknownFormat     = 6   // the source is not a zip, tar, gzip, or zstd file
+	ExitNetwork           = 7   // a request to Slack or object storage failed
+	ExitInvalidArchive    = 8   // the source has validation errors or does not match its seal
+	ExitAuth              = 9   // Slack or object storage rejected the credentials or denied access
+	ExitInterrupted       = 130 // the command was interrupted by a signal
+)
+
+// exitError is an error with the exit code it should cause.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// usageError marks the error as caused by the flags, arguments, or configuration of the command.
+func usageError(err error) error {
+	return &exitError{code: ExitUsage, err: err}
+}
+
+// invalidArchiveError marks the error as caused by an archive that failed validation or verification.
+func invalidArchiveError(err error) error {
+	return &exitError{code: ExitInvalidArchive, err: err}
+}
+
+// statusError is a response with a status code that is not 2xx, e.g., from a request to download a file from Slack.
+type statusError struct {
+	url        string
+	statusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s from %q", e.statusCode, http.StatusText(e.statusCode), e.url)
+}
+
+// isAuthStatus returns true if the status code means the credentials were rejected or access was denied.
+func isAuthStatus(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden
+}
+
+// s3AuthCodes are the codes of the errors returned by S3-compatible object storage when access is denied.
+var s3AuthCodes = map[string]struct{}{
+	"AccessDenied":          {},
+	"AllAccessDisabled":     {},
+	"ExpiredToken":          {},
+	"InvalidAccessKeyId":    {},
+	"InvalidToken":          {},
+	"SignatureDoesNotMatch": {},
+}
+
+// exitCodes are the names of the exit codes used in errors written as JSON.
+var exitCodes = map[int]string{
+	ExitError:             "error",
+	ExitUsage:             "usage",
+	ExitMissingFile:       "missing_file",
+	ExitInvalidJSON:       "invalid_json",
+	ExitNotEnterpriseGrid: "not_enterprise_grid",
+	ExitUnknownFormat:     "unknown_format",
+	ExitNetwork:           "network",
+	ExitInvalidArchive:    "invalid_archive",
+	ExitAuth:              "auth",
+	ExitInterrupted:       "interrupted",
+}
+
+// exitCode returns the exit code for the error.
+// An interrupt takes precedence, since a canceled request or read can also cause the other errors.
+func exitCode(err error) int {
+	var ee *exitError
+	var se *statusError
+	var s3Error minio.ErrorResponse
+	var urlError *url.Error
+	var opError *net.OpError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.As(err, &ee):
+		return ee.code
+	case errors.Is(err, slack.ErrNotEnterpriseGrid):
+		return ExitNotEnterpriseGrid
+	case errors.Is(err, slack.ErrMissingFile), errors.Is(err, fs.ErrNotExist):
+		return ExitMissingFile
+	case errors.Is(err, slack.ErrInvalidJSON):
+		return ExitInvalidJSON
+	case errors.Is(err, container.ErrUnknownFormat):
+		return ExitUnknownFormat
+	case errors.As(err, &se):
+		if isAuthStatus(se.statusCode) {
+			return ExitAuth
+		}
+		return ExitNetwork
+	case errors.As(err, &s3Error):
+		if _, ok := s3AuthCodes[s3Error.Code]; ok || isAuthStatus(s3Error.StatusCode) {
+			return ExitAuth
+		}
+		return ExitNetwork
+	case errors.As(err, &urlError), errors.As(err, &opError):
+		return ExitNetwork
+	}
+	return ExitError
+}
+
+// jsonError is an error written as JSON to stderr when --error-format is json.
+type jsonError struct {
+	Error    string `json:"error"`
+	Code     string `json:"code"`
+	ExitCode int    `json:"exit_code"`
+	File     string `json:"file,omitempty"`
+	Offset   int64  `json:"offset,omitempty"`
+}
+
+// writeError writes the error in the format, either text or json, and returns the exit code.
+func writeError(w io.Writer, format string, err error) int {
+	code := exitCode(err)
+	if format != "json" {
+		_, _ = fmt.Fprintln(w, "slack-archiver: "+err.Error())
+		_, _ = fmt.Fprintln(w, "Try slack-archiver --help for more information.")
+		return code
+	}
+	je := &jsonError{
+		Error:    err.Error(),
+		Code:     exitCodes[code],
+		ExitCode: code,
+	}
+	var fileError *ziputil.FileError
+	if errors.As(err, &fileError) {
+		je.File = fileError.Name
+		je.Offset = fileError.Offset
+	}
+	_ = json.NewEncoder(w).Encode(je)
+	return code
+}
+
+// skippedFiles is the summary of the day files and conversations skipped with --continue-on-error written as JSON.
+type skippedFiles struct {
+	Skipped int                  `json:"skipped"`
+	Files   []*slack.SkippedFile `json:"files"`
+}
+
+// writeSkippedFiles writes a summary of the day files and conversations skipped by the collector in the error format, if any were skipped.
+func writeSkippedFiles(w io.Writer, format string, collector *slack.ErrorCollector) {
+	if collector == nil {
+		return
+	}
+	skipped := collector.Skipped()
+	if len(skipped) == 0 {
+		return
+	}
+	if format == "json" {
+		_ = json.NewEncoder(w).Encode(&skippedFiles{Skipped: len(skipped), Files: skipped})
+		return
+	}
+	_, _ = fmt.Fprintf(w, "slack-archiver: skipped %d day files or conversations that could not be read:\n", len(skipped))
+	for _, f := range skipped {
+		if len(f.File) == 0 {
+			_, _ = fmt.Fprintf(w, "  %s %q: %s\n", f.Type, f.Conversation, f.Error)
+		} else if f.Offset > 0 {
+			_, _ = fmt.Fprintf(w, "  %s at byte %d: %s\n", f.File, f.Offset, f.Error)
+		} else {
+			_, _ = fmt.Fprintf(w, "  %s: %s\n", f.File, f.Error)
+		}
+	}
+}
@@ -8,21 +8,46 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"path"
+	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-
+	"gopkg.in/yaml.v2"
+
+	"github.com/deptofdefense/slack-archiver/pkg/blob"
+	"github.com/deptofdefense/slack-archiver/pkg/cryptutil"
+	"github.com/deptofdefense/slack-archiver/pkg/diff"
+	"github.com/deptofdefense/slack-archiver/pkg/export"
+	_ "github.com/deptofdefense/slack-archiver/pkg/export/mattermost"
+	"github.com/deptofdefense/slack-archiver/pkg/extract"
+	"github.com/deptofdefense/slack-archiver/pkg/merge"
+	"github.com/deptofdefense/slack-archiver/pkg/output"
+	"github.com/deptofdefense/slack-archiver/pkg/policy"
+	"github.com/deptofdefense/slack-archiver/pkg/progress"
+	"github.com/deptofdefense/slack-archiver/pkg/pseudonymize"
+	"github.com/deptofdefense/slack-archiver/pkg/query"
+	"github.com/deptofdefense/slack-archiver/pkg/redact"
+	"github.com/deptofdefense/slack-archiver/pkg/seal"
 	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/source"
+	"github.com/deptofdefense/slack-archiver/pkg/stats"
+	"github.com/deptofdefense/slack-archiver/pkg/validate"
 )
 
 const (
@@ -30,40 +55,738 @@ const (
 )
 
 const (
-	FlagSource      = "src"
-	FlagDestination = "dest"
-	FlagOverwrite   = "overwrite"
-	FlagVersion     = "version"
+	FlagSource              = "src"
+	FlagSources             = "sources"
+	FlagDestination         = "dest"
+	FlagOverwrite           = "overwrite"
+	FlagFiles               = "files"
+	FlagLayout              = "layout"
+	FlagFormat              = "format"
+	FlagTop                 = "top"
+	FlagOld                 = "old"
+	FlagNew                 = "new"
+	FlagMode                = "mode"
+	FlagKeyFile             = "key-file"
+	FlagRules               = "rules"
+	FlagPatterns            = "patterns"
+	FlagDictionary          = "dictionary"
+	FlagRemoveUsers         = "remove-users"
+	FlagRemoveConversations = "remove-conversations"
+	FlagLog                 = "log"
+	FlagMapping             = "mapping"
+	FlagIDs                 = "ids"
+	FlagCustodian           = "custodian"
+	FlagPolicy              = "policy"
+	FlagDryRun              = "dry-run"
+	FlagAsOf                = "as-of"
+	FlagSince               = "since"
+	FlagUntil               = "until"
+	FlagParallelism         = "parallelism"
+	FlagOrdered             = "ordered"
+	FlagContinueOnError     = "continue-on-error"
+	FlagMaxErrors           = "max-errors"
+	FlagLogLevel            = "log-level"
+	FlagLogFormat           = "log-format"
+	FlagErrorFormat         = "error-format"
+	FlagOutput              = "output"
+	FlagFields              = "fields"
+	FlagSort                = "sort"
+	FlagOut                 = "out"
+	FlagTemplate            = "template"
+	FlagWhere               = "where"
+	FlagConfig              = "config"
+	FlagProfile             = "profile"
+	FlagTokenFile           = "token-file"
+	FlagRecipient           = "recipient"
+	FlagIdentity            = "identity"
+	FlagPassphraseFile      = "passphrase-file"
+	FlagManifest            = "manifest"
+	FlagPublicKey           = "public-key"
+	FlagS3Endpoint          = "s3-endpoint"
+	FlagS3Region            = "s3-region"
+	FlagVersion             = "version"
+)
+
+const (
+	// KeyFilters is the key of the config file with the list of expressions that select records, in addition to --where.
+	KeyFilters = "filters"
 )
 
-func initListFlags(flag *pflag.FlagSet) {
-	flag.StringP(FlagSource, "s", "", "path to Slack zip file")
+func initRootFlags(flag *pflag.FlagSet) {
+	flag.String(FlagConfig, "", "path to YAML or TOML config file with the default values of flags and named profiles")
+	flag.String(FlagProfile, "", "name of the profile in the config file to use (defaults to the profile named in the config file)")
+	flag.StringSlice(FlagIdentity, []string{}, "paths to age or OpenPGP private keys used to open an encrypted source")
+	flag.String(FlagPassphraseFile, "", "path to file with the passphrase of the OpenPGP private keys")
+	flag.String(FlagS3Endpoint, "", "endpoint of S3-compatible object storage for s3:// sources and destinations, prefixed with http:// to disable TLS (defaults to Amazon S3)")
+	flag.String(FlagS3Region, "", "region of S3-compatible object storage")
+	flag.String(FlagLogLevel, "", "log level, either debug, info, warn, or error (defaults to warn on a terminal and info otherwise)")
+	flag.String(FlagLogFormat, "auto", "log format, either auto, text, or json, where auto uses text on a terminal and json otherwise")
+	flag.String(FlagErrorFormat, "text", "format of the error written to stderr when a command fails, either text or json")
+	flag.StringP(FlagSource, "s", "", "path or s3:// URL of Slack zip file, extracted directory, or tar file, or \"-\" for stdin")
+	flag.String(FlagDestination, "", "path or s3:// URL of the output archive or file, or of the directory files are downloaded to")
+	flag.Bool(FlagOverwrite, false, "overwrite the destination, manifest, or downloaded files if they already exist")
+	flag.StringSlice(FlagRecipient, []string{}, "age public keys, or paths to age or OpenPGP public keys, to encrypt the output to")
 	flag.BoolP(FlagVersion, "v", false, "show version")
 }
 
+func initTimeRangeFlags(flag *pflag.FlagSet) {
+	flag.String(FlagSince, "", "only include messages sent on or after the date, formatted as YYYY-MM-DD")
+	flag.String(FlagUntil, "", "only include messages sent on or before the date, formatted as YYYY-MM-DD")
+}
+
+func initParallelismFlags(flag *pflag.FlagSet) {
+	flag.Int(FlagParallelism, 1, "number of conversations decoded at once, or 0 for the number of CPUs")
+}
+
+func initOutputFlags(flag *pflag.FlagSet) {
+	flag.StringP(FlagOutput, "o", output.FormatNDJSON, "output format, either "+strings.Join(output.Formats, ", "))
+	flag.StringSlice(FlagFields, []string{}, "only output these fields, by JSON name or dotted path, e.g., id,name,profile.email")
+	flag.StringSlice(FlagSort, []string{}, "sort by these fields, each prefixed with \"-\" to sort in descending order (holds all records in memory)")
+	flag.String(FlagOut, "", "path to file to write the output to instead of stdout")
+	flag.String(FlagTemplate, "", "Go template used to output each record, e.g., '{{.Name}}', instead of the output format")
+}
+
+var (
+	// fileVariables are the records of each file listed or downloaded
+	fileVariables = []string{query.VariableMessage, query.VariableFile, query.VariableUser, query.VariableChannel, query.VariableTeam}
+	// messageVariables are the records of each message exported
+	messageVariables = []string{query.VariableMessage, query.VariableUser, query.VariableChannel, query.VariableTeam}
+)
+
+// initWhereFlag adds the flag with the expression that selects the records, which can reference the variables.
+func initWhereFlag(flag *pflag.FlagSet, records string, variables ...string) {
+	flag.String(FlagWhere, "", fmt.Sprintf("only include the %s for which the expression is true, which can reference %s", records, strings.Join(variables, ", ")))
+}
+
+func initContinueOnErrorFlags(flag *pflag.FlagSet) {
+	flag.Bool(FlagContinueOnError, false, "skip day files that cannot be read, and write a summary of the skipped files to stderr")
+	flag.Int(FlagMaxErrors, 0, "stop once more than this many day files are skipped, or 0 for no limit (requires --continue-on-error)")
+}
+
+// initLayoutFlag adds the flag with the layout of the directory files are downloaded to.
+func initLayoutFlag(flag *pflag.FlagSet) {
+	flag.String(FlagLayout, string(slack.LayoutPartitioned), "layout of the directory files are downloaded to, either "+strings.Join(slack.Layouts, " or "))
+}
+
 func initDownloadFilesFlags(flag *pflag.FlagSet) {
-	flag.String(FlagSource, "", "path to Slack zip file")
-	flag.String(FlagDestination, "", "path to where to download files")
-	flag.Bool(FlagOverwrite, false, "overwrite existing files")
-	flag.BoolP(FlagVersion, "v", false, "show version")
+	initLayoutFlag(flag)
+	flag.String(FlagTokenFile, "", "path to file with a Slack token used to download private files")
+}
+
+func initExportFlags(flag *pflag.FlagSet) {
+	flag.String(FlagFiles, "", "path to files downloaded with \"download files\", used for attachments")
+	initLayoutFlag(flag)
+}
+
+func initStatsFlags(flag *pflag.FlagSet) {
+	flag.StringP(FlagFormat, "f", "json", "output format, either json or table")
+	flag.Int(FlagTop, 10, "number of reactions and days to include in leaderboards")
+}
+
+func initDiffFlags(flag *pflag.FlagSet) {
+	flag.String(FlagOld, "", "path to old Slack zip file or extracted directory")
+	flag.String(FlagNew, "", "path to new Slack zip file or extracted directory")
+}
+
+func initMergeFlags(flag *pflag.FlagSet) {
+	flag.StringSlice(FlagSources, []string{}, "paths or s3:// URLs of Slack zip files or extracted directories, from oldest to newest")
+}
+
+func initRedactFlags(flag *pflag.FlagSet) {
+	flag.String(FlagMode, string(redact.ModeMask), "redaction mode, either mask or hash")
+	flag.String(FlagKeyFile, "", "path to file with the key used when hashing values")
+	flag.StringSlice(FlagRules, []string{"ssn", "phone", "dodid"}, fmt.Sprintf("builtin rules applied to message text, from %s", strings.Join(redact.BuiltinRuleNames(), ", ")))
+	flag.StringSlice(FlagPatterns, []string{}, "additional rules applied to message text, formatted as name=expression")
+	flag.String(FlagDictionary, "", "path to file with words to redact from message text, one per line")
+	flag.StringSlice(FlagRemoveUsers, []string{}, "ids of users to remove")
+	flag.StringSlice(FlagRemoveConversations, []string{}, "ids or names of conversations to remove")
+	flag.String(FlagLog, "", "path to redaction log (defaults to stdout)")
+}
+
+func initPseudonymizeFlags(flag *pflag.FlagSet) {
+	flag.String(FlagKeyFile, "", "path to file with the secret key used to derive pseudonyms")
+	flag.String(FlagMapping, "", "path to write the encrypted mapping from users to pseudonyms")
+}
+
+func initPseudonymizeRevealFlags(flag *pflag.FlagSet) {
+	flag.String(FlagKeyFile, "", "path to file with the secret key used to derive pseudonyms")
+	flag.String(FlagMapping, "", "path to the encrypted mapping from users to pseudonyms")
+	flag.StringSlice(FlagIDs, []string{}, "user ids or pseudonym ids to reveal (defaults to all)")
+}
+
+func initExtractFlags(flag *pflag.FlagSet) {
+	flag.StringSlice(FlagCustodian, []string{}, "ids of the custodians")
+}
+
+func initApplyPolicyFlags(flag *pflag.FlagSet) {
+	flag.String(FlagPolicy, "", "path to YAML policy file")
+	flag.Bool(FlagDryRun, false, "report what the policy would do without writing a pruned archive")
+	flag.String(FlagAsOf, "", "date the policy is evaluated as of, formatted as YYYY-MM-DD (defaults to now)")
+}
+
+func initSealFlags(flag *pflag.FlagSet) {
+	flag.String(FlagFiles, "", "path to files downloaded with \"download files\" to include in the manifest")
+	flag.String(FlagKeyFile, "", "path to PEM encoded ed25519 private key used to sign the manifest")
+	flag.String(FlagManifest, "", "path to write the signed manifest (defaults to stdout)")
+}
+
+func initVerifySealFlags(flag *pflag.FlagSet) {
+	flag.String(FlagFiles, "", "path to files downloaded with \"download files\" that were included in the manifest")
+	flag.String(FlagManifest, "", "path to the signed manifest")
+	flag.String(FlagPublicKey, "", "path to PEM encoded ed25519 public key used to verify the manifest")
 }
 
 func initViper(cmd *cobra.Command) (*viper.Viper, error) {
+	return newViper(cmd.Flags())
+}
+
+// newViper binds the flag sets and environment variables, and then reads the config file, if any.
+// Flags that are set take precedence over environment variables, which take precedence over the config file and then the defaults of the flags.
+func newViper(flagSets ...*pflag.FlagSet) (*viper.Viper, error) {
 	v := viper.New()
-	err := v.BindPFlags(cmd.Flags())
-	if err != nil {
-		return v, fmt.Errorf("error binding flag set to viper: %w", err)
+	for _, flagSet := range flagSets {
+		err := v.BindPFlags(flagSet)
+		if err != nil {
+			return v, fmt.Errorf("error binding flag set to viper: %w", err)
+		}
 	}
 	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	v.AutomaticEnv() // set environment variables to overwrite config
+	err := readConfig(v)
+	if err != nil {
+		return v, err
+	}
 	return v, nil
 }
 
+// readConfig reads the config file into the config of v.  The keys of the config file are the names of flags.
+// The settings of the profile, which is named by the profile flag or the profile key of the config file,
+// take precedence over the settings at the top of the config file.  Profiles are under the profiles key, e.g.,
+//
+//	parallelism: 4
+//	profile: production
+//	profiles:
+//	  production:
+//	    src: s3://exports/export.zip
+//	    dest: s3://exports/files
+//	    layout: id
+//	    filters:
+//	    - channel.type == "channel"
+//
+// The filters are expressions that are combined with --where by commands that select records.
+func readConfig(v *viper.Viper) error {
+	path := v.GetString(FlagConfig)
+	if len(path) == 0 {
+		return nil
+	}
+
+	c := viper.New()
+	c.SetConfigFile(path)
+	err := c.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("error reading config file %q: %w", path, err)
+	}
+
+	settings := c.AllSettings()
+	profiles, ok := settings["profiles"].(map[string]interface{})
+	if !ok && settings["profiles"] != nil {
+		return fmt.Errorf("invalid config file %q: profiles is not a map", path)
+	}
+	delete(settings, "profiles")
+	delete(settings, FlagConfig)
+
+	name := v.GetString(FlagProfile)
+	if len(name) == 0 {
+		name = c.GetString(FlagProfile)
+	}
+	if len(name) > 0 {
+		profile, ok := profiles[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("profile %q is not in config file %q", name, path)
+		}
+		for key, value := range profile {
+			settings[key] = value
+		}
+		settings[FlagProfile] = name
+	}
+
+	if filters, ok := settings[KeyFilters]; ok {
+		if _, ok := filters.([]interface{}); !ok {
+			return fmt.Errorf("invalid config file %q: filters is not a list", path)
+		}
+	}
+
+	err = v.MergeConfigMap(settings)
+	if err != nil {
+		return fmt.Errorf("error merging config file %q: %w", path, err)
+	}
+	return nil
+}
+
 func checkConfig(v *viper.Viper) error {
 	src := v.GetString(FlagSource)
 	if len(src) == 0 {
 		return fmt.Errorf("src is missing")
 	}
+	return checkWalkConfig(v)
+}
+
+func checkListConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	return initOutputOptions(v).Validate()
+}
+
+func checkWalkConfig(v *viper.Viper) error {
+	if v.GetInt(FlagParallelism) < 0 {
+		return fmt.Errorf("parallelism must be zero or greater")
+	}
+	if maxErrors := v.GetInt(FlagMaxErrors); maxErrors < 0 {
+		return fmt.Errorf("max-errors must be zero or greater")
+	} else if maxErrors > 0 && !v.GetBool(FlagContinueOnError) {
+		return fmt.Errorf("max-errors requires continue-on-error")
+	}
+	since, until, err := initTimeRange(v)
+	if err != nil {
+		return err
+	}
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		return fmt.Errorf("since %q is after until %q", v.GetString(FlagSince), v.GetString(FlagUntil))
+	}
+	return nil
+}
+
+// initLogger returns a logger that writes to w with the level and format from the root flags.
+// If the level or format is not given, then it depends on if stderr is a terminal.
+func initLogger(v *viper.Viper, w io.Writer) (*slog.Logger, error) {
+	terminal := progress.IsTerminal(os.Stderr)
+
+	level := slog.LevelInfo
+	if terminal {
+		level = slog.LevelWarn
+	}
+	if str := v.GetString(FlagLogLevel); len(str) > 0 {
+		err := level.UnmarshalText([]byte(str))
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level %q, expecting debug, info, warn, or error", str)
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	switch format := v.GetString(FlagLogFormat); format {
+	case "", "auto":
+		if terminal {
+			return slog.New(slog.NewTextHandler(w, options)), nil
+		}
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expecting auto, text, or json", format)
+	}
+}
+
+// initWalkOptions returns the options for walking conversations, which are checked by checkWalkConfig.
+func initWalkOptions(v *viper.Viper) *slack.WalkOptions {
+	since, until, _ := initTimeRange(v)
+	parallelism := v.GetInt(FlagParallelism)
+	if parallelism == 0 {
+		parallelism = runtime.NumCPU()
+	}
+	return &slack.WalkOptions{
+		Parallelism: parallelism,
+		Ordered:     v.GetBool(FlagOrdered),
+		Since:       since,
+		Until:       until,
+	}
+}
+
+// initOutputOptions returns the options for writing the records of a list command, which are checked by checkListConfig.
+func initOutputOptions(v *viper.Viper) *output.Options {
+	return &output.Options{
+		Format:   v.GetString(FlagOutput),
+		Fields:   v.GetStringSlice(FlagFields),
+		Sort:     v.GetStringSlice(FlagSort),
+		Template: v.GetString(FlagTemplate),
+	}
+}
+
+// initQuery compiles the expression of --where and the filters of the config file with the variables of the command,
+// which must all be true for a record to be selected, or returns nil if no expression is given.
+func initQuery(v *viper.Viper, variables ...string) (*query.Query, error) {
+	expressions := make([]string, 0)
+	for _, expression := range append(v.GetStringSlice(KeyFilters), v.GetString(FlagWhere)) {
+		if len(strings.TrimSpace(expression)) > 0 {
+			expressions = append(expressions, "("+expression+")")
+		}
+	}
+	if len(expressions) == 0 {
+		return nil, nil
+	}
+	return query.Compile(strings.Join(expressions, " && "), variables...)
+}
+
+// initErrorCollector returns the collector of the day files skipped with --continue-on-error, or nil if an unreadable day file stops the command.
+func initErrorCollector(v *viper.Viper, options *slack.WalkOptions) *slack.ErrorCollector {
+	if !v.GetBool(FlagContinueOnError) {
+		return nil
+	}
+	collector := &slack.ErrorCollector{MaxErrors: v.GetInt(FlagMaxErrors)}
+	options.OnError = collector.Collect
+	return collector
+}
+
+// initTimeRange returns the times messages are included from and before.
+// Since the until date is inclusive, messages are included before the start of the next day.
+func initTimeRange(v *viper.Viper) (time.Time, time.Time, error) {
+	since, until := time.Time{}, time.Time{}
+	if str := v.GetString(FlagSince); len(str) > 0 {
+		t, err := time.Parse(slack.DayFileDateFormat, str)
+		if err != nil {
+			return since, until, fmt.Errorf("invalid since date %q: %w", str, err)
+		}
+		since = t
+	}
+	if str := v.GetString(FlagUntil); len(str) > 0 {
+		t, err := time.Parse(slack.DayFileDateFormat, str)
+		if err != nil {
+			return since, until, fmt.Errorf("invalid until date %q: %w", str, err)
+		}
+		until = t.AddDate(0, 0, 1)
+	}
+	return since, until, nil
+}
+
+func checkStatsConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	format := v.GetString(FlagFormat)
+	if format != "json" && format != "table" {
+		return fmt.Errorf("invalid format %q, expecting json or table", format)
+	}
+	if v.GetInt(FlagTop) < 0 {
+		return fmt.Errorf("top must be zero or greater")
+	}
+	return nil
+}
+
+func checkDiffConfig(v *viper.Viper) error {
+	oldSource := v.GetString(FlagOld)
+	if len(oldSource) == 0 {
+		return fmt.Errorf("old is missing")
+	}
+	newSource := v.GetString(FlagNew)
+	if len(newSource) == 0 {
+		return fmt.Errorf("new is missing")
+	}
+	return nil
+}
+
+func checkMergeConfig(v *viper.Viper) error {
+	sources := v.GetStringSlice(FlagSources)
+	if len(sources) == 0 {
+		return fmt.Errorf("sources is missing")
+	}
+	dest := v.GetString(FlagDestination)
+	if len(dest) == 0 {
+		return fmt.Errorf("dest is missing")
+	}
+	return nil
+}
+
+func checkRedactConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	dest := v.GetString(FlagDestination)
+	if len(dest) == 0 {
+		return fmt.Errorf("dest is missing")
+	}
+	switch redact.Mode(v.GetString(FlagMode)) {
+	case redact.ModeMask:
+	case redact.ModeHash:
+		if len(v.GetString(FlagKeyFile)) == 0 {
+			return fmt.Errorf("key-file is required when mode is hash")
+		}
+	default:
+		return fmt.Errorf("invalid mode %q, expecting mask or hash", v.GetString(FlagMode))
+	}
+	return nil
+}
+
+func initRedactOptions(v *viper.Viper) (*redact.Options, error) {
+	options := &redact.Options{
+		Mode:                redact.Mode(v.GetString(FlagMode)),
+		RemoveUsers:         v.GetStringSlice(FlagRemoveUsers),
+		RemoveConversations: v.GetStringSlice(FlagRemoveConversations),
+	}
+
+	if keyFile := v.GetString(FlagKeyFile); len(keyFile) > 0 {
+		key, err := readKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		options.Key = key
+	}
+
+	for _, name := range v.GetStringSlice(FlagRules) {
+		rule, err := redact.GetBuiltinRule(name)
+		if err != nil {
+			return nil, err
+		}
+		options.Rules = append(options.Rules, rule)
+	}
+
+	for _, definition := range v.GetStringSlice(FlagPatterns) {
+		rule, err := redact.NewPatternRule(definition)
+		if err != nil {
+			return nil, err
+		}
+		options.Rules = append(options.Rules, rule)
+	}
+
+	if dictionary := v.GetString(FlagDictionary); len(dictionary) > 0 {
+		data, err := os.ReadFile(dictionary)
+		if err != nil {
+			return nil, fmt.Errorf("error reading dictionary %q: %w", dictionary, err)
+		}
+		rule, err := redact.NewDictionaryRule("dictionary", strings.Split(string(data), "\n"))
+		if err != nil {
+			return nil, err
+		}
+		options.Rules = append(options.Rules, rule)
+	}
+
+	return options, nil
+}
+
+func checkPseudonymizeConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	dest := v.GetString(FlagDestination)
+	if len(dest) == 0 {
+		return fmt.Errorf("dest is missing")
+	}
+	if len(v.GetString(FlagKeyFile)) == 0 {
+		return fmt.Errorf("key-file is missing")
+	}
+	if mapping := v.GetString(FlagMapping); len(mapping) > 0 && !v.GetBool(FlagOverwrite) {
+		if _, err := os.Stat(mapping); err == nil {
+			return fmt.Errorf("mapping %q already exists", mapping)
+		}
+	}
+	return nil
+}
+
+func checkPseudonymizeRevealConfig(v *viper.Viper) error {
+	if len(v.GetString(FlagKeyFile)) == 0 {
+		return fmt.Errorf("key-file is missing")
+	}
+	if len(v.GetString(FlagMapping)) == 0 {
+		return fmt.Errorf("mapping is missing")
+	}
+	return nil
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file %q: %w", path, err)
+	}
+	key = []byte(strings.TrimSpace(string(key)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file %q is empty", path)
+	}
+	return key, nil
+}
+
+// initS3Options returns the options for sources and destinations in S3-compatible object storage.
+func initS3Options(v *viper.Viper) blob.S3Options {
+	return blob.S3Options{
+		Endpoint: v.GetString(FlagS3Endpoint),
+		Region:   v.GetString(FlagS3Region),
+	}
+}
+
+// openArchive opens the source, decrypting it with the identities if it is encrypted.
+// Sources that are S3 URLs are read from object storage.
+func openArchive(ctx context.Context, v *viper.Viper, src string) (*slack.Archive, error) {
+	options := &source.Options{
+		S3: initS3Options(v),
+	}
+	if identityFiles := v.GetStringSlice(FlagIdentity); len(identityFiles) > 0 {
+		var passphrase []byte
+		if passphraseFile := v.GetString(FlagPassphraseFile); len(passphraseFile) > 0 {
+			p, err := readKeyFile(passphraseFile)
+			if err != nil {
+				return nil, err
+			}
+			passphrase = p
+		}
+		identities, err := cryptutil.LoadIdentities(identityFiles, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error loading identities: %w", err)
+		}
+		options.Decrypters = append(options.Decrypters, identities)
+	} else if cryptutil.IsEncrypted(src) {
+		return nil, fmt.Errorf("source %q is encrypted, but identity is missing", src)
+	}
+	return source.Open(ctx, src, options)
+}
+
+// initRecipients returns the recipients the output is encrypted to, or nil if the output is not encrypted.
+func initRecipients(v *viper.Viper) (*cryptutil.Recipients, error) {
+	values := v.GetStringSlice(FlagRecipient)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	recipients, err := cryptutil.ParseRecipients(values)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing recipients: %w", err)
+	}
+	return recipients, nil
+}
+
+// partialFile is the file of a destination, which is aborted rather than moved into place if any write fails.
+type partialFile struct {
+	blob.Writer
+	err error // the first error writing
+}
+
+func (f *partialFile) Write(p []byte) (int, error) {
+	n, err := f.Writer.Write(p)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return n, err
+}
+
+func (f *partialFile) Close() error {
+	if f.err != nil {
+		_ = f.Writer.Abort()
+		return f.err
+	}
+	return f.Writer.Close()
+}
+
+// createDestination creates a writer for the destination, which is a local path or an S3 URL,
+// and encrypts everything written to the recipients if any are given.
+// Unless overwrite is set, the destination must not already exist in its store.
+// Everything is written to a partial file next to the destination, or a multipart upload, that is only moved into place
+// once the writer is closed, so a half-written destination is never left behind.
+// The partial file is removed if any write fails or abort is called.
+func createDestination(ctx context.Context, v *viper.Viper, dest string) (io.WriteCloser, func(), error) {
+	recipients, err := initRecipients(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, key, err := blob.NewStore(dest, initS3Options(v))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening destination %q: %w", dest, err)
+	}
+	if len(key) == 0 {
+		return nil, nil, usageError(fmt.Errorf("dest %q is missing the key of the object", dest))
+	}
+	if !v.GetBool(FlagOverwrite) {
+		_, err = store.Stat(ctx, key)
+		if err == nil {
+			return nil, nil, usageError(fmt.Errorf("dest %q already exists", dest))
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("error checking destination %q: %w", dest, err)
+		}
+	}
+	bw, err := store.Create(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating destination %q: %w", dest, err)
+	}
+	f := &partialFile{Writer: bw}
+	abort := func() {
+		_ = bw.Abort()
+	}
+	w, err := cryptutil.NewWriter(dest, f, recipients)
+	if err != nil {
+		abort()
+		return nil, nil, fmt.Errorf("error creating destination %q: %w", dest, err)
+	}
+	return w, abort, nil
+}
+
+// createArchive creates the destination archive, encrypting it to the recipients if any are given.
+// The archive is only moved into place once closed, and abort removes everything written so far.
+func createArchive(ctx context.Context, v *viper.Viper, dest string) (*slack.ArchiveWriter, func(), error) {
+	w, abort, err := createDestination(ctx, v, dest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return slack.NewArchiveWriter(dest, w), abort, nil
+}
+
+func checkExtractConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	dest := v.GetString(FlagDestination)
+	if len(dest) == 0 {
+		return fmt.Errorf("dest is missing")
+	}
+	if len(v.GetStringSlice(FlagCustodian)) == 0 {
+		return fmt.Errorf("custodian is missing")
+	}
+	return nil
+}
+
+func checkApplyPolicyConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	if len(v.GetString(FlagPolicy)) == 0 {
+		return fmt.Errorf("policy is missing")
+	}
+	if !v.GetBool(FlagDryRun) {
+		dest := v.GetString(FlagDestination)
+		if len(dest) == 0 {
+			return fmt.Errorf("dest is missing")
+		}
+	}
+	if asOf := v.GetString(FlagAsOf); len(asOf) > 0 {
+		if _, err := time.Parse(slack.DayFileDateFormat, asOf); err != nil {
+			return fmt.Errorf("invalid as-of date %q: %w", asOf, err)
+		}
+	}
+	return nil
+}
+
+func checkSealConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	if len(v.GetString(FlagKeyFile)) == 0 {
+		return fmt.Errorf("key-file is missing")
+	}
+	if manifest := v.GetString(FlagManifest); len(manifest) > 0 && !v.GetBool(FlagOverwrite) {
+		if _, err := os.Stat(manifest); err == nil {
+			return fmt.Errorf("manifest %q already exists", manifest)
+		}
+	}
+	return nil
+}
+
+func checkVerifySealConfig(v *viper.Viper) error {
+	if err := checkConfig(v); err != nil {
+		return err
+	}
+	if len(v.GetString(FlagManifest)) == 0 {
+		return fmt.Errorf("manifest is missing")
+	}
+	if len(v.GetString(FlagPublicKey)) == 0 {
+		return fmt.Errorf("public-key is missing")
+	}
 	return nil
 }
 
@@ -76,33 +799,1228 @@ func checkDownloadFilesConfig(v *viper.Viper) error {
 	if len(dest) == 0 {
 		return fmt.Errorf("dest is missing")
 	}
-	return nil
+	return checkWalkConfig(v)
+}
+
+// downloadSummary counts the files handled by "download files", which is logged when the download finishes or is interrupted.
+type downloadSummary struct {
+	Files      int
+	TotalBytes int64
+	Downloaded int
+	Bytes      int64
+	Skipped    int
+	Remaining  int
+}
+
+// downloadFile downloads the file at the url to the key in the store, and returns the number of bytes downloaded.
+// If the download fails or the context is canceled, then the partial file is removed from the store.
+// If the token is not blank, then it is sent as a bearer token.  The bytes read are added to the progress of the reporter, which can be nil.
+// Responses with a status code that is not 2xx, such as an error page when the token is missing, are rejected and nothing is written.
+func downloadFile(
+	ctx context.Context,
+	client *http.Client,
+	u *url.URL,
+	token string,
+	store blob.Store,
+	key string,
+	recipients *cryptutil.Recipients,
+	reporter *progress.Reporter,
+) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request for url %q: %w", u.String(), err)
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error downloading from url %q: %w", u.String(), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("error downloading file %q: %w", key, &statusError{url: u.String(), statusCode: resp.StatusCode})
+	}
+
+	w, err := store.Create(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("error creating file %q for url %q: %w", key, u.String(), err)
+	}
+
+	// the file is only committed to the store once closed, so encrypted files are flushed before closing it
+	var dst io.Writer = w
+	var encrypted io.WriteCloser
+	if recipients != nil {
+		encrypted, err = recipients.Encrypt(w)
+		if err != nil {
+			_ = w.Abort()
+			return 0, fmt.Errorf("error encrypting file %q for url %q: %w", key, u.String(), err)
+		}
+		dst = encrypted
+	}
+
+	n, err := io.Copy(dst, reporter.Reader(resp.Body))
+	if err != nil {
+		_ = w.Abort()
+		reporter.Discard(n)
+		return 0, fmt.Errorf("error copying file %q for url %q: %w", key, u.String(), err)
+	}
+
+	if encrypted != nil {
+		err = encrypted.Close()
+		if err != nil {
+			_ = w.Abort()
+			reporter.Discard(n)
+			return 0, fmt.Errorf("error flushing encrypted file %q for url %q: %w", key, u.String(), err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		reporter.Discard(n)
+		return 0, fmt.Errorf("error closing file %q for url %q: %w", key, u.String(), err)
+	}
+
+	return n, nil
 }
 
 func main() {
 
+	// the error format is read with the rest of the configuration, unless the command fails before then
+	errorFormat := ""
+
 	rootCommand := &cobra.Command{
 		Use:                   `slack-archiver [flags]`,
 		DisableFlagsInUseLine: true,
 		Short:                 "slack-archiver is a tool to archive a Slack enterprise grid.",
 		Long:                  "slack-archiver is a tool to archive a Slack enterprise grid.",
-	}
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return usageError(fmt.Errorf("error initializing viper: %w", err))
+			}
+			errorFormat = v.GetString(FlagErrorFormat)
+			if errorFormat != "text" && errorFormat != "json" {
+				return usageError(fmt.Errorf("invalid error format %q, expecting text or json", errorFormat))
+			}
+			logger, err := initLogger(v, os.Stderr)
+			if err != nil {
+				return usageError(err)
+			}
+			slog.SetDefault(logger)
+			return nil
+		},
+	}
+	initRootFlags(rootCommand.PersistentFlags())
+	rootCommand.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError(err)
+	})
+
+	listCommand := &cobra.Command{
+		Use:                   `list`,
+		DisableFlagsInUseLine: true,
+		Short:                 "list data",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+	}
+
+	listFilesCommand := &cobra.Command{
+		Use:                   `files [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "list files",
+		Long:                  "list files",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkListConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			where, err := initQuery(v, fileVariables...)
+			if err != nil {
+				return usageError(err)
+			}
+
+			src := v.GetString(FlagSource)
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+			}
+
+			index := query.NewIndex(enterpriseGrid)
+
+			out, err := output.Create(v.GetString(FlagOut), initOutputOptions(v))
+			if err != nil {
+				return fmt.Errorf("error creating output: %w", err)
+			}
+
+			walkOptions := initWalkOptions(v)
+			collector := initErrorCollector(v, walkOptions)
+			defer writeSkippedFiles(os.Stderr, v.GetString(FlagErrorFormat), collector)
+
+			err = enterpriseGrid.WalkConversations(cmd.Context(), walkOptions, func(c *slack.Conversation, messages []*slack.Message) error {
+				for _, msg := range messages {
+					for k, file := range msg.Files {
+						if where != nil {
+							ok, matchError := where.Match(index.Env(c, msg, &msg.Files[k]))
+							if matchError != nil {
+								return fmt.Errorf("error filtering file %q: %w", file.ID, matchError)
+							}
+							if !ok {
+								continue
+							}
+						}
+						outputError := out.Write(file)
+						if outputError != nil {
+							return fmt.Errorf(
+								"error writing file from %q: %w",
+								fmt.Sprintf("%s%s/%d", c.Prefix, file.Name, k),
+								outputError,
+							)
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				_ = out.Close()
+				return fmt.Errorf("error listing files from %q: %w", src, err)
+			}
+
+			err = out.Close()
+			if err != nil {
+				return fmt.Errorf("error writing output: %w", err)
+			}
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+			return nil
+		},
+	}
+	initTimeRangeFlags(listFilesCommand.Flags())
+	initParallelismFlags(listFilesCommand.Flags())
+	initContinueOnErrorFlags(listFilesCommand.Flags())
+	initOutputFlags(listFilesCommand.Flags())
+	initWhereFlag(listFilesCommand.Flags(), "files", fileVariables...)
+	listFilesCommand.Flags().Bool(FlagOrdered, false, "list files in the order of the conversations, even when decoding conversations in parallel")
+
+	listTeamsCommand := &cobra.Command{
+		Use:                   `teams [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "list teams",
+		Long:                  "list teams",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkListConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			where, err := initQuery(v, query.VariableTeam)
+			if err != nil {
+				return usageError(err)
+			}
+
+			src := v.GetString(FlagSource)
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+			}
+
+			teams := enterpriseGrid.GetTeams()
+
+			out, err := output.Create(v.GetString(FlagOut), initOutputOptions(v))
+			if err != nil {
+				return fmt.Errorf("error creating output: %w", err)
+			}
+			for _, team := range teams {
+				if where != nil {
+					ok, matchError := where.Match(&query.Env{Team: team})
+					if matchError != nil {
+						_ = out.Close()
+						return fmt.Errorf("error filtering team %q: %w", team.Name, matchError)
+					}
+					if !ok {
+						continue
+					}
+				}
+				outputError := out.Write(team)
+				if outputError != nil {
+					_ = out.Close()
+					return fmt.Errorf(
+						"error writing team %q from %q: %w",
+						team.Name,
+						src,
+						outputError,
+					)
+				}
+			}
+
+			err = out.Close()
+			if err != nil {
+				return fmt.Errorf("error writing output: %w", err)
+			}
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+			return nil
+		},
+	}
+	initOutputFlags(listTeamsCommand.Flags())
+	initWhereFlag(listTeamsCommand.Flags(), "teams", query.VariableTeam)
+
+	listCommand.AddCommand(listFilesCommand, listTeamsCommand)
+
+	downloadCommand := &cobra.Command{
+		Use:                   `download`,
+		DisableFlagsInUseLine: true,
+		Short:                 "download data",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+	}
+
+	downloadFilesCommand := &cobra.Command{
+		Use:                   `files [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "download files",
+		Long:                  "download files",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkDownloadFilesConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			where, err := initQuery(v, fileVariables...)
+			if err != nil {
+				return usageError(err)
+			}
+
+			layout, err := slack.ParseLayout(v.GetString(FlagLayout))
+			if err != nil {
+				return usageError(err)
+			}
+
+			src := v.GetString(FlagSource)
+			dest := v.GetString(FlagDestination)
+			overwrite := v.GetBool(FlagOverwrite)
+
+			recipients, err := initRecipients(v)
+			if err != nil {
+				return err
+			}
+
+			token := ""
+			if tokenFile := v.GetString(FlagTokenFile); len(tokenFile) > 0 {
+				b, readError := os.ReadFile(tokenFile)
+				if readError != nil {
+					return fmt.Errorf("error reading token file %q: %w", tokenFile, readError)
+				}
+				token = strings.TrimSpace(string(b))
+			}
+
+			store, prefix, err := blob.NewStore(dest, initS3Options(v))
+			if err != nil {
+				return fmt.Errorf("error opening destination %q: %w", dest, err)
+			}
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+			}
+
+			allFiles := make([]slack.MessageFile, 0)
+			index := query.NewIndex(enterpriseGrid)
+
+			walkOptions := initWalkOptions(v)
+			walkOptions.Ordered = true // download files in the same order, regardless of parallelism
+			collector := initErrorCollector(v, walkOptions)
+			defer writeSkippedFiles(os.Stderr, v.GetString(FlagErrorFormat), collector)
+			err = enterpriseGrid.WalkConversations(cmd.Context(), walkOptions, func(c *slack.Conversation, messages []*slack.Message) error {
+				for _, msg := range messages {
+					for k, file := range msg.Files {
+						if where != nil {
+							ok, matchError := where.Match(index.Env(c, msg, &msg.Files[k]))
+							if matchError != nil {
+								return fmt.Errorf("error filtering file %q: %w", file.ID, matchError)
+							}
+							if !ok {
+								continue
+							}
+						}
+						allFiles = append(allFiles, file)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("error reading messages from %q: %w", src, err)
+			}
+
+			client := http.Client{
+				CheckRedirect: func(r *http.Request, via []*http.Request) error {
+					r.URL.Opaque = r.URL.Path
+					return nil
+				},
+			}
+
+			summary := &downloadSummary{}
+			for _, f := range allFiles {
+				if !f.IsTombstone() {
+					summary.Files++
+					summary.TotalBytes += f.Size
+				}
+			}
+
+			// show progress on a terminal, with logs written above it
+			logger := slog.Default()
+			var reporter *progress.Reporter
+			if progress.IsTerminal(os.Stderr) {
+				reporter = progress.NewReporter(os.Stderr, summary.Files, summary.TotalBytes)
+				logger, err = initLogger(v, reporter.Writer())
+				if err != nil {
+					return err
+				}
+				reporter.Start(500 * time.Millisecond)
+				defer reporter.Stop()
+			}
+
+			logger.Info("downloading files", "files", summary.Files, "bytes", summary.TotalBytes, "dest", dest)
+
+			for _, f := range allFiles {
+				if !f.IsTombstone() {
+
+					u, parseError := url.Parse(f.URLPrivateDownload)
+					if parseError != nil {
+						return fmt.Errorf(
+							"error parsing url for file %q: %w",
+							f.ID,
+							parseError,
+						)
+					}
+
+					downloadPath, downloadPathError := f.GetDownloadPath(layout)
+					if downloadPathError != nil {
+						return fmt.Errorf("error creating download path for file %q: %w", f.ID, downloadPathError)
+					}
+
+					key := path.Join(prefix, downloadPath)
+					if recipients != nil {
+						key += recipients.Extension()
+					}
+
+					if size, statError := store.Stat(cmd.Context(), key); statError == nil {
+						if !overwrite {
+							if recipients != nil {
+								// if not overwriting and the file is encrypted, then skip since the sizes cannot be compared
+								summary.Skipped++
+								reporter.Skip(f.Size)
+								logger.Info("skipped existing file", "id", f.ID, "key", key)
+								continue
+							}
+							if size == f.Size {
+								// if not overwriting and the sizes match, then skip
+								summary.Skipped++
+								reporter.Skip(f.Size)
+								logger.Info("skipped existing file", "id", f.ID, "key", key)
+								continue
+							}
+						}
+					}
+
+					if cmd.Context().Err() != nil {
+						break
+					}
+
+					start := time.Now()
+					n, downloadError := downloadFile(cmd.Context(), &client, u, token, store, key, recipients, reporter)
+					if downloadError != nil {
+						if cmd.Context().Err() != nil {
+							// the partial file was removed, so the file is remaining
+							logger.Warn("removed partial file", "id", f.ID, "key", key)
+							break
+						}
+						return fmt.Errorf("error downloading file %q: %w", f.ID, downloadError)
+					}
+					summary.Downloaded++
+					summary.Bytes += n
+					reporter.Done()
+					logger.Info("downloaded file", "id", f.ID, "key", key, "bytes", n, "duration", time.Since(start))
+				} else {
+					logger.Debug("skipped tombstoned file", "id", f.ID)
+				}
+			}
+
+			// stop the progress before the summary, so the summary is written below it
+			reporter.Stop()
+
+			if err = cmd.Context().Err(); err != nil {
+				summary.Remaining = summary.Files - summary.Downloaded - summary.Skipped
+				logger.Warn(
+					"download interrupted",
+					"downloaded", summary.Downloaded,
+					"bytes", summary.Bytes,
+					"skipped", summary.Skipped,
+					"remaining", summary.Remaining,
+				)
+				return fmt.Errorf("download interrupted: %w", err)
+			}
+
+			logger.Info("downloaded files", "downloaded", summary.Downloaded, "bytes", summary.Bytes, "skipped", summary.Skipped)
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+			return nil
+		},
+	}
+	initDownloadFilesFlags(downloadFilesCommand.Flags())
+	initTimeRangeFlags(downloadFilesCommand.Flags())
+	initParallelismFlags(downloadFilesCommand.Flags())
+	initContinueOnErrorFlags(downloadFilesCommand.Flags())
+	initWhereFlag(downloadFilesCommand.Flags(), "files", fileVariables...)
+
+	downloadCommand.AddCommand(downloadFilesCommand)
+
+	exportCommand := &cobra.Command{
+		Use:                   `export`,
+		DisableFlagsInUseLine: true,
+		Short:                 "export data to other formats",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+	}
+
+	for _, format := range export.Formats() {
+		formatName := format.Name
+		exportFormatCommand := &cobra.Command{
+			Use:                   fmt.Sprintf("%s [flags]", formatName),
+			DisableFlagsInUseLine: true,
+			Short:                 fmt.Sprintf("export to %s", format.Description),
+			Long:                  fmt.Sprintf("export to %s, written to --dest or stdout", format.Description),
+			SilenceErrors:         true,
+			SilenceUsage:          true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				v, err := initViper(cmd)
+				if err != nil {
+					return fmt.Errorf("error initializing viper: %w", err)
+				}
+
+				if len(args) > 0 {
+					return cmd.Usage()
+				}
+
+				if v.GetBool(FlagVersion) {
+					fmt.Println(SlackArchiverVersion)
+					return nil
+				}
+
+				if errConfig := checkConfig(v); errConfig != nil {
+					return usageError(errConfig)
+				}
+
+				where, err := initQuery(v, messageVariables...)
+				if err != nil {
+					return usageError(err)
+				}
+
+				layout, err := slack.ParseLayout(v.GetString(FlagLayout))
+				if err != nil {
+					return usageError(err)
+				}
+
+				src := v.GetString(FlagSource)
+				dest := v.GetString(FlagDestination)
+
+				archive, err := openArchive(cmd.Context(), v, src)
+				if err != nil {
+					return fmt.Errorf("error reading source %q: %w", src, err)
+				}
+				defer func() { _ = archive.Close() }()
+
+				enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+				if err != nil {
+					return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+				}
+
+				options := export.Options{
+					FilesDirectory: v.GetString(FlagFiles),
+					Layout:         layout,
+				}
+				if where != nil {
+					index := query.NewIndex(enterpriseGrid)
+					options.Filter = func(c *slack.Conversation, m *slack.Message) (bool, error) {
+						return where.Match(index.Env(c, m, nil))
+					}
+				}
+
+				exporter, err := export.New(formatName, options)
+				if err != nil {
+					return fmt.Errorf("error creating exporter: %w", err)
+				}
+
+				var w io.Writer = os.Stdout
+				var destFile io.WriteCloser
+				abort := func() {}
+				if len(dest) > 0 {
+					destFile, abort, err = createDestination(cmd.Context(), v, dest)
+					if err != nil {
+						return err
+					}
+					w = destFile
+				} else {
+					recipients, recipientsError := initRecipients(v)
+					if recipientsError != nil {
+						return recipientsError
+					}
+					if recipients != nil {
+						destFile, err = recipients.Encrypt(os.Stdout)
+						if err != nil {
+							return fmt.Errorf("error encrypting output: %w", err)
+						}
+						w = destFile
+					}
+				}
+
+				err = exporter.Export(cmd.Context(), w, enterpriseGrid)
+				if err != nil {
+					abort()
+					return fmt.Errorf("error exporting %q to %s: %w", src, formatName, err)
+				}
+
+				if destFile != nil {
+					err = destFile.Close()
+					if err != nil {
+						return fmt.Errorf("error closing destination %q: %w", dest, err)
+					}
+				}
+
+				err = archive.Close()
+				if err != nil {
+					return fmt.Errorf("error closing file for source %q: %w", src, err)
+				}
+				return nil
+			},
+		}
+		initExportFlags(exportFormatCommand.Flags())
+		initWhereFlag(exportFormatCommand.Flags(), "messages", messageVariables...)
+		exportCommand.AddCommand(exportFormatCommand)
+	}
+
+	validateCommand := &cobra.Command{
+		Use:                   `validate [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "validate archive",
+		Long: "validate the internal consistency of an archive and write a JSON report to stdout.  " +
+			"User and conversation files that cannot be decoded, invalid day files, files without ids, and duplicate message timestamps are errors.  " +
+			"Users and channel members that are not organization users, and replies without parents, are warnings.  " +
+			"Exits with a non-zero code if the report includes any errors.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			src := v.GetString(FlagSource)
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			report, err := validate.Validate(cmd.Context(), archive)
+			if err != nil {
+				return fmt.Errorf("error validating %q: %w", src, err)
+			}
+
+			err = json.NewEncoder(os.Stdout).Encode(report)
+			if err != nil {
+				return fmt.Errorf("error encoding validation report for %q: %w", src, err)
+			}
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+
+			if report.HasErrors() {
+				return invalidArchiveError(fmt.Errorf("source %q has %d validation errors", src, report.Errors))
+			}
+			return nil
+		},
+	}
+
+	extractCommand := &cobra.Command{
+		Use:                   `extract [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "extract custodian records",
+		Long: "write a sub-archive with everything the custodians touched.  " +
+			"Includes every direct message and multiparty instant message with a custodian as a member, " +
+			"the channel and group messages authored by a custodian or with a file uploaded by a custodian " +
+			"along with their threads, and the user records of the custodians.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkExtractConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			src := v.GetString(FlagSource)
+			dest := v.GetString(FlagDestination)
+			custodians := v.GetStringSlice(FlagCustodian)
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+			}
+
+			w, abort, err := createArchive(cmd.Context(), v, dest)
+			if err != nil {
+				return fmt.Errorf("error creating destination %q: %w", dest, err)
+			}
+
+			err = extract.Extract(cmd.Context(), enterpriseGrid, w, custodians)
+			if err != nil {
+				abort()
+				return fmt.Errorf("error extracting custodians from %q: %w", src, err)
+			}
+
+			err = w.Close()
+			if err != nil {
+				return fmt.Errorf("error closing destination %q: %w", dest, err)
+			}
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+			return nil
+		},
+	}
+	initExtractFlags(extractCommand.Flags())
+
+	mergeCommand := &cobra.Command{
+		Use:                   `merge [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "merge archives",
+		Long: "merge multiple archives into one archive.  " +
+			"Sources, given with --sources, should be given from oldest to newest, since later sources take precedence.  " +
+			"Users are combined by id with the latest updated time winning, conversations and members are combined, " +
+			"and messages are deduplicated by conversation and timestamp.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkMergeConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			sources := v.GetStringSlice(FlagSources)
+			dest := v.GetString(FlagDestination)
+
+			archives := make([]*slack.Archive, 0, len(sources))
+			defer func() {
+				for _, a := range archives {
+					_ = a.Close()
+				}
+			}()
+
+			grids := make([]*slack.EnterpriseGrid, 0, len(sources))
+			for _, src := range sources {
+				archive, openError := openArchive(cmd.Context(), v, src)
+				if openError != nil {
+					return fmt.Errorf("error reading source %q: %w", src, openError)
+				}
+				archives = append(archives, archive)
+				enterpriseGrid, getEnterpriseGridError := archive.GetEnterpriseGridContext(cmd.Context())
+				if getEnterpriseGridError != nil {
+					return fmt.Errorf("error reading enterprise grid from %q: %w", src, getEnterpriseGridError)
+				}
+				grids = append(grids, enterpriseGrid)
+			}
+
+			w, abort, err := createArchive(cmd.Context(), v, dest)
+			if err != nil {
+				return fmt.Errorf("error creating destination %q: %w", dest, err)
+			}
+
+			err = merge.Merge(cmd.Context(), grids, w)
+			if err != nil {
+				abort()
+				return fmt.Errorf("error merging sources into %q: %w", dest, err)
+			}
+
+			err = w.Close()
+			if err != nil {
+				return fmt.Errorf("error closing destination %q: %w", dest, err)
+			}
+
+			for i, a := range archives {
+				err = a.Close()
+				if err != nil {
+					return fmt.Errorf("error closing file for source %q: %w", sources[i], err)
+				}
+			}
+			return nil
+		},
+	}
+	initMergeFlags(mergeCommand.Flags())
+
+	pseudonymizeCommand := &cobra.Command{
+		Use:                   `pseudonymize [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "pseudonymize archive",
+		Long: "write a copy of an archive with every user replaced by a stable pseudonym derived from a secret key.  " +
+			"The same key always produces the same pseudonyms.  " +
+			"Profiles are reduced to pseudonymous names, public links to files are removed, and other fields are copied as is, " +
+			"but the command fails if any text in them contains the real id or email of a user.  " +
+			"If a mapping path is given, then the mapping from users to pseudonyms is written encrypted with the key, " +
+			"so it can be reversed with the reveal command.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkPseudonymizeConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			src := v.GetString(FlagSource)
+			dest := v.GetString(FlagDestination)
+			mappingPath := v.GetString(FlagMapping)
+
+			key, err := readKeyFile(v.GetString(FlagKeyFile))
+			if err != nil {
+				return err
+			}
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+			}
+
+			w, abort, err := createArchive(cmd.Context(), v, dest)
+			if err != nil {
+				return fmt.Errorf("error creating destination %q: %w", dest, err)
+			}
+
+			mapper := pseudonymize.NewMapper(key)
+
+			err = pseudonymize.Pseudonymize(cmd.Context(), enterpriseGrid, w, mapper)
+			if err != nil {
+				abort()
+				return fmt.Errorf("error pseudonymizing %q: %w", src, err)
+			}
+
+			if len(mappingPath) > 0 {
+				mappingFile, createError := os.Create(mappingPath)
+				if createError != nil {
+					abort()
+					return fmt.Errorf("error creating mapping %q: %w", mappingPath, createError)
+				}
+				err = pseudonymize.WriteMappings(mappingFile, key, mapper.Mappings())
+				if err != nil {
+					_ = mappingFile.Close()
+					abort()
+					return fmt.Errorf("error writing mapping %q: %w", mappingPath, err)
+				}
+				err = mappingFile.Close()
+				if err != nil {
+					abort()
+					return fmt.Errorf("error closing mapping %q: %w", mappingPath, err)
+				}
+			}
+
+			err = w.Close()
+			if err != nil {
+				return fmt.Errorf("error closing destination %q: %w", dest, err)
+			}
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+			return nil
+		},
+	}
+	initPseudonymizeFlags(pseudonymizeCommand.Flags())
+
+	pseudonymizeRevealCommand := &cobra.Command{
+		Use:                   `reveal [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "reveal pseudonyms",
+		Long:                  "decrypt the mapping from users to pseudonyms and write it as newline-delimited JSON to stdout",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkPseudonymizeRevealConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			mappingPath := v.GetString(FlagMapping)
+
+			key, err := readKeyFile(v.GetString(FlagKeyFile))
+			if err != nil {
+				return err
+			}
+
+			mappingFile, err := os.Open(mappingPath)
+			if err != nil {
+				return fmt.Errorf("error opening mapping %q: %w", mappingPath, err)
+			}
+
+			mappings, err := pseudonymize.ReadMappings(mappingFile, key)
+			if err != nil {
+				_ = mappingFile.Close()
+				return fmt.Errorf("error reading mapping %q: %w", mappingPath, err)
+			}
+
+			err = mappingFile.Close()
+			if err != nil {
+				return fmt.Errorf("error closing mapping %q: %w", mappingPath, err)
+			}
+
+			ids := map[string]struct{}{}
+			for _, id := range v.GetStringSlice(FlagIDs) {
+				ids[id] = struct{}{}
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			for _, mapping := range mappings {
+				if len(ids) > 0 {
+					_, matchID := ids[mapping.ID]
+					_, matchPseudonymID := ids[mapping.PseudonymID]
+					if !matchID && !matchPseudonymID {
+						continue
+					}
+				}
+				err = encoder.Encode(mapping)
+				if err != nil {
+					return fmt.Errorf("error encoding mapping for %q: %w", mapping.ID, err)
+				}
+			}
+			return nil
+		},
+	}
+	initPseudonymizeRevealFlags(pseudonymizeRevealCommand.Flags())
+
+	pseudonymizeCommand.AddCommand(pseudonymizeRevealCommand)
+
+	redactCommand := &cobra.Command{
+		Use:                   `redact [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "redact archive",
+		Long: "write a copy of an archive with personally identifiable information removed.  " +
+			"Profile emails, phone numbers, skype names, and custom fields are masked or hashed, " +
+			"rules are applied to message, block, attachment, and file text, and selected users and their messages, replies, and reactions are removed along with selected conversations.  " +
+			"Every change is written to the redaction log as newline-delimited JSON.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkRedactConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			src := v.GetString(FlagSource)
+			dest := v.GetString(FlagDestination)
+
+			options, err := initRedactOptions(v)
+			if err != nil {
+				return fmt.Errorf("error initializing redaction options: %w", err)
+			}
+
+			var logWriter io.Writer = os.Stdout
+			if logPath := v.GetString(FlagLog); len(logPath) > 0 {
+				logFile, createError := os.Create(logPath)
+				if createError != nil {
+					return fmt.Errorf("error creating redaction log %q: %w", logPath, createError)
+				}
+				defer func() {
+					_ = logFile.Close()
+				}()
+				logWriter = logFile
+			}
+			logEncoder := json.NewEncoder(logWriter)
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+			}
+
+			w, abort, err := createArchive(cmd.Context(), v, dest)
+			if err != nil {
+				return fmt.Errorf("error creating destination %q: %w", dest, err)
+			}
+
+			err = redact.Redact(cmd.Context(), enterpriseGrid, w, options, func(entry *redact.LogEntry) error {
+				return logEncoder.Encode(entry)
+			})
+			if err != nil {
+				abort()
+				return fmt.Errorf("error redacting %q: %w", src, err)
+			}
+
+			err = w.Close()
+			if err != nil {
+				return fmt.Errorf("error closing destination %q: %w", dest, err)
+			}
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+			return nil
+		},
+	}
+	initRedactFlags(redactCommand.Flags())
+
+	statsCommand := &cobra.Command{
+		Use:                   `stats [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "summarize archive",
+		Long:                  "summarize the messages, users, files, and reactions per conversation and team in an archive",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
+
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
+			}
+
+			if errConfig := checkStatsConfig(v); errConfig != nil {
+				return usageError(errConfig)
+			}
+
+			src := v.GetString(FlagSource)
+			format := v.GetString(FlagFormat)
+
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
+
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+			}
 
-	listCommand := &cobra.Command{
-		Use:                   `list`,
-		DisableFlagsInUseLine: true,
-		Short:                 "list data",
-		SilenceErrors:         true,
-		SilenceUsage:          true,
+			walkOptions := initWalkOptions(v)
+			collector := initErrorCollector(v, walkOptions)
+			defer writeSkippedFiles(os.Stderr, v.GetString(FlagErrorFormat), collector)
+
+			summary, err := stats.Summarize(cmd.Context(), enterpriseGrid, v.GetInt(FlagTop), walkOptions)
+			if err != nil {
+				return fmt.Errorf("error summarizing %q: %w", src, err)
+			}
+
+			if format == "table" {
+				err = summary.WriteTable(os.Stdout)
+			} else {
+				err = json.NewEncoder(os.Stdout).Encode(summary)
+			}
+			if err != nil {
+				return fmt.Errorf("error writing summary of %q: %w", src, err)
+			}
+
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
+			return nil
+		},
 	}
+	initStatsFlags(statsCommand.Flags())
+	initTimeRangeFlags(statsCommand.Flags())
+	initParallelismFlags(statsCommand.Flags())
+	initContinueOnErrorFlags(statsCommand.Flags())
 
-	listFilesCommand := &cobra.Command{
-		Use:                   `files [flags]`,
+	applyPolicyCommand := &cobra.Command{
+		Use:                   `apply-policy [flags]`,
 		DisableFlagsInUseLine: true,
-		Short:                 "list files",
-		Long:                  "list files",
-		SilenceErrors:         true,
-		SilenceUsage:          true,
+		Short:                 "apply retention policy",
+		Long: "evaluate a retention policy over the messages in an archive and write a JSON report to stdout.  " +
+			"Unless dry-run is set, the archive is written to dest without the purged messages.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			v, err := initViper(cmd)
 			if err != nil {
@@ -118,143 +2036,60 @@ func main() {
 				return nil
 			}
 
-			if errConfig := checkConfig(v); errConfig != nil {
-				return errConfig
+			if errConfig := checkApplyPolicyConfig(v); errConfig != nil {
+				return usageError(errConfig)
 			}
 
 			src := v.GetString(FlagSource)
+			dest := v.GetString(FlagDestination)
+			dryRun := v.GetBool(FlagDryRun)
+
+			asOf := time.Now().UTC()
+			if str := v.GetString(FlagAsOf); len(str) > 0 {
+				asOf, _ = time.Parse(slack.DayFileDateFormat, str)
+			}
+
+			p, err := policy.LoadPolicy(v.GetString(FlagPolicy))
+			if err != nil {
+				return err
+			}
 
-			archive, err := slack.OpenArchive(src)
+			archive, err := openArchive(cmd.Context(), v, src)
 			if err != nil {
 				return fmt.Errorf("error reading source %q: %w", src, err)
 			}
+			defer func() { _ = archive.Close() }()
 
-			enterpriseGrid, err := archive.GetEnterpriseGrid()
+			enterpriseGrid, err := archive.GetEnterpriseGridContext(cmd.Context())
 			if err != nil {
 				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
 			}
 
-			encoder := json.NewEncoder(os.Stdout)
-
-			// Files from multiparty instant messages
-
-			for i, mpim := range enterpriseGrid.GetMultiPartyInstantMessages() {
-				messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("%s/", mpim.Name))
-				if getMessagesError != nil {
-					return fmt.Errorf(
-						"error reading mulitparty instant messages %d from %q for mpim %q : %w",
-						i,
-						src,
-						mpim.Name,
-						getMessagesError,
-					)
-				}
-				for _, msg := range messages {
-					if files := msg.Files; len(files) > 0 {
-						for k, file := range files {
-							encodeError := encoder.Encode(file)
-							if encodeError != nil {
-								return fmt.Errorf(
-									"error encoding file from %q: %w",
-									fmt.Sprintf("%s/%s/%d", mpim.Name, file.Name, k),
-									encodeError,
-								)
-							}
-						}
-					}
+			var w *slack.ArchiveWriter
+			abort := func() {}
+			if !dryRun {
+				w, abort, err = createArchive(cmd.Context(), v, dest)
+				if err != nil {
+					return fmt.Errorf("error creating destination %q: %w", dest, err)
 				}
 			}
 
-			// Files from direct messages
-
-			for i, dm := range enterpriseGrid.GetDirectMessages() {
-				messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("%s/", dm.ID))
-				if getMessagesError != nil {
-					return fmt.Errorf(
-						"error reading direct message %d from %q for mpim %q : %w",
-						i,
-						src,
-						dm.ID,
-						getMessagesError,
-					)
-				}
-				for _, msg := range messages {
-					if files := msg.Files; len(files) > 0 {
-						for k, file := range files {
-							encodeError := encoder.Encode(file)
-							if encodeError != nil {
-								return fmt.Errorf(
-									"error encoding file from %q: %w",
-									fmt.Sprintf("%s/%s/%d", dm.ID, file.Name, k),
-									encodeError,
-								)
-							}
-						}
-					}
-				}
+			report, err := policy.Apply(cmd.Context(), enterpriseGrid, p, asOf, w)
+			if err != nil {
+				abort()
+				return fmt.Errorf("error applying policy to %q: %w", src, err)
 			}
 
-			// Files from teams
-
-			for _, t := range enterpriseGrid.GetTeams() {
-
-				// Files from channels
-
-				for _, c := range t.Channels {
-					messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("teams/%s/%s/", t.Name, c.Name))
-					if getMessagesError != nil {
-						return fmt.Errorf(
-							"error reading messages for channel %q in team %q from %q: %w",
-							c.Name,
-							t.Name,
-							src,
-							getMessagesError,
-						)
-					}
-					for _, msg := range messages {
-						if files := msg.Files; len(files) > 0 {
-							for k, file := range files {
-								encodeError := encoder.Encode(file)
-								if encodeError != nil {
-									return fmt.Errorf(
-										"error encoding file from %q: %w",
-										fmt.Sprintf("teams/%s/%s/%s/%d", t.Name, c.Name, file.Name, k),
-										encodeError,
-									)
-								}
-							}
-						}
-					}
+			if w != nil {
+				err = w.Close()
+				if err != nil {
+					return fmt.Errorf("error closing destination %q: %w", dest, err)
 				}
+			}
 
-				// Files from groups
-
-				for _, g := range t.Groups {
-					messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("teams/%s/%s/", t.Name, g.Name))
-					if getMessagesError != nil {
-						return fmt.Errorf(
-							"error reading messages for group %q in team %q from %q: %w",
-							g.Name,
-							t.Name,
-							src,
-							getMessagesError,
-						)
-					}
-					for _, msg := range messages {
-						if files := msg.Files; len(files) > 0 {
-							for k, file := range files {
-								encodeError := encoder.Encode(file)
-								if encodeError != nil {
-									return fmt.Errorf(
-										"error encoding file from %q: %w",
-										fmt.Sprintf("teams/%s/%s/%s/%d", t.Name, g.Name, file.Name, k),
-										encodeError,
-									)
-								}
-							}
-						}
-					}
-				}
+			err = json.NewEncoder(os.Stdout).Encode(report)
+			if err != nil {
+				return fmt.Errorf("error encoding policy report for %q: %w", src, err)
 			}
 
 			err = archive.Close()
@@ -264,15 +2099,16 @@ func main() {
 			return nil
 		},
 	}
-	initListFlags(listFilesCommand.Flags())
+	initApplyPolicyFlags(applyPolicyCommand.Flags())
 
-	listTeamsCommand := &cobra.Command{
-		Use:                   `teams [flags]`,
+	sealCommand := &cobra.Command{
+		Use:                   `seal [flags]`,
 		DisableFlagsInUseLine: true,
-		Short:                 "list teams",
-		Long:                  "list teams",
-		SilenceErrors:         true,
-		SilenceUsage:          true,
+		Short:                 "seal archive",
+		Long: "hash every entry in an archive and the downloaded files into a Merkle tree, " +
+			"and write a manifest signed with an ed25519 key.  Use verify-seal to prove the archive has not changed.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			v, err := initViper(cmd)
 			if err != nil {
@@ -288,63 +2124,67 @@ func main() {
 				return nil
 			}
 
-			if errConfig := checkConfig(v); errConfig != nil {
-				return errConfig
+			if errConfig := checkSealConfig(v); errConfig != nil {
+				return usageError(errConfig)
 			}
 
 			src := v.GetString(FlagSource)
+			manifestPath := v.GetString(FlagManifest)
 
-			archive, err := slack.OpenArchive(src)
+			key, err := seal.LoadPrivateKey(v.GetString(FlagKeyFile))
 			if err != nil {
-				return fmt.Errorf("error reading source %q: %w", src, err)
+				return err
 			}
 
-			enterpriseGrid, err := archive.GetEnterpriseGrid()
+			archive, err := openArchive(cmd.Context(), v, src)
 			if err != nil {
-				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+				return fmt.Errorf("error reading source %q: %w", src, err)
 			}
+			defer func() { _ = archive.Close() }()
 
-			teams := enterpriseGrid.GetTeams()
-
-			encoder := json.NewEncoder(os.Stdout)
-			for _, team := range teams {
-				encodeError := encoder.Encode(team)
-				if encodeError != nil {
-					return fmt.Errorf(
-						"error encoding team %q from %q: %w",
-						team.Name,
-						src,
-						encodeError,
-					)
-				}
+			manifest, err := seal.Seal(cmd.Context(), archive, v.GetString(FlagFiles), key, time.Now())
+			if err != nil {
+				return fmt.Errorf("error sealing %q: %w", src, err)
 			}
 
 			err = archive.Close()
 			if err != nil {
 				return fmt.Errorf("error closing file for source %q: %w", src, err)
 			}
-			return nil
-		},
-	}
-	initListFlags(listTeamsCommand.Flags())
 
-	listCommand.AddCommand(listFilesCommand, listTeamsCommand)
+			if len(manifestPath) == 0 {
+				return seal.WriteManifest(os.Stdout, manifest)
+			}
 
-	downloadCommand := &cobra.Command{
-		Use:                   `download`,
-		DisableFlagsInUseLine: true,
-		Short:                 "download data",
-		SilenceErrors:         true,
-		SilenceUsage:          true,
+			manifestFile, err := os.Create(manifestPath)
+			if err != nil {
+				return fmt.Errorf("error creating manifest %q: %w", manifestPath, err)
+			}
+
+			err = seal.WriteManifest(manifestFile, manifest)
+			if err != nil {
+				_ = manifestFile.Close()
+				return fmt.Errorf("error writing manifest %q: %w", manifestPath, err)
+			}
+
+			err = manifestFile.Close()
+			if err != nil {
+				return fmt.Errorf("error closing manifest %q: %w", manifestPath, err)
+			}
+			return nil
+		},
 	}
+	initSealFlags(sealCommand.Flags())
 
-	downloadFilesCommand := &cobra.Command{
-		Use:                   `files [flags]`,
+	verifySealCommand := &cobra.Command{
+		Use:                   `verify-seal [flags]`,
 		DisableFlagsInUseLine: true,
-		Short:                 "download files",
-		Long:                  "download files",
-		SilenceErrors:         true,
-		SilenceUsage:          true,
+		Short:                 "verify sealed archive",
+		Long: "verify the signature of a manifest written by seal, and write a JSON report to stdout " +
+			"of every entry in the archive or downloaded files that was altered, added, or removed.  " +
+			"Exits with a non-zero code if the signature is invalid or any entry changed.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			v, err := initViper(cmd)
 			if err != nil {
@@ -360,192 +2200,222 @@ func main() {
 				return nil
 			}
 
-			if errConfig := checkDownloadFilesConfig(v); errConfig != nil {
-				return errConfig
+			if errConfig := checkVerifySealConfig(v); errConfig != nil {
+				return usageError(errConfig)
 			}
 
 			src := v.GetString(FlagSource)
-			dest := v.GetString(FlagDestination)
-			overwrite := v.GetBool(FlagOverwrite)
+			manifestPath := v.GetString(FlagManifest)
 
-			archive, err := slack.OpenArchive(src)
+			key, err := seal.LoadPublicKey(v.GetString(FlagPublicKey))
 			if err != nil {
-				return fmt.Errorf("error reading source %q: %w", src, err)
+				return err
 			}
 
-			enterpriseGrid, err := archive.GetEnterpriseGrid()
+			manifestFile, err := os.Open(manifestPath)
 			if err != nil {
-				return fmt.Errorf("error reading enterprise grid from %q: %w", src, err)
+				return fmt.Errorf("error opening manifest %q: %w", manifestPath, err)
 			}
 
-			allFiles := make([]slack.MessageFile, 0)
-
-			// Files from multiparty instant messages
-
-			for i, mpim := range enterpriseGrid.GetMultiPartyInstantMessages() {
-				messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("%s/", mpim.Name))
-				if getMessagesError != nil {
-					return fmt.Errorf(
-						"error reading mulitparty instant messages %d from %q for mpim %q : %w",
-						i,
-						src,
-						mpim.Name,
-						getMessagesError,
-					)
-				}
-				for _, msg := range messages {
-					if files := msg.Files; len(files) > 0 {
-						allFiles = append(allFiles, files...)
-					}
-				}
+			manifest, err := seal.ReadManifest(manifestFile)
+			_ = manifestFile.Close()
+			if err != nil {
+				return fmt.Errorf("error reading manifest %q: %w", manifestPath, err)
 			}
 
-			// Files from direct messages
+			archive, err := openArchive(cmd.Context(), v, src)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", src, err)
+			}
+			defer func() { _ = archive.Close() }()
 
-			for i, dm := range enterpriseGrid.GetDirectMessages() {
-				messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("%s/", dm.ID))
-				if getMessagesError != nil {
-					return fmt.Errorf(
-						"error reading direct message %d from %q for mpim %q : %w",
-						i,
-						src,
-						dm.ID,
-						getMessagesError,
-					)
-				}
-				for _, msg := range messages {
-					if files := msg.Files; len(files) > 0 {
-						allFiles = append(allFiles, files...)
-					}
-				}
+			report, err := seal.Verify(cmd.Context(), manifest, key, archive, v.GetString(FlagFiles))
+			if err != nil {
+				return fmt.Errorf("error verifying %q against manifest %q: %w", src, manifestPath, err)
 			}
 
-			// Files from teams
+			err = json.NewEncoder(os.Stdout).Encode(report)
+			if err != nil {
+				return fmt.Errorf("error encoding seal report for %q: %w", src, err)
+			}
 
-			for _, t := range enterpriseGrid.GetTeams() {
+			err = archive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", src, err)
+			}
 
-				// Files from channels
+			if !report.Valid {
+				return invalidArchiveError(fmt.Errorf(
+					"source %q does not match manifest %q: %d altered, %d added, and %d removed",
+					src,
+					manifestPath,
+					report.Altered,
+					report.Added,
+					report.Removed,
+				))
+			}
+			return nil
+		},
+	}
+	initVerifySealFlags(verifySealCommand.Flags())
 
-				for _, c := range t.Channels {
-					messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("teams/%s/%s/", t.Name, c.Name))
-					if getMessagesError != nil {
-						return fmt.Errorf(
-							"error reading messages for channel %q in team %q from %q: %w",
-							c.Name,
-							t.Name,
-							src,
-							getMessagesError,
-						)
-					}
-					for _, msg := range messages {
-						if files := msg.Files; len(files) > 0 {
-							allFiles = append(allFiles, files...)
-						}
-					}
-				}
+	diffCommand := &cobra.Command{
+		Use:                   `diff [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "compare archives",
+		Long: "compare an old and new archive and write the changes as newline-delimited JSON to stdout.  " +
+			"Reports added, removed, and renamed conversations, membership changes, " +
+			"added, edited, and deleted messages, files that became tombstones, and user status changes.  " +
+			"Since exports are date-ranged, deleted messages are only reported within the dates of the day files in the new archive.",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
 
-				// Files from groups
+			if len(args) > 0 {
+				return cmd.Usage()
+			}
 
-				for _, g := range t.Groups {
-					messages, getMessagesError := enterpriseGrid.GetMessages(fmt.Sprintf("teams/%s/%s/", t.Name, g.Name))
-					if getMessagesError != nil {
-						return fmt.Errorf(
-							"error reading messages for group %q in team %q from %q: %w",
-							g.Name,
-							t.Name,
-							src,
-							getMessagesError,
-						)
-					}
-					for _, msg := range messages {
-						if files := msg.Files; len(files) > 0 {
-							allFiles = append(allFiles, files...)
-						}
-					}
-				}
+			if v.GetBool(FlagVersion) {
+				fmt.Println(SlackArchiverVersion)
+				return nil
 			}
 
-			client := http.Client{
-				CheckRedirect: func(r *http.Request, via []*http.Request) error {
-					r.URL.Opaque = r.URL.Path
-					return nil
-				},
+			if errConfig := checkDiffConfig(v); errConfig != nil {
+				return usageError(errConfig)
 			}
 
-			for _, f := range allFiles {
-				if !f.IsTombstone() {
+			oldSource := v.GetString(FlagOld)
+			newSource := v.GetString(FlagNew)
 
-					u, parseError := url.Parse(f.URLPrivateDownload)
-					if parseError != nil {
-						return fmt.Errorf(
-							"error parsing url for file %q: %w",
-							f.ID,
-							parseError,
-						)
-					}
+			oldArchive, err := openArchive(cmd.Context(), v, oldSource)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", oldSource, err)
+			}
+			defer func() { _ = oldArchive.Close() }()
 
-					filename := u.Path[strings.LastIndex(u.Path, "/")+1 : len(u.Path)]
+			oldGrid, err := oldArchive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", oldSource, err)
+			}
 
-					created := time.Unix(int64(f.Created), 0)
-					createdYear, createdMonth, createdDay := created.Date()
+			newArchive, err := openArchive(cmd.Context(), v, newSource)
+			if err != nil {
+				return fmt.Errorf("error reading source %q: %w", newSource, err)
+			}
+			defer func() { _ = newArchive.Close() }()
 
-					fullpath := filepath.Join(
-						dest,
-						fmt.Sprintf("year=%d", createdYear),
-						fmt.Sprintf("month=%d", int(createdMonth)),
-						fmt.Sprintf("day=%d", createdDay),
-						fmt.Sprintf("user=%s", f.User),
-						fmt.Sprintf("filetype=%s", f.FileType),
-						fmt.Sprintf("id=%s", f.ID),
-						filename,
-					)
+			newGrid, err := newArchive.GetEnterpriseGridContext(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading enterprise grid from %q: %w", newSource, err)
+			}
 
-					mkdirAllError := os.MkdirAll(filepath.Dir(fullpath), 0775)
-					if err != nil {
-						return fmt.Errorf("error creating directory for file %q from url %q: %w", f.ID, u.String(), mkdirAllError)
-					}
+			encoder := json.NewEncoder(os.Stdout)
+			err = diff.Diff(cmd.Context(), oldGrid, newGrid, func(c *diff.Change) error {
+				return encoder.Encode(c)
+			})
+			if err != nil {
+				return fmt.Errorf("error comparing %q to %q: %w", oldSource, newSource, err)
+			}
 
-					if fi, statError := os.Stat(fullpath); statError == nil {
-						if !overwrite {
-							if fi.Size() == f.Size {
-								// if not overwriting and the sizes match, then skip
-								continue
-							}
-						}
-					}
+			err = oldArchive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", oldSource, err)
+			}
 
-					resp, getError := client.Get(u.String())
-					if getError != nil {
-						return fmt.Errorf("error downloading file %q from url %q: %w", f.ID, u.String(), getError)
-					}
+			err = newArchive.Close()
+			if err != nil {
+				return fmt.Errorf("error closing file for source %q: %w", newSource, err)
+			}
+			return nil
+		},
+	}
+	initDiffFlags(diffCommand.Flags())
 
-					downloadedFile, createError := os.Create(fullpath)
-					if createError != nil {
-						return fmt.Errorf("error creating file %q for url %q: %w", fullpath, u.String(), createError)
-					}
+	configCommand := &cobra.Command{
+		Use:                   `config`,
+		DisableFlagsInUseLine: true,
+		Short:                 "manage configuration",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+	}
 
-					_, copyError := io.Copy(downloadedFile, resp.Body)
-					if copyError != nil {
-						return fmt.Errorf("error copying file %q for url %q: %w", fullpath, u.String(), copyError)
-					}
+	configShowCommand := &cobra.Command{
+		Use:                   `show [command] [flags]`,
+		DisableFlagsInUseLine: true,
+		Short:                 "show configuration",
+		Long:                  "show the effective configuration of the command, merged from the root flags, environment variables, the config file, and the defaults of the flags",
+		SilenceErrors:         true,
+		SilenceUsage:          true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := initViper(cmd)
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
+
+			format := v.GetString(FlagFormat)
+			if format != "yaml" && format != "json" {
+				return usageError(fmt.Errorf("invalid format %q, expecting yaml or json", format))
+			}
 
-					_ = downloadedFile.Close()
+			target := cmd.Root()
+			if len(args) > 0 {
+				found, rest, findError := cmd.Root().Find(args)
+				if findError != nil || len(rest) > 0 || found == cmd.Root() {
+					return usageError(fmt.Errorf("unknown command %q", strings.Join(args, " ")))
+				}
+				target = found
+			}
 
-					_ = resp.Body.Close()
+			// the flags of the target command and the root flags given to this command
+			tv, err := newViper(target.LocalFlags(), cmd.InheritedFlags())
+			if err != nil {
+				return fmt.Errorf("error initializing viper: %w", err)
+			}
 
+			settings := map[string]interface{}{}
+			addSetting := func(f *pflag.Flag) {
+				if f.Name == "help" {
+					return
+				}
+				// environment variables and config files are read as strings, so values are converted to the type of the flag
+				switch f.Value.Type() {
+				case "bool":
+					settings[f.Name] = tv.GetBool(f.Name)
+				case "int":
+					settings[f.Name] = tv.GetInt(f.Name)
+				case "stringSlice":
+					settings[f.Name] = tv.GetStringSlice(f.Name)
+				default:
+					settings[f.Name] = tv.GetString(f.Name)
 				}
 			}
+			target.LocalFlags().VisitAll(addSetting)
+			cmd.InheritedFlags().VisitAll(addSetting)
+			// the filters of the config file are combined with --where, so they are shown for the commands that select records
+			if target.LocalFlags().Lookup(FlagWhere) != nil {
+				settings[KeyFilters] = tv.GetStringSlice(KeyFilters)
+			}
 
-			err = archive.Close()
+			if format == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(settings)
+			}
+			b, err := yaml.Marshal(settings)
 			if err != nil {
-				return fmt.Errorf("error closing file for source %q: %w", src, err)
+				return fmt.Errorf("error marshaling configuration: %w", err)
 			}
-			return nil
+			_, err = os.Stdout.Write(b)
+			return err
 		},
 	}
-	initDownloadFilesFlags(downloadFilesCommand.Flags())
+	configShowCommand.Flags().StringP(FlagFormat, "f", "yaml", "output format, either yaml or json")
 
-	downloadCommand.AddCommand(downloadFilesCommand)
+	configCommand.AddCommand(configShowCommand)
 
 	versionCommand := &cobra.Command{
 		Use:                   `version`,
@@ -559,11 +2429,20 @@ func main() {
 		},
 	}
 
-	rootCommand.AddCommand(listCommand, downloadCommand, versionCommand)
-
-	if err := rootCommand.Execute(); err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "slack-archiver: "+err.Error())
-		_, _ = fmt.Fprintln(os.Stderr, "Try slack-archiver --help for more information.")
-		os.Exit(1)
+	rootCommand.AddCommand(applyPolicyCommand, configCommand, listCommand, diffCommand, downloadCommand, exportCommand, extractCommand, mergeCommand, pseudonymizeCommand, redactCommand, sealCommand, statsCommand, validateCommand, verifySealCommand, versionCommand)
+
+	// The context is canceled on an interrupt or termination signal, so commands can stop cleanly.
+	// Once canceled, the signals are no longer handled, so a second signal exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCommand.ExecuteContext(ctx); err != nil {
+		if len(errorFormat) == 0 {
+			errorFormat, _ = rootCommand.PersistentFlags().GetString(FlagErrorFormat)
+		}
+		os.Exit(writeError(os.Stderr, errorFormat, err))
 	}
 }
@@ -0,0 +1,105 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package blob
+
+import (
+	"container/list"
+	"io"
+	"sync"
+)
+
+// cacheEntry is a block that is cached or being fetched.  Ready is closed once the fetch finishes.
+type cacheEntry struct {
+	data    []byte
+	err     error
+	ready   chan struct{}
+	element *list.Element // the element in the least recently used list, or nil while the block is being fetched
+}
+
+// blockCache caches fixed-size blocks of a remote blob, so the many small reads made while reading a zip file
+// are served from a few ranged requests.  The least recently used block is evicted when the cache is full.
+// Blocks are fetched without holding the lock, so reads of different blocks are made in parallel,
+// and concurrent reads of the same block wait for a single fetch.
+type blockCache struct {
+	mutex     sync.Mutex // guards entries and order
+	size      int64
+	blockSize int64
+	maxBlocks int
+	entries   map[int64]*cacheEntry
+	order     *list.List // block indices from least to most recently used
+	fetch     func(offset int64, length int64) ([]byte, error)
+}
+
+func newBlockCache(size int64, blockSize int64, maxBlocks int, fetch func(offset int64, length int64) ([]byte, error)) *blockCache {
+	return &blockCache{
+		size:      size,
+		blockSize: blockSize,
+		maxBlocks: maxBlocks,
+		entries:   map[int64]*cacheEntry{},
+		order:     list.New(),
+		fetch:     fetch,
+	}
+}
+
+func (c *blockCache) block(index int64) ([]byte, error) {
+	c.mutex.Lock()
+	if e, ok := c.entries[index]; ok {
+		if e.element != nil {
+			c.order.MoveToBack(e.element)
+		}
+		c.mutex.Unlock()
+		<-e.ready
+		return e.data, e.err
+	}
+	e := &cacheEntry{ready: make(chan struct{})}
+	c.entries[index] = e
+	c.mutex.Unlock()
+
+	offset := index * c.blockSize
+	length := c.blockSize
+	if offset+length > c.size {
+		length = c.size - offset
+	}
+	e.data, e.err = c.fetch(offset, length)
+
+	c.mutex.Lock()
+	if e.err != nil {
+		// failed fetches are not cached, so the next read tries again
+		delete(c.entries, index)
+	} else {
+		e.element = c.order.PushBack(index)
+		for c.order.Len() > c.maxBlocks {
+			oldest := c.order.Front()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(int64))
+		}
+	}
+	c.mutex.Unlock()
+	close(e.ready)
+	return e.data, e.err
+}
+
+func (c *blockCache) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		if off >= c.size {
+			return n, io.EOF
+		}
+		b, err := c.block(off / c.blockSize)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], b[off%c.blockSize:])
+		if copied == 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
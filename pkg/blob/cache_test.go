@@ -0,0 +1,87 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package blob
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestBlockCacheParallel checks that blocks are fetched in parallel, and that concurrent reads of a block wait for a single fetch.
+func TestBlockCacheParallel(t *testing.T) {
+	data := testData(256)
+	fetches := map[int64]*atomic.Int32{0: {}, 64: {}, 128: {}, 192: {}}
+	inFlight := make(chan struct{}, len(fetches))
+	release := make(chan struct{})
+	c := newBlockCache(int64(len(data)), 64, 4, func(offset int64, length int64) ([]byte, error) {
+		fetches[offset].Add(1)
+		inFlight <- struct{}{}
+		<-release
+		return data[offset : offset+length], nil
+	})
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(offset int64) {
+			defer wg.Done()
+			p := make([]byte, 10)
+			if _, err := c.ReadAt(p, offset); err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(p, data[offset:offset+10]) {
+				t.Errorf("unexpected bytes reading at %d", offset)
+			}
+		}(int64(i%4) * 64)
+	}
+
+	// every block is fetched at once, since a fetch does not block the fetches of other blocks
+	for i := 0; i < len(fetches); i++ {
+		select {
+		case <-inFlight:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expecting %d fetches in parallel, but found %d", len(fetches), i)
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	for offset, n := range fetches {
+		if got := n.Load(); got != 1 {
+			t.Fatalf("expecting 1 fetch of the block at %d, but found %d", offset, got)
+		}
+	}
+}
+
+// TestBlockCacheError checks that a failed fetch is returned to the read and is not cached.
+func TestBlockCacheError(t *testing.T) {
+	data := testData(64)
+	fail := true
+	c := newBlockCache(int64(len(data)), 64, 4, func(offset int64, length int64) ([]byte, error) {
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return data[offset : offset+length], nil
+	})
+	p := make([]byte, 10)
+	if _, err := c.ReadAt(p, 0); err == nil {
+		t.Fatal("expecting error from failed fetch")
+	}
+	fail = false
+	if _, err := c.ReadAt(p, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, data[:10]) {
+		t.Fatal("unexpected bytes after failed fetch")
+	}
+}
@@ -0,0 +1,14 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package blob provides the storage that archives are read from and files are downloaded to.
+//
+// A location is either a local path or an S3 URL, such as s3://bucket/exports/export.zip.
+// S3 locations work with any S3-compatible object storage, such as MinIO, by setting the endpoint.
+// Credentials for S3 are read from the environment (AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or MINIO_ACCESS_KEY and MINIO_SECRET_KEY),
+// from the AWS shared credentials file, or from the IAM role of the instance.
+package blob
@@ -0,0 +1,102 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package blob
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type localBlob struct {
+	*os.File
+	size int64
+}
+
+func (b *localBlob) Size() int64 {
+	return b.size
+}
+
+// LocalStore stores blobs on the local file system.  Names are paths, which can use slashes as separators.
+type LocalStore struct{}
+
+// NewLocalStore returns a store for the local file system.
+func NewLocalStore() *LocalStore {
+	return &LocalStore{}
+}
+
+func (s *LocalStore) Open(ctx context.Context, name string) (Blob, error) {
+	f, err := os.Open(filepath.FromSlash(name))
+	if err != nil {
+		return nil, fmt.Errorf("error opening %q: %w", name, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("error stating %q: %w", name, err)
+	}
+	return &localBlob{File: f, size: fi.Size()}, nil
+}
+
+func (s *LocalStore) Stat(ctx context.Context, name string) (int64, error) {
+	fi, err := os.Stat(filepath.FromSlash(name))
+	if err != nil {
+		return 0, fmt.Errorf("error stating %q: %w", name, err)
+	}
+	return fi.Size(), nil
+}
+
+// PartialExtension is appended to the name of a file while it is being written.
+const PartialExtension = ".partial"
+
+// localWriter writes to a partial file next to the named file, which is renamed to the name once closed.
+type localWriter struct {
+	*os.File
+	name string
+}
+
+// Close closes the partial file and renames it to the name of the blob.
+func (w *localWriter) Close() error {
+	err := w.File.Close()
+	if err != nil {
+		_ = os.Remove(w.File.Name())
+		return fmt.Errorf("error closing %q: %w", w.name, err)
+	}
+	err = os.Rename(w.File.Name(), w.name)
+	if err != nil {
+		_ = os.Remove(w.File.Name())
+		return fmt.Errorf("error renaming %q: %w", w.name, err)
+	}
+	return nil
+}
+
+// Abort closes and removes the partial file.
+func (w *localWriter) Abort() error {
+	_ = w.File.Close()
+	err := os.Remove(w.File.Name())
+	if err != nil {
+		return fmt.Errorf("error removing partial file for %q: %w", w.name, err)
+	}
+	return nil
+}
+
+// Create creates the parent directories of the named file and then creates a partial file next to it,
+// so that a partial file is never left at the name if writing is interrupted.
+func (s *LocalStore) Create(ctx context.Context, name string) (Writer, error) {
+	p := filepath.FromSlash(name)
+	err := os.MkdirAll(filepath.Dir(p), 0775)
+	if err != nil {
+		return nil, fmt.Errorf("error creating directory for %q: %w", name, err)
+	}
+	f, err := os.OpenFile(p+PartialExtension, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %q: %w", name, err)
+	}
+	return &localWriter{File: f, name: p}, nil
+}
@@ -0,0 +1,191 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	DefaultS3Endpoint = "s3.amazonaws.com"
+)
+
+var errUploadAborted = errors.New("upload aborted")
+
+const (
+	s3BlockSize = 4 << 20 // 4 MiB
+	s3MaxBlocks = 16
+	// s3PartSize is the size of the parts of multipart uploads, which are buffered in memory.
+	// Since uploads are streamed, the size of an object is limited to 10,000 parts, or 320 GiB.
+	s3PartSize = 32 << 20 // 32 MiB
+)
+
+// S3Options configure an S3 store.
+type S3Options struct {
+	// Endpoint is the host of the S3-compatible object storage, e.g., minio.example.mil:9000.
+	// Prefix the endpoint with http:// to connect without TLS.  Defaults to Amazon S3.
+	Endpoint string
+	Region   string
+	Bucket   string
+}
+
+// S3Store stores blobs as objects in an S3 bucket.  Names are object keys.
+type S3Store struct {
+	client    *minio.Client
+	bucket    string
+	blockSize int64
+	maxBlocks int
+}
+
+// NewS3Store returns a store for the bucket.
+func NewS3Store(options S3Options) (*S3Store, error) {
+	if len(options.Bucket) == 0 {
+		return nil, fmt.Errorf("bucket is missing")
+	}
+	endpoint, secure := options.Endpoint, true
+	if len(endpoint) == 0 {
+		endpoint = DefaultS3Endpoint
+	}
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing endpoint %q: %w", endpoint, err)
+		}
+		endpoint, secure = u.Host, u.Scheme != "http"
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		}),
+		Secure: secure,
+		Region: options.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating client for endpoint %q: %w", endpoint, err)
+	}
+	return &S3Store{client: client, bucket: options.Bucket, blockSize: s3BlockSize, maxBlocks: s3MaxBlocks}, nil
+}
+
+func (s *S3Store) url(name string) string {
+	return SchemeS3 + s.bucket + "/" + name
+}
+
+func (s *S3Store) notExist(name string, err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return fmt.Errorf("error stating %q: %w", s.url(name), fs.ErrNotExist)
+	}
+	return fmt.Errorf("error stating %q: %w", s.url(name), err)
+}
+
+type s3Blob struct {
+	*blockCache
+}
+
+func (b *s3Blob) Size() int64 {
+	return b.size
+}
+
+func (b *s3Blob) Close() error {
+	return nil
+}
+
+// Open stats the object and returns a blob that reads the object with ranged requests made with the context.
+func (s *S3Store) Open(ctx context.Context, name string) (Blob, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s.notExist(name, err)
+	}
+	etag := info.ETag
+	fetch := func(offset int64, length int64) ([]byte, error) {
+		options := minio.GetObjectOptions{}
+		if err := options.SetRange(offset, offset+length-1); err != nil {
+			return nil, fmt.Errorf("error setting range for %q: %w", s.url(name), err)
+		}
+		if err := options.SetMatchETag(etag); err != nil {
+			return nil, fmt.Errorf("error setting etag for %q: %w", s.url(name), err)
+		}
+		object, err := s.client.GetObject(ctx, s.bucket, name, options)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %q: %w", s.url(name), err)
+		}
+		defer func() { _ = object.Close() }()
+		b := make([]byte, length)
+		_, err = io.ReadFull(object, b)
+		if err != nil {
+			return nil, fmt.Errorf("error reading bytes %d-%d of %q: %w", offset, offset+length-1, s.url(name), err)
+		}
+		return b, nil
+	}
+	return &s3Blob{blockCache: newBlockCache(info.Size, s.blockSize, s.maxBlocks, fetch)}, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, name string) (int64, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return 0, s.notExist(name, err)
+	}
+	return info.Size, nil
+}
+
+type s3Writer struct {
+	name   string
+	writer *io.PipeWriter
+	done   chan error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+// Close finishes the upload and waits for the object to be stored.
+func (w *s3Writer) Close() error {
+	err := w.writer.Close()
+	if err != nil {
+		return fmt.Errorf("error closing upload of %q: %w", w.name, err)
+	}
+	err = <-w.done
+	if err != nil {
+		return fmt.Errorf("error uploading %q: %w", w.name, err)
+	}
+	return nil
+}
+
+// Abort fails the upload with an error, so the multipart upload is aborted and no object is stored.
+func (w *s3Writer) Abort() error {
+	_ = w.writer.CloseWithError(errUploadAborted)
+	<-w.done
+	return nil
+}
+
+// Create streams everything written to the returned writer to the object with a multipart upload.
+func (s *S3Store) Create(ctx context.Context, name string) (Writer, error) {
+	pr, pw := io.Pipe()
+	w := &s3Writer{name: s.url(name), writer: pw, done: make(chan error, 1)}
+	go func() {
+		_, err := s.client.PutObject(ctx, s.bucket, name, pr, -1, minio.PutObjectOptions{
+			ContentType: "application/octet-stream",
+			PartSize:    s3PartSize,
+		})
+		_ = pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
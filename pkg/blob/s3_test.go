@@ -0,0 +1,263 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an S3 server that stores objects in memory.  It supports the requests made by S3Store:
+// HEAD and ranged GET requests of objects, and multipart uploads.
+type fakeS3 struct {
+	mutex   sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	next    int
+	gets    int // the number of GET requests of objects
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+type fakeS3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type fakeS3InitiateResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type fakeS3CompleteResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string   `xml:"Bucket"`
+	Key     string   `xml:"Key"`
+	ETag    string   `xml:"ETag"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if key == r.URL.Path || len(key) == 0 {
+		writeXML(w, http.StatusNotFound, fakeS3Error{Code: "NoSuchBucket", Message: r.URL.Path})
+		return
+	}
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+
+	switch {
+	case r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.writeHeader(w, data)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet:
+		f.get(w, r, key)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.next++
+		id := strconv.Itoa(f.next)
+		f.uploads[id] = map[int][]byte{}
+		writeXML(w, http.StatusOK, fakeS3InitiateResult{Bucket: f.bucket, Key: key, UploadID: id})
+	case r.Method == http.MethodPut && len(uploadID) > 0:
+		parts, ok := f.uploads[uploadID]
+		if !ok {
+			writeXML(w, http.StatusNotFound, fakeS3Error{Code: "NoSuchUpload", Message: uploadID})
+			return
+		}
+		number, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			writeXML(w, http.StatusBadRequest, fakeS3Error{Code: "InvalidArgument", Message: err.Error()})
+			return
+		}
+		data, err := readBody(r)
+		if err != nil {
+			writeXML(w, http.StatusBadRequest, fakeS3Error{Code: "IncompleteBody", Message: err.Error()})
+			return
+		}
+		parts[number] = data
+		w.Header().Set("ETag", etag(data))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && len(uploadID) > 0:
+		parts, ok := f.uploads[uploadID]
+		if !ok {
+			writeXML(w, http.StatusNotFound, fakeS3Error{Code: "NoSuchUpload", Message: uploadID})
+			return
+		}
+		numbers := make([]int, 0, len(parts))
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		data := make([]byte, 0)
+		for _, number := range numbers {
+			data = append(data, parts[number]...)
+		}
+		delete(f.uploads, uploadID)
+		f.objects[key] = data
+		writeXML(w, http.StatusOK, fakeS3CompleteResult{Bucket: f.bucket, Key: key, ETag: etag(data)})
+	case r.Method == http.MethodDelete && len(uploadID) > 0:
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeXML(w, http.StatusNotImplemented, fakeS3Error{Code: "NotImplemented", Message: r.Method + " " + r.URL.String()})
+	}
+}
+
+// readBody reads the body of the request, which is decoded if it uses aws-chunked encoding,
+// where each chunk is preceded by its size in hex and the last chunk is empty and followed by trailing headers.
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	br := bufio.NewReader(r.Body)
+	data := make([]byte, 0)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error reading chunk size: %w", err)
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(strings.SplitN(line, ";", 2)[0]), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing chunk size %q: %w", line, err)
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2) // the chunk and the line break after it
+		if _, err = io.ReadFull(br, chunk); err != nil {
+			return nil, fmt.Errorf("error reading chunk: %w", err)
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func (f *fakeS3) writeHeader(w http.ResponseWriter, data []byte) {
+	w.Header().Set("ETag", etag(data))
+	w.Header().Set("Last-Modified", time.Date(2020, time.July, 29, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+}
+
+// get writes the object, or the range of the object if the request has a range of the form bytes=start-end.
+func (f *fakeS3) get(w http.ResponseWriter, r *http.Request, key string) {
+	data, ok := f.objects[key]
+	if !ok {
+		writeXML(w, http.StatusNotFound, fakeS3Error{Code: "NoSuchKey", Message: key})
+		return
+	}
+	f.gets++
+	if match := r.Header.Get("If-Match"); len(match) > 0 && match != etag(data) {
+		writeXML(w, http.StatusPreconditionFailed, fakeS3Error{Code: "PreconditionFailed", Message: match})
+		return
+	}
+	status := http.StatusOK
+	body := data
+	if value := r.Header.Get("Range"); len(value) > 0 {
+		var start, end int
+		if _, err := fmt.Sscanf(value, "bytes=%d-%d", &start, &end); err != nil || start > end || end >= len(data) {
+			writeXML(w, http.StatusRequestedRangeNotSatisfiable, fakeS3Error{Code: "InvalidRange", Message: value})
+			return
+		}
+		status = http.StatusPartialContent
+		body = data[start : end+1]
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+	}
+	f.writeHeader(w, body)
+	w.Header().Set("ETag", etag(data))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// newTestS3Store returns a store for a fake S3 server with small blocks, so that reads cross block boundaries.
+func newTestS3Store(t *testing.T) (*S3Store, *fakeS3) {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	fake := newFakeS3("bucket")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	store, err := NewS3Store(S3Options{Endpoint: server.URL, Region: "us-east-1", Bucket: fake.bucket})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.blockSize = 64
+	store.maxBlocks = 4
+	return store, fake
+}
+
+// TestS3StoreBlockCache checks that reads are served from cached blocks and that the least recently used block is evicted.
+func TestS3StoreBlockCache(t *testing.T) {
+	store, fake := newTestS3Store(t)
+	data := testData(1000)
+	writeBlob(t, store, "export.zip", data)
+	b, err := store.Open(context.Background(), "export.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+	for _, test := range []struct {
+		offset int64
+		length int
+		gets   int // the number of GET requests expected for the read
+	}{
+		{offset: 0, length: 200, gets: 4},   // blocks 0 to 3
+		{offset: 10, length: 150, gets: 0},  // cached
+		{offset: 900, length: 100, gets: 2}, // blocks 14 and 15, which evict blocks 0 and 1
+		{offset: 130, length: 50, gets: 0},  // block 2 is still cached
+		{offset: 0, length: 10, gets: 1},    // block 0 was evicted
+	} {
+		before := fake.gets
+		p := make([]byte, test.length)
+		if _, err = b.ReadAt(p, test.offset); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, data[test.offset:test.offset+int64(test.length)]) {
+			t.Fatalf("unexpected bytes reading %d bytes at %d", test.length, test.offset)
+		}
+		if got := fake.gets - before; got != test.gets {
+			t.Fatalf("expecting %d requests reading %d bytes at %d, but found %d", test.gets, test.length, test.offset, got)
+		}
+	}
+}
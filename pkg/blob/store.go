@@ -0,0 +1,71 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// Blob is a stored object that supports ranged reads, e.g., to read the central directory of a zip file.
+type Blob interface {
+	io.ReaderAt
+	io.Closer
+	// Size returns the size of the blob in bytes.
+	Size() int64
+}
+
+// Writer writes a blob.  The blob is only complete once the writer is closed.
+type Writer interface {
+	io.WriteCloser
+	// Abort discards everything written, so that a partial blob is never left in the store.
+	Abort() error
+}
+
+// Store is blob storage, such as the local file system or an S3 bucket.
+type Store interface {
+	// Open opens the named blob for ranged reads.  The context applies to the reads of the blob until it is closed.
+	Open(ctx context.Context, name string) (Blob, error)
+	// Stat returns the size of the named blob.  If the blob does not exist, then the error wraps fs.ErrNotExist.
+	Stat(ctx context.Context, name string) (int64, error)
+	// Create returns a writer to the named blob.  The blob is only complete once the writer is closed.
+	// If a blob already exists with the name, then it is replaced when the writer is closed, but not if it is aborted.
+	Create(ctx context.Context, name string) (Writer, error)
+}
+
+const (
+	SchemeS3 = "s3://"
+)
+
+// ParseS3URL returns the bucket and key of an S3 URL, such as s3://bucket/exports/export.zip.
+// The key is blank if the URL only names the bucket.  If the location is not an S3 URL, then ok is false.
+func ParseS3URL(location string) (bucket string, key string, ok bool) {
+	if !strings.HasPrefix(location, SchemeS3) {
+		return "", "", false
+	}
+	rest := location[len(SchemeS3):]
+	if i := strings.Index(rest, "/"); i != -1 {
+		return rest[:i], strings.Trim(rest[i+1:], "/"), true
+	}
+	return rest, "", true
+}
+
+// NewStore returns the store for a location and the name of the location in the store.
+// S3 URLs use an S3 store for the bucket with the given options, and everything else uses the local file system.
+func NewStore(location string, options S3Options) (Store, string, error) {
+	if bucket, key, ok := ParseS3URL(location); ok {
+		options.Bucket = bucket
+		store, err := NewS3Store(options)
+		if err != nil {
+			return nil, "", err
+		}
+		return store, key, nil
+	}
+	return NewLocalStore(), location, nil
+}
@@ -0,0 +1,152 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"testing"
+)
+
+// testData returns bytes that differ at every offset within a block, so a read from the wrong block is detected.
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func writeBlob(t *testing.T, store Store, name string, data []byte) {
+	t.Helper()
+	w, err := store.Create(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(data); err != nil {
+		_ = w.Abort()
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readBlob(t *testing.T, store Store, name string) []byte {
+	t.Helper()
+	b, err := store.Open(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+	data, err := io.ReadAll(io.NewSectionReader(b, 0, b.Size()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// testStore checks the behavior shared by every store.  Names are joined to the prefix.
+func testStore(t *testing.T, store Store, prefix string) {
+	ctx := context.Background()
+	name := path.Join(prefix, "exports", "export.zip")
+	data := testData(1000)
+
+	t.Run("StatNotExist", func(t *testing.T) {
+		if _, err := store.Stat(ctx, path.Join(prefix, "missing.zip")); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("expecting fs.ErrNotExist, but found %v", err)
+		}
+		if _, err := store.Open(ctx, path.Join(prefix, "missing.zip")); err == nil {
+			t.Fatal("expecting an error opening a missing blob")
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		writeBlob(t, store, name, data)
+		size, err := store.Stat(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != int64(len(data)) {
+			t.Fatalf("expecting size %d, but found %d", len(data), size)
+		}
+		if got := readBlob(t, store, name); !bytes.Equal(got, data) {
+			t.Fatal("blob does not match the bytes written")
+		}
+	})
+
+	t.Run("ReadAt", func(t *testing.T) {
+		b, err := store.Open(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = b.Close() }()
+		if b.Size() != int64(len(data)) {
+			t.Fatalf("expecting size %d, but found %d", len(data), b.Size())
+		}
+		// ranges within a block, across one boundary, across several boundaries, and back to the start
+		for _, r := range [][2]int{{10, 20}, {60, 10}, {100, 300}, {0, 1000}, {5, 1}, {990, 10}} {
+			p := make([]byte, r[1])
+			n, readError := b.ReadAt(p, int64(r[0]))
+			if readError != nil {
+				t.Fatalf("error reading %d bytes at %d: %v", r[1], r[0], readError)
+			}
+			if n != r[1] || !bytes.Equal(p, data[r[0]:r[0]+r[1]]) {
+				t.Fatalf("unexpected bytes reading %d bytes at %d", r[1], r[0])
+			}
+		}
+		p := make([]byte, 20)
+		n, err := b.ReadAt(p, 990)
+		if !errors.Is(err, io.EOF) || n != 10 || !bytes.Equal(p[:n], data[990:]) {
+			t.Fatalf("expecting 10 bytes and io.EOF reading past the end, but found %d bytes and %v", n, err)
+		}
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		aborted := path.Join(prefix, "exports", "aborted.zip")
+		for _, n := range []string{aborted, name} {
+			w, err := store.Create(ctx, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = w.Write([]byte("partial")); err != nil {
+				t.Fatal(err)
+			}
+			if err = w.Abort(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := store.Stat(ctx, aborted); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("expecting fs.ErrNotExist after abort, but found %v", err)
+		}
+		if got := readBlob(t, store, name); !bytes.Equal(got, data) {
+			t.Fatal("aborted write replaced the existing blob")
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		replaced := testData(300)
+		writeBlob(t, store, name, replaced)
+		if got := readBlob(t, store, name); !bytes.Equal(got, replaced) {
+			t.Fatal("blob was not replaced")
+		}
+	})
+}
+
+func TestLocalStore(t *testing.T) {
+	testStore(t, NewLocalStore(), t.TempDir())
+}
+
+func TestS3Store(t *testing.T) {
+	store, _ := newTestS3Store(t)
+	testStore(t, store, "prefix")
+}
@@ -0,0 +1,279 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package container
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrUnknownFormat is returned when the source is empty or is not a container that can be opened.
+var ErrUnknownFormat = errors.New("source is not a zip, tar, gzip, or zstd file")
+
+// maxDepth limits how many containers can be nested inside each other.
+const maxDepth = 8
+
+type opener struct {
+	spool   Spooler
+	closers []io.Closer
+	depth   int
+}
+
+// Close releases everything that was spooled, in reverse order.
+func (o *opener) Close() error {
+	var first error
+	for i := len(o.closers) - 1; i >= 0; i-- {
+		if err := o.closers[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	o.closers = nil
+	return first
+}
+
+func (o *opener) enter() error {
+	o.depth++
+	if o.depth > maxDepth {
+		return fmt.Errorf("containers are nested more than %d deep", maxDepth)
+	}
+	return nil
+}
+
+func (o *opener) openReaderAt(r io.ReaderAt, size int64) (fs.FS, error) {
+	if err := o.enter(); err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerSize)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+	if detectFormat(header[:n]) == formatZip {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, fmt.Errorf("error opening zip reader: %w", err)
+		}
+		return o.openZip(zr)
+	}
+	return o.openStream(io.NewSectionReader(r, 0, size))
+}
+
+func (o *opener) openStream(r io.Reader) (fs.FS, error) {
+	if err := o.enter(); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(r, headerSize)
+	header, err := br.Peek(headerSize)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+	switch detectFormat(header) {
+	case formatZip:
+		ra, size, closer, err := o.spool(br)
+		if err != nil {
+			return nil, err
+		}
+		o.closers = append(o.closers, closer)
+		return o.openReaderAt(ra, size)
+	case formatGzip:
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("error opening gzip reader: %w", err)
+		}
+		defer func() { _ = gr.Close() }()
+		return o.openStream(gr)
+	case formatZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("error opening zstd reader: %w", err)
+		}
+		defer zr.Close()
+		return o.openStream(zr)
+	case formatTar:
+		return o.openTar(tar.NewReader(br))
+	}
+	if len(header) == 0 {
+		return nil, fmt.Errorf("source is empty: %w", ErrUnknownFormat)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// openZip returns the zip file, or the directory in the zip file that holds the export, e.g., "export/".
+// If the zip file does not hold an export, but holds a single container, then the export is read from the container.
+func (o *opener) openZip(zr *zip.Reader) (fs.FS, error) {
+	names := make([]string, 0, len(zr.File))
+	nested := make([]*zip.File, 0)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if !strings.HasSuffix(f.Name, "/") && isContainerName(f.Name) {
+			nested = append(nested, f)
+		}
+	}
+	if root, ok := exportRoot(names); ok {
+		return subRoot(zr, root)
+	}
+	if len(nested) != 1 {
+		return zr, nil
+	}
+	rc, err := nested[0].Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening nested container %q: %w", nested[0].Name, err)
+	}
+	defer func() { _ = rc.Close() }()
+	fsys, err := o.openStream(rc)
+	if err != nil {
+		return nil, fmt.Errorf("error opening nested container %q: %w", nested[0].Name, err)
+	}
+	return fsys, nil
+}
+
+// subRoot returns the directory of the file system that holds the export, where the root is blank or ends with a slash.
+func subRoot(fsys fs.FS, root string) (fs.FS, error) {
+	if len(root) == 0 {
+		return fsys, nil
+	}
+	sub, err := fs.Sub(fsys, strings.TrimSuffix(root, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error opening directory %q: %w", root, err)
+	}
+	return sub, nil
+}
+
+// spooled is a container in a tar file that was spooled.
+type spooled struct {
+	name   string
+	ra     io.ReaderAt
+	size   int64
+	closer io.Closer
+}
+
+// openTar reads the tar file as a stream.  The files in the tar file are written to a zip file as they are read,
+// and the zip file is spooled, so the export is never held in memory unless the spooler holds it in memory.
+// Containers in the tar file are spooled separately.  If the tar file holds an extracted export, then the directory
+// of the zip file with the export is returned.  Otherwise, the tar file must hold a single container with the export.
+func (o *opener) openTar(tr *tar.Reader) (fs.FS, error) {
+	pr, pw := io.Pipe()
+	var names []string
+	var nested []*spooled
+	var copyError error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		names, nested, copyError = o.copyTar(tr, pw)
+		_ = pw.CloseWithError(copyError)
+	}()
+	ra, size, closer, err := o.spool(pr)
+	// stop the copy if the spooler returned before reading everything
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	for _, n := range nested {
+		o.closers = append(o.closers, n.closer)
+	}
+	if err != nil {
+		// the spooler fails with the error of the copy, if the copy failed first
+		if copyError != nil && !errors.Is(copyError, io.ErrClosedPipe) {
+			return nil, copyError
+		}
+		return nil, fmt.Errorf("error spooling tar file: %w", err)
+	}
+	o.closers = append(o.closers, closer)
+	if copyError != nil {
+		return nil, copyError
+	}
+
+	if root, ok := exportRoot(names); ok {
+		zr, err := zip.NewReader(ra, size)
+		if err != nil {
+			return nil, fmt.Errorf("error opening zip reader: %w", err)
+		}
+		return subRoot(zr, root)
+	}
+	if len(nested) == 1 {
+		fsys, err := o.openReaderAt(nested[0].ra, nested[0].size)
+		if err != nil {
+			return nil, fmt.Errorf("error opening nested container %q: %w", nested[0].name, err)
+		}
+		return fsys, nil
+	}
+	return nil, fmt.Errorf("tar file does not hold an export or a single container with an export")
+}
+
+// copyTar writes the regular files in the tar file to a zip file written to w, and spools the containers in the tar file.
+// It returns the names of the files written to the zip file along with the spooled containers.
+func (o *opener) copyTar(tr *tar.Reader, w io.Writer) ([]string, []*spooled, error) {
+	names := make([]string, 0)
+	nested := make([]*spooled, 0)
+	zw := zip.NewWriter(w)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return names, nested, fmt.Errorf("error reading tar file: %w", err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean(strings.TrimPrefix(h.Name, "/")), "./")
+		if isContainerName(name) {
+			s := &spooled{name: name}
+			if s.ra, s.size, s.closer, err = o.spool(tr); err != nil {
+				return names, nested, fmt.Errorf("error spooling %q from tar file: %w", name, err)
+			}
+			nested = append(nested, s)
+			continue
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: h.ModTime})
+		if err != nil {
+			return names, nested, fmt.Errorf("error creating file %q: %w", name, err)
+		}
+		if _, err = io.Copy(fw, tr); err != nil {
+			return names, nested, fmt.Errorf("error copying %q from tar file: %w", name, err)
+		}
+		names = append(names, name)
+	}
+	if err := zw.Close(); err != nil {
+		return names, nested, fmt.Errorf("error closing zip writer: %w", err)
+	}
+	return names, nested, nil
+}
+
+// Open opens the export in a file that supports random access.  Zip files are read in place.
+// Anything spooled from nested containers is released when the returned closer is closed, but r is not closed.
+func Open(r io.ReaderAt, size int64, spool Spooler) (fs.FS, io.Closer, error) {
+	o := &opener{spool: spool}
+	fsys, err := o.openReaderAt(r, size)
+	if err != nil {
+		_ = o.Close()
+		return nil, nil, err
+	}
+	return fsys, o, nil
+}
+
+// OpenStream opens the export read from a stream, such as stdin or a decrypted file.
+// Zip files are spooled, since they require random access.  Everything spooled is released when the returned closer is closed.
+func OpenStream(r io.Reader, spool Spooler) (fs.FS, io.Closer, error) {
+	o := &opener{spool: spool}
+	fsys, err := o.openStream(r)
+	if err != nil {
+		_ = o.Close()
+		return nil, nil, err
+	}
+	return fsys, o, nil
+}
@@ -0,0 +1,210 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package container
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// testFiles are the files of an export, which are written under a prefix.
+var testFiles = map[string]string{
+	"org_users.json":                      `[{"id": "U1"}]`,
+	"dms.json":                            `[]`,
+	"teams/hello/general/2020-07-29.json": `[]`,
+}
+
+func newZip(t *testing.T, files map[string]string) []byte {
+	b := &bytes.Buffer{}
+	zw := zip.NewWriter(b)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func newTar(t *testing.T, files map[string]string) []byte {
+	b := &bytes.Buffer{}
+	tw := tar.NewWriter(b)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func newGzip(t *testing.T, data []byte) []byte {
+	b := &bytes.Buffer{}
+	gw := gzip.NewWriter(b)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func newZstd(t *testing.T, data []byte) []byte {
+	b := &bytes.Buffer{}
+	zw, err := zstd.NewWriter(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// prefixed returns the test files under the prefix.
+func prefixed(prefix string) map[string]string {
+	files := map[string]string{}
+	for name, data := range testFiles {
+		files[prefix+name] = data
+	}
+	return files
+}
+
+func TestOpen(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		data func(t *testing.T) []byte
+	}{
+		{name: "Zip", data: func(t *testing.T) []byte { return newZip(t, testFiles) }},
+		{name: "ZipDirectory", data: func(t *testing.T) []byte { return newZip(t, prefixed("export/")) }},
+		{name: "Tar", data: func(t *testing.T) []byte { return newTar(t, prefixed("./")) }},
+		{name: "TarGzip", data: func(t *testing.T) []byte { return newGzip(t, newTar(t, prefixed("export/"))) }},
+		{name: "TarZstd", data: func(t *testing.T) []byte { return newZstd(t, newTar(t, testFiles)) }},
+		{
+			name: "ZipInTar",
+			data: func(t *testing.T) []byte {
+				return newTar(t, map[string]string{"export.zip": string(newZip(t, testFiles)), "README.txt": "readme"})
+			},
+		},
+		{
+			name: "TarGzipInZip",
+			data: func(t *testing.T) []byte {
+				return newZip(t, map[string]string{"export.tar.gz": string(newGzip(t, newTar(t, testFiles)))})
+			},
+		},
+		{
+			name: "ZipInZipInGzip",
+			data: func(t *testing.T) []byte {
+				return newGzip(t, newZip(t, map[string]string{"export.zip": string(newZip(t, prefixed("export/")))}))
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data := test.data(t)
+			for _, open := range []struct {
+				name string
+				fn   func() (fs.FS, io.Closer, error)
+			}{
+				{name: "Open", fn: func() (fs.FS, io.Closer, error) {
+					return Open(bytes.NewReader(data), int64(len(data)), SpoolMemory)
+				}},
+				{name: "OpenStream", fn: func() (fs.FS, io.Closer, error) {
+					return OpenStream(bytes.NewReader(data), SpoolTempFile)
+				}},
+			} {
+				fsys, closer, err := open.fn()
+				if err != nil {
+					t.Fatalf("%s: %v", open.name, err)
+				}
+				for name, expected := range testFiles {
+					got, err := fs.ReadFile(fsys, name)
+					if err != nil {
+						t.Errorf("%s: error reading %q: %v", open.name, name, err)
+						continue
+					}
+					if string(got) != expected {
+						t.Errorf("%s: expecting %q to be %s, but found %s", open.name, name, expected, got)
+					}
+				}
+				if err = closer.Close(); err != nil {
+					t.Errorf("%s: error closing: %v", open.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		unknown bool // the error is ErrUnknownFormat
+	}{
+		{name: "Empty", data: func(t *testing.T) []byte { return []byte{} }, unknown: true},
+		{name: "Text", data: func(t *testing.T) []byte { return []byte("hello") }, unknown: true},
+		{name: "GzipText", data: func(t *testing.T) []byte { return newGzip(t, []byte("hello")) }, unknown: true},
+		{name: "TarWithoutExport", data: func(t *testing.T) []byte { return newTar(t, map[string]string{"README.txt": "readme"}) }},
+		{
+			name: "TarWithTwoContainers",
+			data: func(t *testing.T) []byte {
+				return newTar(t, map[string]string{"a.zip": string(newZip(t, testFiles)), "b.zip": string(newZip(t, testFiles))})
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := OpenStream(bytes.NewReader(test.data(t)), SpoolMemory)
+			if err == nil {
+				t.Fatal("expecting an error for an invalid source")
+			}
+			if test.unknown && !errors.Is(err, ErrUnknownFormat) {
+				t.Fatalf("expecting ErrUnknownFormat, but found %v", err)
+			}
+		})
+	}
+}
+
+func TestExportRoot(t *testing.T) {
+	for _, test := range []struct {
+		names    []string
+		expected string
+		found    bool
+	}{
+		{names: []string{"org_users.json"}, expected: "", found: true},
+		{names: []string{"export/teams/a/dms.json", "export/dms.json"}, expected: "export/", found: true},
+		{names: []string{"export/xorg_users.json"}, expected: "", found: false},
+		{names: []string{}, expected: "", found: false},
+	} {
+		root, found := exportRoot(test.names)
+		if root != test.expected || found != test.found {
+			t.Errorf("expecting root %q and %t for %q, but found %q and %t", test.expected, test.found, test.names, root, found)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package container opens Slack exports that are delivered inside other containers.
+//
+// An export can be a zip file, a zip file inside a zip file, or a tar file that is uncompressed or compressed with gzip or zstd.
+// A tar file can hold either the extracted export or a zip file of the export.
+// The format is detected from the content rather than the name, so exports can be read from stdin.
+//
+// Zip files require random access, so a zip file read from a stream is spooled first, e.g., to a temporary file.
+// Tar files are read as a stream, and the files of an extracted export are held in memory.
+package container
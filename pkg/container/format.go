@@ -0,0 +1,81 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package container
+
+import (
+	"bytes"
+	"strings"
+)
+
+type format int
+
+const (
+	formatUnknown format = iota
+	formatZip
+	formatGzip
+	formatZstd
+	formatTar
+)
+
+// headerSize is the number of bytes needed to detect every format, since the tar magic is at offset 257.
+const headerSize = 512
+
+var (
+	magicZip      = []byte("PK\x03\x04")
+	magicZipEmpty = []byte("PK\x05\x06")
+	magicGzip     = []byte{0x1f, 0x8b}
+	magicZstd     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicTar      = []byte("ustar")
+)
+
+func detectFormat(header []byte) format {
+	switch {
+	case bytes.HasPrefix(header, magicZip), bytes.HasPrefix(header, magicZipEmpty):
+		return formatZip
+	case bytes.HasPrefix(header, magicGzip):
+		return formatGzip
+	case bytes.HasPrefix(header, magicZstd):
+		return formatZstd
+	case len(header) >= 262 && bytes.Equal(header[257:262], magicTar):
+		return formatTar
+	}
+	return formatUnknown
+}
+
+// containerExtensions are the extensions of files inside a container that may hold the export.
+var containerExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz", ".tar.zst", ".tzst"}
+
+func isContainerName(name string) bool {
+	for _, ext := range containerExtensions {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootFiles are the files at the root of an enterprise grid export.
+var rootFiles = []string{"org_users.json", "dms.json"}
+
+// exportRoot returns the directory that contains the root files of the export, and false if there is none.
+// The directory is blank for the root or ends with a slash.
+func exportRoot(names []string) (string, bool) {
+	root, found := "", false
+	for _, name := range names {
+		for _, rootFile := range rootFiles {
+			if name != rootFile && !strings.HasSuffix(name, "/"+rootFile) {
+				continue
+			}
+			dir := strings.TrimSuffix(name, rootFile)
+			if !found || len(dir) < len(root) {
+				root, found = dir, true
+			}
+		}
+	}
+	return root, found
+}
@@ -0,0 +1,73 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Spooler copies a stream to storage that supports random access, and returns a closer that releases the storage.
+type Spooler func(r io.Reader) (io.ReaderAt, int64, io.Closer, error)
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+type tempFile struct {
+	file    *os.File
+	removed bool
+}
+
+// Close closes the temporary file and removes it, if it was not already removed.
+func (t *tempFile) Close() error {
+	err := t.file.Close()
+	if err != nil {
+		if !t.removed {
+			_ = os.Remove(t.file.Name())
+		}
+		return fmt.Errorf("error closing temporary file %q: %w", t.file.Name(), err)
+	}
+	if !t.removed {
+		err = os.Remove(t.file.Name())
+		if err != nil {
+			return fmt.Errorf("error removing temporary file %q: %w", t.file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// SpoolTempFile copies the stream to a temporary file that is removed when closed.
+// Where the operating system allows it, the file is removed as soon as it is created,
+// so nothing is left behind even if the process exits without closing it.
+func SpoolTempFile(r io.Reader) (io.ReaderAt, int64, io.Closer, error) {
+	f, err := os.CreateTemp("", "slack-archiver-*.spool")
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("error creating temporary file: %w", err)
+	}
+	t := &tempFile{file: f, removed: os.Remove(f.Name()) == nil}
+	size, err := io.Copy(f, r)
+	if err != nil {
+		_ = t.Close()
+		return nil, 0, nil, fmt.Errorf("error spooling to temporary file %q: %w", f.Name(), err)
+	}
+	return f, size, t, nil
+}
+
+// SpoolMemory copies the stream to memory, e.g., so that decrypted plaintext is never written to disk.
+func SpoolMemory(r io.Reader) (io.ReaderAt, int64, io.Closer, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("error spooling to memory: %w", err)
+	}
+	return bytes.NewReader(data), int64(len(data)), nopCloser{}, nil
+}
@@ -0,0 +1,52 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package cryptutil
+
+import (
+	"fmt"
+	"io"
+)
+
+type encryptedFile struct {
+	name   string
+	file   io.WriteCloser
+	writer io.WriteCloser
+}
+
+func (f *encryptedFile) Write(p []byte) (int, error) {
+	return f.writer.Write(p)
+}
+
+// Close flushes the encrypted file and then closes the underlying file.
+func (f *encryptedFile) Close() error {
+	err := f.writer.Close()
+	if err != nil {
+		_ = f.file.Close()
+		return fmt.Errorf("error flushing encrypted file %q: %w", f.name, err)
+	}
+	err = f.file.Close()
+	if err != nil {
+		return fmt.Errorf("error closing encrypted file %q: %w", f.name, err)
+	}
+	return nil
+}
+
+// NewWriter returns a writer that encrypts everything written to it to the recipients and writes the result to w,
+// e.g., a blob in object storage.  Closing the returned writer closes w.  If recipients is nil, then w is returned.
+// The name is only used in error messages.
+func NewWriter(name string, w io.WriteCloser, recipients *Recipients) (io.WriteCloser, error) {
+	if recipients == nil {
+		return w, nil
+	}
+	ew, err := recipients.Encrypt(w)
+	if err != nil {
+		_ = w.Close()
+		return nil, fmt.Errorf("error encrypting file %q: %w", name, err)
+	}
+	return &encryptedFile{name: name, file: w, writer: ew}, nil
+}
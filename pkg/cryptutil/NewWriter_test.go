@@ -0,0 +1,210 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package cryptutil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+type buffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *buffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newAgeKey returns the public key of a new age identity and the path to the identity file.
+func newAgeKey(t *testing.T) (string, string) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity.Recipient().String(), writeFile(t, "key.txt", []byte(identity.String()+"\n"))
+}
+
+// newPGPKey returns the paths to the armored public and private keys of a new OpenPGP entity.
+func newPGPKey(t *testing.T) (string, string) {
+	entity, err := openpgp.NewEntity("alice", "", "alice@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, private := &bytes.Buffer{}, &bytes.Buffer{}
+	w, err := pgparmor.Encode(public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	w, err = pgparmor.Encode(private, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	return writeFile(t, "key.pub.asc", public.Bytes()), writeFile(t, "key.asc", private.Bytes())
+}
+
+// encrypt encrypts the plaintext to the recipients with NewWriter.
+func encrypt(t *testing.T, recipients *Recipients, plaintext string) []byte {
+	out := &buffer{}
+	w, err := NewWriter("test", out, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.WriteString(w, plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !out.closed {
+		t.Fatal("expecting closing the encrypted writer to close the underlying writer")
+	}
+	return out.Bytes()
+}
+
+func decrypt(identities *Identities, ciphertext []byte) (string, error) {
+	r, err := identities.Decrypt(bytes.NewReader(ciphertext))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	agePublicKey, ageIdentity := newAgeKey(t)
+	pgpPublicKey, pgpIdentity := newPGPKey(t)
+	for _, test := range []struct {
+		name      string
+		recipient string
+		identity  string
+		extension string
+		armor     bool
+	}{
+		{name: "Age", recipient: agePublicKey, identity: ageIdentity, extension: ExtensionAge},
+		{name: "AgeArmored", recipient: agePublicKey, identity: ageIdentity, extension: ExtensionAge, armor: true},
+		{name: "AgeRecipientsFile", recipient: writeFile(t, "recipients.txt", []byte(agePublicKey+"\n")), identity: ageIdentity, extension: ExtensionAge},
+		{name: "OpenPGP", recipient: pgpPublicKey, identity: pgpIdentity, extension: ExtensionPGP},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			recipients, err := ParseRecipients([]string{test.recipient})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := recipients.Extension(); got != test.extension {
+				t.Errorf("expecting extension %q, but found %q", test.extension, got)
+			}
+			if !IsEncrypted("export.zip" + recipients.Extension()) {
+				t.Errorf("expecting a file with extension %q to be encrypted", recipients.Extension())
+			}
+
+			ciphertext := encrypt(t, recipients, "hello")
+			if test.armor {
+				b := &bytes.Buffer{}
+				w := armor.NewWriter(b)
+				_, _ = w.Write(ciphertext)
+				_ = w.Close()
+				ciphertext = b.Bytes()
+			}
+			if bytes.Contains(ciphertext, []byte("hello")) {
+				t.Fatal("expecting the plaintext to be encrypted")
+			}
+
+			identities, err := LoadIdentities([]string{test.identity}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plaintext, err := decrypt(identities, ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plaintext != "hello" {
+				t.Fatalf("expecting plaintext %q, but found %q", "hello", plaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptWrongIdentity(t *testing.T) {
+	agePublicKey, _ := newAgeKey(t)
+	_, otherIdentity := newAgeKey(t)
+	_, pgpIdentity := newPGPKey(t)
+	recipients, err := ParseRecipients([]string{agePublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := encrypt(t, recipients, "hello")
+	for _, path := range []string{otherIdentity, pgpIdentity} {
+		identities, err := LoadIdentities([]string{path}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = decrypt(identities, ciphertext); err == nil {
+			t.Errorf("expecting an error decrypting with identity %q", filepath.Base(path))
+		}
+	}
+}
+
+func TestParseRecipients(t *testing.T) {
+	agePublicKey, _ := newAgeKey(t)
+	pgpPublicKey, _ := newPGPKey(t)
+	for _, test := range []struct {
+		name   string
+		values []string
+	}{
+		{name: "None", values: []string{" "}},
+		{name: "Mixed", values: []string{agePublicKey, pgpPublicKey}},
+		{name: "InvalidAge", values: []string{"age1invalid"}},
+		{name: "MissingFile", values: []string{filepath.Join(t.TempDir(), "missing.txt")}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseRecipients(test.values); err == nil {
+				t.Fatal("expecting an error for invalid recipients")
+			}
+		})
+	}
+}
+
+func TestNewWriterWithoutRecipients(t *testing.T) {
+	out := &buffer{}
+	w, err := NewWriter("test", out, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != out {
+		t.Fatal("expecting the writer to be returned as is without recipients")
+	}
+}
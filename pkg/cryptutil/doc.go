@@ -0,0 +1,14 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package cryptutil includes tools for encrypting and decrypting files at rest with age or OpenPGP.
+//
+// Recipients are age public keys (age1...), paths to files of age public keys, or paths to OpenPGP public keys.
+// Identities are paths to files of age secret keys (AGE-SECRET-KEY-1...) or paths to OpenPGP private keys.
+//
+// Encrypted files use the ".age" extension for age and the ".gpg" extension for OpenPGP.
+package cryptutil
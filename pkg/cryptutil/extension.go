@@ -0,0 +1,22 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package cryptutil
+
+import (
+	"strings"
+)
+
+const (
+	ExtensionAge = ".age"
+	ExtensionPGP = ".gpg"
+)
+
+// IsEncrypted returns true if the name of the file has the extension of an age or OpenPGP encrypted file.
+func IsEncrypted(name string) bool {
+	return strings.HasSuffix(name, ExtensionAge) || strings.HasSuffix(name, ExtensionPGP)
+}
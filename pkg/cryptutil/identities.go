@@ -0,0 +1,117 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package cryptutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+var (
+	ageHeader        = []byte("age-encryption.org/")
+	ageArmorHeader   = []byte(armor.Header)
+	pgpMessageHeader = []byte("-----BEGIN PGP MESSAGE-----")
+)
+
+// Identities are the age or OpenPGP private keys used to decrypt files.
+type Identities struct {
+	age []age.Identity
+	pgp openpgp.EntityList
+}
+
+// Encrypted returns true if the named file is encrypted, so that Identities can be used to open encrypted archives.
+func (i *Identities) Encrypted(name string) bool {
+	return IsEncrypted(name)
+}
+
+// Decrypt returns a reader of the plaintext of the age or OpenPGP encrypted file read from r.
+// The format is detected from the header of the file, so binary and armored files are both supported.
+func (i *Identities) Decrypt(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(ageArmorHeader))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error reading header of encrypted file: %w", err)
+	}
+
+	if bytes.HasPrefix(header, ageHeader) || bytes.HasPrefix(header, ageArmorHeader) {
+		if len(i.age) == 0 {
+			return nil, fmt.Errorf("file is encrypted with age, but no age identities were given")
+		}
+		var src io.Reader = br
+		if bytes.HasPrefix(header, ageArmorHeader) {
+			src = armor.NewReader(br)
+		}
+		plaintext, decryptError := age.Decrypt(src, i.age...)
+		if decryptError != nil {
+			return nil, fmt.Errorf("error decrypting with age: %w", decryptError)
+		}
+		return plaintext, nil
+	}
+
+	if len(i.pgp) == 0 {
+		return nil, fmt.Errorf("file is not encrypted with age and no OpenPGP identities were given")
+	}
+	var src io.Reader = br
+	if bytes.HasPrefix(header, pgpMessageHeader) {
+		block, decodeError := pgparmor.Decode(br)
+		if decodeError != nil {
+			return nil, fmt.Errorf("error decoding armored OpenPGP message: %w", decodeError)
+		}
+		src = block.Body
+	}
+	md, err := openpgp.ReadMessage(src, i.pgp, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting with OpenPGP: %w", err)
+	}
+	return md.UnverifiedBody, nil
+}
+
+// LoadIdentities reads the age or OpenPGP private keys from the given files.
+// If the OpenPGP private keys are protected, then they are decrypted with the passphrase.
+func LoadIdentities(paths []string, passphrase []byte) (*Identities, error) {
+	i := &Identities{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading identity file %q: %w", path, err)
+		}
+		if isArmoredPGP(data) {
+			entities, parseError := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+			if parseError != nil {
+				return nil, fmt.Errorf("error parsing OpenPGP private key %q: %w", path, parseError)
+			}
+			for _, entity := range entities {
+				if len(passphrase) == 0 {
+					if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+						return nil, fmt.Errorf("OpenPGP private key %q is protected by a passphrase, but no passphrase was given", path)
+					}
+					continue
+				}
+				if decryptError := entity.DecryptPrivateKeys(passphrase); decryptError != nil {
+					return nil, fmt.Errorf("error decrypting OpenPGP private key %q: %w", path, decryptError)
+				}
+			}
+			i.pgp = append(i.pgp, entities...)
+			continue
+		}
+		identities, err := age.ParseIdentities(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing age identity file %q: %w", path, err)
+		}
+		i.age = append(i.age, identities...)
+	}
+	return i, nil
+}
@@ -0,0 +1,98 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package cryptutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Recipients are the age or OpenPGP public keys that files are encrypted to.
+type Recipients struct {
+	age []age.Recipient
+	pgp openpgp.EntityList
+}
+
+// Extension returns the extension used for files encrypted to the recipients.
+func (r *Recipients) Extension() string {
+	if len(r.pgp) > 0 {
+		return ExtensionPGP
+	}
+	return ExtensionAge
+}
+
+// Encrypt returns a writer that encrypts to the recipients everything written to it and writes the result to w.
+// The returned writer must be closed to flush the encrypted file, but closing it does not close w.
+func (r *Recipients) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	if len(r.pgp) > 0 {
+		ew, err := openpgp.Encrypt(w, r.pgp, nil, &openpgp.FileHints{IsBinary: true}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting with OpenPGP: %w", err)
+		}
+		return ew, nil
+	}
+	ew, err := age.Encrypt(w, r.age...)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting with age: %w", err)
+	}
+	return ew, nil
+}
+
+// ParseRecipients parses the given age public keys and paths to files of public keys.
+// The recipients must be either all age or all OpenPGP, since a file is only encrypted with one of them.
+func ParseRecipients(values []string) (*Recipients, error) {
+	r := &Recipients{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			continue
+		}
+		if strings.HasPrefix(value, "age1") {
+			recipient, err := age.ParseX25519Recipient(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing age recipient %q: %w", value, err)
+			}
+			r.age = append(r.age, recipient)
+			continue
+		}
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("error reading recipients file %q: %w", value, err)
+		}
+		if isArmoredPGP(data) {
+			entities, parseError := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+			if parseError != nil {
+				return nil, fmt.Errorf("error parsing OpenPGP public key %q: %w", value, parseError)
+			}
+			r.pgp = append(r.pgp, entities...)
+			continue
+		}
+		recipients, err := age.ParseRecipients(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing age recipients file %q: %w", value, err)
+		}
+		r.age = append(r.age, recipients...)
+	}
+	if len(r.age) == 0 && len(r.pgp) == 0 {
+		return nil, fmt.Errorf("no recipients found")
+	}
+	if len(r.age) > 0 && len(r.pgp) > 0 {
+		return nil, fmt.Errorf("recipients must be either all age or all OpenPGP")
+	}
+	return r, nil
+}
+
+func isArmoredPGP(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP"))
+}
@@ -0,0 +1,269 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package diff
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// dateRange is the first and last day of the day files in an export, formatted as YYYY-MM-DD.
+type dateRange struct {
+	first string
+	last  string
+}
+
+// contains returns true if the message was sent on a day in the range.
+func (r *dateRange) contains(m *slack.Message) bool {
+	t, err := slack.ParseTimestamp(m.Timestamp)
+	if err != nil || len(r.first) == 0 {
+		return false
+	}
+	day := t.UTC().Format(slack.DayFileDateFormat)
+	return day >= r.first && day <= r.last
+}
+
+// exportRange returns the range of the days of the day files across every conversation in the enterprise grid,
+// which is the date range the export was made for.  Conversations at unsafe paths are not included.
+func exportRange(e *slack.EnterpriseGrid) *dateRange {
+	r := &dateRange{}
+	for _, c := range e.GetConversations() {
+		dayFiles, err := e.GetDayFiles(c)
+		if err != nil || len(dayFiles) == 0 {
+			continue
+		}
+		if first := dayFiles[0].Day; len(r.first) == 0 || first < r.first {
+			r.first = first
+		}
+		if last := dayFiles[len(dayFiles)-1].Day; last > r.last {
+			r.last = last
+		}
+	}
+	return r
+}
+
+// Diff compares the old and new enterprise grids and calls fn for each change.
+// Conversations are matched by id and messages are matched by conversation id and timestamp.
+// Since exports are date-ranged, a message missing from the new grid is only reported as deleted
+// if it was sent within the date range of the new export, which is from the first to the last day file of any conversation.
+// So deletions are reported even if they are the first or last messages of a conversation, or every message of a conversation.
+// Comparing stops with the error of the context if it is canceled.
+func Diff(ctx context.Context, oldGrid *slack.EnterpriseGrid, newGrid *slack.EnterpriseGrid, fn func(c *Change) error) error {
+	err := diffUsers(oldGrid, newGrid, fn)
+	if err != nil {
+		return err
+	}
+
+	window := exportRange(newGrid)
+
+	oldConversations := map[string]*slack.Conversation{}
+	for _, c := range oldGrid.GetConversations() {
+		oldConversations[c.ID] = c
+	}
+
+	newConversations := map[string]*slack.Conversation{}
+	for _, c := range newGrid.GetConversations() {
+		newConversations[c.ID] = c
+	}
+
+	for _, c := range oldGrid.GetConversations() {
+		if _, ok := newConversations[c.ID]; !ok {
+			err = fn(&Change{Kind: KindConversation, Action: ActionRemoved, Conversation: c.ID, Old: c.Name})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, newConversation := range newGrid.GetConversations() {
+		oldConversation, ok := oldConversations[newConversation.ID]
+		if !ok {
+			err = fn(&Change{Kind: KindConversation, Action: ActionAdded, Conversation: newConversation.ID, New: newConversation.Name})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = diffConversation(ctx, oldGrid, oldConversation, newGrid, newConversation, window, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func diffUsers(oldGrid *slack.EnterpriseGrid, newGrid *slack.EnterpriseGrid, fn func(c *Change) error) error {
+	oldUsers := map[string]*slack.User{}
+	for _, u := range oldGrid.OrganizationUsers {
+		oldUsers[u.ID] = u
+	}
+	newUsers := map[string]*slack.User{}
+	for _, u := range newGrid.OrganizationUsers {
+		newUsers[u.ID] = u
+	}
+
+	for _, u := range oldGrid.OrganizationUsers {
+		if _, ok := newUsers[u.ID]; !ok {
+			err := fn(&Change{Kind: KindUser, Action: ActionRemoved, ID: u.ID, Old: u.Name})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, newUser := range newGrid.OrganizationUsers {
+		oldUser, ok := oldUsers[newUser.ID]
+		if !ok {
+			err := fn(&Change{Kind: KindUser, Action: ActionAdded, ID: newUser.ID, New: newUser.Name})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		fields := []struct {
+			name string
+			old  bool
+			new  bool
+		}{
+			{name: "deleted", old: oldUser.Deleted, new: newUser.Deleted},
+			{name: "is_restricted", old: oldUser.IsRestricted, new: newUser.IsRestricted},
+			{name: "is_ultra_restricted", old: oldUser.IsUltraRestricted, new: newUser.IsUltraRestricted},
+		}
+		for _, f := range fields {
+			if f.old != f.new {
+				err := fn(&Change{Kind: KindUser, Action: ActionChanged, ID: newUser.ID, Field: f.name, Old: f.old, New: f.new})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func diffConversation(
+	ctx context.Context,
+	oldGrid *slack.EnterpriseGrid,
+	oldConversation *slack.Conversation,
+	newGrid *slack.EnterpriseGrid,
+	newConversation *slack.Conversation,
+	window *dateRange,
+	fn func(c *Change) error,
+) error {
+	id := newConversation.ID
+
+	if oldConversation.Name != newConversation.Name {
+		err := fn(&Change{Kind: KindConversation, Action: ActionRenamed, Conversation: id, Old: oldConversation.Name, New: newConversation.Name})
+		if err != nil {
+			return err
+		}
+	}
+
+	oldMembers := map[string]struct{}{}
+	for _, m := range oldConversation.Members {
+		oldMembers[m] = struct{}{}
+	}
+	newMembers := map[string]struct{}{}
+	for _, m := range newConversation.Members {
+		newMembers[m] = struct{}{}
+	}
+	for _, m := range oldConversation.Members {
+		if _, ok := newMembers[m]; !ok {
+			err := fn(&Change{Kind: KindMember, Action: ActionRemoved, Conversation: id, ID: m})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range newConversation.Members {
+		if _, ok := oldMembers[m]; !ok {
+			err := fn(&Change{Kind: KindMember, Action: ActionAdded, Conversation: id, ID: m})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	oldMessages, err := oldGrid.GetConversationMessagesContext(ctx, oldConversation)
+	if err != nil {
+		return fmt.Errorf("error reading old messages: %w", err)
+	}
+
+	newMessages, err := newGrid.GetConversationMessagesContext(ctx, newConversation)
+	if err != nil {
+		return fmt.Errorf("error reading new messages: %w", err)
+	}
+
+	oldByTimestamp := map[string]*slack.Message{}
+	for _, m := range oldMessages {
+		oldByTimestamp[m.Timestamp] = m
+	}
+
+	newByTimestamp := map[string]*slack.Message{}
+	for _, m := range newMessages {
+		newByTimestamp[m.Timestamp] = m
+	}
+
+	for _, m := range oldMessages {
+		if _, ok := newByTimestamp[m.Timestamp]; ok {
+			continue
+		}
+		if !window.contains(m) {
+			continue
+		}
+		err = fn(&Change{Kind: KindMessage, Action: ActionDeleted, Conversation: id, Timestamp: m.Timestamp, Old: m.Text})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, newMessage := range newMessages {
+		oldMessage, ok := oldByTimestamp[newMessage.Timestamp]
+		if !ok {
+			err = fn(&Change{Kind: KindMessage, Action: ActionAdded, Conversation: id, Timestamp: newMessage.Timestamp, New: newMessage.Text})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if isEdited(oldMessage, newMessage) {
+			err = fn(&Change{Kind: KindMessage, Action: ActionEdited, Conversation: id, Timestamp: newMessage.Timestamp, Old: oldMessage.Text, New: newMessage.Text})
+			if err != nil {
+				return err
+			}
+		}
+		oldFiles := map[string]slack.MessageFile{}
+		for _, f := range oldMessage.Files {
+			oldFiles[f.ID] = f
+		}
+		for _, f := range newMessage.Files {
+			if oldFile, ok := oldFiles[f.ID]; ok && !oldFile.IsTombstone() && f.IsTombstone() {
+				err = fn(&Change{Kind: KindFile, Action: ActionTombstoned, Conversation: id, Timestamp: newMessage.Timestamp, ID: f.ID, Old: oldFile.Name})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func isEdited(oldMessage *slack.Message, newMessage *slack.Message) bool {
+	if oldMessage.Text != newMessage.Text {
+		return true
+	}
+	if newMessage.Edited == nil {
+		return false
+	}
+	return oldMessage.Edited == nil || oldMessage.Edited.Timestamp != newMessage.Edited.Timestamp
+}
@@ -0,0 +1,123 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package diff
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestGrid returns an enterprise grid with the channels general and random, and the day files of the channels.
+func newTestGrid(t *testing.T, dayFiles map[string]string) *slack.EnterpriseGrid {
+	files := slacktest.Files{
+		"org_users.json": `[{"id": "U1", "name": "alice"}]`,
+		"teams/hello/channels.json": `[
+			{"id": "C1", "name": "general", "members": ["U1"]},
+			{"id": "C2", "name": "random", "members": ["U1"]}
+		]`,
+		"teams/hello/users.json": `[{"id": "U1", "name": "alice"}]`,
+	}
+	for name, data := range dayFiles {
+		files["teams/hello/"+name] = data
+	}
+	return slacktest.NewGrid(t, files)
+}
+
+const (
+	july1 = `[{"type": "message", "user": "U1", "text": "july 1", "ts": "1593561600.000100"}]`
+	july2 = `[{"type": "message", "user": "U1", "text": "july 2", "ts": "1593648000.000100"}]`
+	july3 = `[{"type": "message", "user": "U1", "text": "july 3", "ts": "1593734400.000100"}]`
+)
+
+// TestDiffDeletedMessages checks that deletions are reported within the date range of the new export,
+// including the first and last messages of a conversation and conversations without any messages left.
+func TestDiffDeletedMessages(t *testing.T) {
+	old := map[string]string{
+		"general/2020-07-01.json": july1,
+		"general/2020-07-02.json": july2,
+		"general/2020-07-03.json": july3,
+		"random/2020-07-02.json":  july2,
+	}
+	for _, test := range []struct {
+		name     string
+		new      map[string]string
+		expected []string
+	}{
+		{
+			name:     "Unchanged",
+			new:      old,
+			expected: []string{},
+		},
+		{
+			name: "FirstAndLast",
+			new: map[string]string{
+				"general/2020-07-02.json": july2,
+				"random/2020-07-01.json":  july1,
+				"random/2020-07-02.json":  july2,
+				"random/2020-07-03.json":  july3,
+			},
+			expected: []string{"C1 july 1", "C1 july 3"},
+		},
+		{
+			name: "EveryMessage",
+			new: map[string]string{
+				"general/2020-07-01.json": july1,
+				"general/2020-07-02.json": july2,
+				"general/2020-07-03.json": july3,
+			},
+			expected: []string{"C2 july 2"},
+		},
+		{
+			name: "OutsideDateRange",
+			new: map[string]string{
+				"general/2020-07-03.json": july3,
+			},
+			expected: []string{},
+		},
+		{
+			name:     "EmptyExport",
+			new:      map[string]string{},
+			expected: []string{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			deleted := make([]string, 0)
+			err := Diff(context.Background(), newTestGrid(t, old), newTestGrid(t, test.new), func(c *Change) error {
+				if c.Kind == KindMessage && c.Action == ActionDeleted {
+					deleted = append(deleted, c.Conversation+" "+c.Old.(string))
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(deleted)
+			if got, expected := strings.Join(deleted, ","), strings.Join(test.expected, ","); got != expected {
+				t.Fatalf("expecting deleted messages %q, but found %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestDiffCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	days := map[string]string{"general/2020-07-01.json": july1}
+	err := Diff(ctx, newTestGrid(t, days), newTestGrid(t, days), func(c *Change) error {
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expecting context.Canceled, but found %v", err)
+	}
+}
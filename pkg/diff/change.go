@@ -0,0 +1,42 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package diff
+
+type Kind string
+
+const (
+	KindConversation Kind = "conversation"
+	KindMember       Kind = "member"
+	KindMessage      Kind = "message"
+	KindFile         Kind = "file"
+	KindUser         Kind = "user"
+)
+
+type Action string
+
+const (
+	ActionAdded      Action = "added"
+	ActionRemoved    Action = "removed"
+	ActionRenamed    Action = "renamed"
+	ActionEdited     Action = "edited"
+	ActionDeleted    Action = "deleted"
+	ActionTombstoned Action = "tombstoned"
+	ActionChanged    Action = "changed"
+)
+
+// Change is a single difference between the old and new archive.
+type Change struct {
+	Kind         Kind        `json:"kind"`
+	Action       Action      `json:"action"`
+	Conversation string      `json:"conversation,omitempty"` // the id of the conversation
+	ID           string      `json:"id,omitempty"`           // the id of the user, member, or file
+	Timestamp    string      `json:"ts,omitempty"`           // the timestamp of the message
+	Field        string      `json:"field,omitempty"`        // the field that changed
+	Old          interface{} `json:"old,omitempty"`
+	New          interface{} `json:"new,omitempty"`
+}
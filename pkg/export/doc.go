@@ -0,0 +1,9 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package export includes the interface and registry for exporting a Slack enterprise grid to other formats.
+package export
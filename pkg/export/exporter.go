@@ -0,0 +1,84 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// Options are the options shared by all exporters.
+type Options struct {
+	FilesDirectory string       // path to the files downloaded with "download files", if any.
+	Layout         slack.Layout // layout of the files directory, which defaults to the partitioned layout.
+	// Filter selects the messages that are exported, if not nil.
+	// Replies are exported as posts if the message they reply to is not selected.
+	Filter func(c *slack.Conversation, m *slack.Message) (bool, error)
+	// Logger logs the messages and conversations that cannot be exported, defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Exporter writes an enterprise grid to a writer in a target format.
+// Exporting stops with the error of the context if it is canceled.
+type Exporter interface {
+	Export(ctx context.Context, w io.Writer, e *slack.EnterpriseGrid) error
+}
+
+// Format is a named target format that can be registered.
+type Format struct {
+	Name        string
+	Description string
+	New         func(options Options) Exporter
+}
+
+var (
+	formatsMutex sync.RWMutex
+	formats      = map[string]Format{}
+)
+
+// Register makes an export format available by name.
+// Register panics if the format is registered twice.
+func Register(format Format) {
+	formatsMutex.Lock()
+	defer formatsMutex.Unlock()
+	if _, exists := formats[format.Name]; exists {
+		panic(fmt.Sprintf("export format %q is already registered", format.Name))
+	}
+	formats[format.Name] = format
+}
+
+// Formats returns the registered formats sorted by name.
+func Formats() []Format {
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// New returns a new exporter for the format with the given name.
+func New(name string, options Options) (Exporter, error) {
+	formatsMutex.RLock()
+	format, ok := formats[name]
+	formatsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q", name)
+	}
+	return format.New(options), nil
+}
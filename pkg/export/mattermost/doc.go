@@ -0,0 +1,10 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package mattermost includes an exporter for the Mattermost bulk import format,
+// as described at https://docs.mattermost.com/onboard/bulk-loading-data.html.
+package mattermost
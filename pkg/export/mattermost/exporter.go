@@ -0,0 +1,536 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package mattermost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+
+	"github.com/deptofdefense/slack-archiver/pkg/export"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+const (
+	ImportVersion = 1
+	// MaxDirectChannelMembers is the most members of a direct channel that Mattermost imports.
+	// Multiparty instant messages with more members are imported as private channels.
+	MaxDirectChannelMembers = 8
+)
+
+var Format = export.Format{
+	Name:        "mattermost",
+	Description: "Mattermost bulk import JSONL",
+	New:         New,
+}
+
+func init() {
+	export.Register(Format)
+}
+
+type Exporter struct {
+	options export.Options
+}
+
+func New(options export.Options) export.Exporter {
+	return &Exporter{options: options}
+}
+
+// post is the common representation of a post and a direct post.
+type post struct {
+	user        string
+	message     string
+	createAt    int64
+	reactions   []*Reaction
+	attachments []*Attachment
+	replies     []*Reply
+}
+
+type exportState struct {
+	*Exporter
+	encoder      *json.Encoder
+	grid         *slack.EnterpriseGrid
+	logger       *slog.Logger
+	usernames    map[string]string // user id to username
+	channelNames map[string]string // channel id to sanitized name
+	channelTeams map[string]string // id of multiparty instant message imported as a private channel to team name
+	skipped      map[string]int    // id of unknown user to the number of messages skipped
+}
+
+func (x *Exporter) Export(ctx context.Context, w io.Writer, e *slack.EnterpriseGrid) error {
+	s := &exportState{
+		Exporter:     x,
+		encoder:      json.NewEncoder(w),
+		grid:         e,
+		logger:       x.options.Logger,
+		usernames:    map[string]string{},
+		channelNames: map[string]string{},
+		channelTeams: map[string]string{},
+		skipped:      map[string]int{},
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
+	}
+
+	conversations := e.GetConversations()
+
+	for _, u := range e.OrganizationUsers {
+		if len(u.Name) > 0 {
+			s.usernames[u.ID] = u.Name
+		}
+	}
+	for _, t := range e.GetTeams() {
+		for _, u := range t.Users {
+			if _, ok := s.usernames[u.ID]; !ok && len(u.Name) > 0 {
+				s.usernames[u.ID] = u.Name
+			}
+		}
+	}
+	for _, c := range conversations {
+		if c.IsTeamConversation() {
+			continue
+		}
+		if members := s.getMembers(c); len(members) > MaxDirectChannelMembers {
+			team, err := s.getTeam(c)
+			if err != nil {
+				return err
+			}
+			s.channelTeams[c.ID] = team
+		}
+	}
+	err := s.nameChannels(conversations)
+	if err != nil {
+		return err
+	}
+
+	err = s.encode(&Line{Type: LineTypeVersion, Version: ImportVersion})
+	if err != nil {
+		return err
+	}
+
+	err = s.exportTeams()
+	if err != nil {
+		return err
+	}
+
+	err = s.exportChannels(conversations)
+	if err != nil {
+		return err
+	}
+
+	err = s.exportUsers(conversations)
+	if err != nil {
+		return err
+	}
+
+	err = s.exportDirectChannels(conversations)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range conversations {
+		err = s.exportPosts(ctx, c)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(s.skipped) > 0 {
+		ids := make([]string, 0, len(s.skipped))
+		messages := 0
+		for id, n := range s.skipped {
+			ids = append(ids, id)
+			messages += n
+		}
+		sort.Strings(ids)
+		s.logger.Warn("skipped messages from unknown users", "messages", messages, "users", ids)
+	}
+
+	return nil
+}
+
+// getTeam returns the team of a multiparty instant message imported as a private channel,
+// which is the team with the most of its members.
+func (s *exportState) getTeam(c *slack.Conversation) (string, error) {
+	members := map[string]struct{}{}
+	for _, id := range c.Members {
+		members[id] = struct{}{}
+	}
+	team, most := "", 0
+	for _, t := range s.grid.GetTeams() {
+		n := 0
+		for _, u := range t.Users {
+			if _, ok := members[u.ID]; ok {
+				n++
+			}
+		}
+		if n > most {
+			team, most = t.Name, n
+		}
+	}
+	if most == 0 {
+		return "", fmt.Errorf(
+			"error exporting %s %q: it has more than %d members, so it must be imported as a private channel, but none of its members are in a team",
+			c.Type,
+			c.Name,
+			MaxDirectChannelMembers,
+		)
+	}
+	return team, nil
+}
+
+// nameChannels assigns a Mattermost name to each conversation imported as a channel, which is unique within its team.
+// If two conversations in a team have the same sanitized name, e.g., "Ops" and "ops", then the later one is suffixed with a hash of its id.
+func (s *exportState) nameChannels(conversations []*slack.Conversation) error {
+	names := map[string]map[string]*slack.Conversation{} // team to channel name to conversation
+	for _, c := range conversations {
+		team, ok := s.getChannelTeam(c)
+		if !ok {
+			continue
+		}
+		teamName := sanitizeName(team)
+		if _, ok = names[teamName]; !ok {
+			names[teamName] = map[string]*slack.Conversation{}
+		}
+		name := sanitizeChannelName(c.Name, c.ID)
+		if _, exists := names[teamName][name]; exists {
+			name = appendIDHash(name, c.ID)
+		}
+		if other, exists := names[teamName][name]; exists {
+			return fmt.Errorf(
+				"error exporting %s %q: its channel name %q in team %q collides with %s %q",
+				c.Type,
+				c.Name,
+				name,
+				teamName,
+				other.Type,
+				other.Name,
+			)
+		}
+		names[teamName][name] = c
+		s.channelNames[c.ID] = name
+	}
+	return nil
+}
+
+// getChannelTeam returns the team of the conversation if it is imported as a channel, or false if it is imported as a direct channel.
+func (s *exportState) getChannelTeam(c *slack.Conversation) (string, bool) {
+	if c.IsTeamConversation() {
+		return c.Team, true
+	}
+	team, ok := s.channelTeams[c.ID]
+	return team, ok
+}
+
+func (s *exportState) encode(line *Line) error {
+	err := s.encoder.Encode(line)
+	if err != nil {
+		return fmt.Errorf("error encoding %s line: %w", line.Type, err)
+	}
+	return nil
+}
+
+func (s *exportState) exportTeams() error {
+	for _, t := range s.grid.GetTeams() {
+		err := s.encode(&Line{
+			Type: LineTypeTeam,
+			Team: &Team{
+				Name:        sanitizeName(t.Name),
+				DisplayName: t.Name,
+				Type:        TeamTypeInvite,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *exportState) exportChannels(conversations []*slack.Conversation) error {
+	for _, c := range conversations {
+		team, ok := s.getChannelTeam(c)
+		if !ok {
+			continue
+		}
+		channelType := ChannelTypeOpen
+		if c.Type != slack.ConversationTypeChannel {
+			channelType = ChannelTypePrivate
+		}
+		err := s.encode(&Line{
+			Type: LineTypeChannel,
+			Channel: &Channel{
+				Team:        sanitizeName(team),
+				Name:        s.channelNames[c.ID],
+				DisplayName: c.Name,
+				Type:        channelType,
+				Header:      c.Topic.Value,
+				Purpose:     c.Purpose.Value,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *exportState) exportUsers(conversations []*slack.Conversation) error {
+	// Team memberships come from the team user lists and the members of team channels and groups.
+	memberships := map[string][]*UserTeam{}
+	getUserTeam := func(userID string, teamName string) *UserTeam {
+		for _, ut := range memberships[userID] {
+			if ut.Name == teamName {
+				return ut
+			}
+		}
+		ut := &UserTeam{Name: teamName, Roles: "team_user"}
+		memberships[userID] = append(memberships[userID], ut)
+		return ut
+	}
+	for _, t := range s.grid.GetTeams() {
+		for _, u := range t.Users {
+			ut := getUserTeam(u.ID, sanitizeName(t.Name))
+			if u.IsAdmin || u.IsOwner {
+				ut.Roles = "team_user team_admin"
+			}
+		}
+	}
+	for _, c := range conversations {
+		team, ok := s.getChannelTeam(c)
+		if !ok {
+			continue
+		}
+		for _, member := range c.Members {
+			ut := getUserTeam(member, sanitizeName(team))
+			ut.Channels = append(ut.Channels, &UserChannel{Name: s.channelNames[c.ID], Roles: "channel_user"})
+		}
+	}
+
+	for _, u := range s.grid.OrganizationUsers {
+		if len(u.Name) == 0 {
+			continue
+		}
+		user := &User{
+			Username: u.Name,
+			Roles:    "system_user",
+			Teams:    memberships[u.ID],
+		}
+		if p := u.Profile; p != nil {
+			user.Email = p.Email
+			user.Nickname = p.DisplayName
+			user.FirstName = p.FirstName
+			user.LastName = p.LastName
+			user.Position = p.Title
+		}
+		if u.Deleted {
+			user.DeleteAt = int64(u.Updated) * 1000
+		}
+		err := s.encode(&Line{Type: LineTypeUser, User: user})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getMembers returns the usernames of the known members of a conversation.
+func (s *exportState) getMembers(c *slack.Conversation) []string {
+	members := make([]string, 0, len(c.Members))
+	for _, id := range c.Members {
+		if username, ok := s.usernames[id]; ok {
+			members = append(members, username)
+		}
+	}
+	return members
+}
+
+func (s *exportState) exportDirectChannels(conversations []*slack.Conversation) error {
+	for _, c := range conversations {
+		if _, ok := s.getChannelTeam(c); ok {
+			continue
+		}
+		members := s.getMembers(c)
+		if len(members) < 2 {
+			s.logger.Warn("skipped conversation with fewer than two known members", "type", c.Type, "name", c.Name)
+			continue
+		}
+		err := s.encode(&Line{
+			Type: LineTypeDirectChannel,
+			DirectChannel: &DirectChannel{
+				Members: members,
+				Header:  c.Topic.Value,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPost converts a message into a post.  If the author is not a known user, then the message is counted as skipped and newPost returns nil.
+func (s *exportState) newPost(m *slack.Message) (*post, error) {
+	username, ok := s.usernames[m.User]
+	if !ok {
+		s.skipped[m.User]++
+		s.logger.Debug("skipped message from unknown user", "user", m.User, "ts", m.Timestamp)
+		return nil, nil
+	}
+	created, err := slack.ParseTimestamp(m.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing timestamp of message: %w", err)
+	}
+	p := &post{
+		user:     username,
+		message:  convertText(m.Text, s.usernames, s.channelNames),
+		createAt: created.UnixNano() / int64(1000000),
+	}
+	for _, r := range m.Reactions {
+		for _, id := range r.Users {
+			if reactor, ok := s.usernames[id]; ok {
+				p.reactions = append(p.reactions, &Reaction{
+					User:      reactor,
+					EmojiName: sanitizeEmojiName(r.Name),
+					CreateAt:  p.createAt,
+				})
+			}
+		}
+	}
+	if len(s.options.FilesDirectory) > 0 {
+		for _, f := range m.Files {
+			if !f.IsHosted() {
+				continue
+			}
+			downloadPath, downloadPathError := f.GetDownloadPath(s.options.Layout)
+			if downloadPathError != nil {
+				return nil, fmt.Errorf("error creating download path for file %q: %w", f.ID, downloadPathError)
+			}
+			p.attachments = append(p.attachments, &Attachment{
+				Path: filepath.Join(s.options.FilesDirectory, filepath.FromSlash(downloadPath)),
+			})
+		}
+	}
+	return p, nil
+}
+
+func (s *exportState) exportPosts(ctx context.Context, c *slack.Conversation) error {
+	messages, err := s.grid.GetConversationMessagesContext(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if s.options.Filter != nil {
+		selected := make([]*slack.Message, 0, len(messages))
+		for _, m := range messages {
+			ok, filterError := s.options.Filter(c, m)
+			if filterError != nil {
+				return fmt.Errorf("error filtering message %q in %s %q: %w", m.Timestamp, c.Type, c.Name, filterError)
+			}
+			if ok {
+				selected = append(selected, m)
+			}
+		}
+		messages = selected
+	}
+
+	posts := make([]*post, 0, len(messages))
+	threads := map[string]*post{}
+
+	// Add the root messages first, so that replies can be attached regardless of order.
+	for _, m := range messages {
+		if m.IsReply() {
+			continue
+		}
+		p, newPostError := s.newPost(m)
+		if newPostError != nil {
+			return fmt.Errorf("error converting message %q in %s %q: %w", m.Timestamp, c.Type, c.Name, newPostError)
+		}
+		if p == nil {
+			continue
+		}
+		posts = append(posts, p)
+		threads[m.Timestamp] = p
+	}
+
+	for _, m := range messages {
+		if !m.IsReply() {
+			continue
+		}
+		p, newPostError := s.newPost(m)
+		if newPostError != nil {
+			return fmt.Errorf("error converting message %q in %s %q: %w", m.Timestamp, c.Type, c.Name, newPostError)
+		}
+		if p == nil {
+			continue
+		}
+		parent, ok := threads[m.ThreadTimestamp]
+		if !ok {
+			// the parent is missing from the export, so import the reply as a post.
+			posts = append(posts, p)
+			continue
+		}
+		parent.replies = append(parent.replies, &Reply{
+			User:        p.user,
+			Message:     p.message,
+			CreateAt:    p.createAt,
+			Reactions:   p.reactions,
+			Attachments: p.attachments,
+		})
+	}
+
+	team, isChannel := s.getChannelTeam(c)
+	var members []string
+	if !isChannel {
+		members = s.getMembers(c)
+		if len(members) < 2 {
+			if len(posts) > 0 {
+				s.logger.Warn("skipped posts of conversation with fewer than two known members", "type", c.Type, "name", c.Name, "posts", len(posts))
+			}
+			return nil
+		}
+	}
+
+	for _, p := range posts {
+		line := &Line{}
+		if isChannel {
+			line.Type = LineTypePost
+			line.Post = &Post{
+				Team:        sanitizeName(team),
+				Channel:     s.channelNames[c.ID],
+				User:        p.user,
+				Message:     p.message,
+				CreateAt:    p.createAt,
+				Reactions:   p.reactions,
+				Replies:     p.replies,
+				Attachments: p.attachments,
+			}
+		} else {
+			line.Type = LineTypeDirectPost
+			line.DirectPost = &DirectPost{
+				ChannelMembers: members,
+				User:           p.user,
+				Message:        p.message,
+				CreateAt:       p.createAt,
+				Reactions:      p.reactions,
+				Replies:        p.replies,
+				Attachments:    p.attachments,
+			}
+		}
+		err = s.encode(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
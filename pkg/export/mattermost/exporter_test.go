@@ -0,0 +1,97 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package mattermost
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/export"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestGrid returns an enterprise grid with a team of 12 users and two multiparty instant messages with more members than
+// a direct channel allows, whose names share the first 10 members.
+func newTestGrid(t *testing.T) *slack.EnterpriseGrid {
+	users := make([]string, 0, 12)
+	ids := make([]string, 0, 12)
+	names := make([]string, 0, 12)
+	for i := 1; i <= 12; i++ {
+		users = append(users, fmt.Sprintf(`{"id": "U%02d", "name": "user%02d"}`, i, i))
+		ids = append(ids, fmt.Sprintf(`"U%02d"`, i))
+		names = append(names, fmt.Sprintf("user%02d", i))
+	}
+	first := "mpdm-" + strings.Join(names[:11], "--") + "-1"
+	second := "mpdm-" + strings.Join(append(names[:10:10], names[11]), "--") + "-1"
+	return slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json": "[" + strings.Join(users, ",") + "]",
+		"mpims.json": fmt.Sprintf(`[
+			{"id": "G1", "name": %q, "members": [%s]},
+			{"id": "G2", "name": %q, "members": [%s]}
+		]`, first, strings.Join(ids[:11], ","), second, strings.Join(append(ids[:10:10], ids[11]), ",")),
+		first + "/2020-07-29.json":  `[{"type": "message", "user": "U01", "text": "first", "ts": "1596060000.000100"}]`,
+		second + "/2020-07-29.json": `[{"type": "message", "user": "U01", "text": "second", "ts": "1596060000.000100"}]`,
+		"teams/hello/channels.json": `[
+			{"id": "C1", "name": "Ops", "members": ["U01"]},
+			{"id": "C2", "name": "ops", "members": ["U01"]}
+		]`,
+		"teams/hello/users.json":          "[" + strings.Join(users, ",") + "]",
+		"teams/hello/Ops/2020-07-29.json": `[{"type": "message", "user": "U01", "text": "upper", "ts": "1596060000.000100"}]`,
+		"teams/hello/ops/2020-07-29.json": `[{"type": "message", "user": "U01", "text": "lower", "ts": "1596060000.000100"}]`,
+	})
+}
+
+func TestExportUniqueChannelNames(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := New(export.Options{}).Export(context.Background(), buf, newTestGrid(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channels := map[string]string{} // channel name to display name
+	posts := map[string]string{}    // message to channel name
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		line := &Line{}
+		if err = json.Unmarshal(scanner.Bytes(), line); err != nil {
+			t.Fatal(err)
+		}
+		switch line.Type {
+		case LineTypeChannel:
+			if other, ok := channels[line.Channel.Name]; ok {
+				t.Fatalf("channel name %q of %q is also used by %q", line.Channel.Name, line.Channel.DisplayName, other)
+			}
+			if len(line.Channel.Name) > maxNameLength {
+				t.Fatalf("channel name %q is longer than %d characters", line.Channel.Name, maxNameLength)
+			}
+			channels[line.Channel.Name] = line.Channel.DisplayName
+		case LineTypePost:
+			posts[line.Post.Message] = line.Post.Channel
+		}
+	}
+	if len(channels) != 4 {
+		t.Fatalf("expecting 4 channels, but found %d: %v", len(channels), channels)
+	}
+	if posts["first"] == posts["second"] || posts["upper"] == posts["lower"] {
+		t.Fatalf("expecting posts in different channels, but found %v", posts)
+	}
+	if posts["upper"] != "ops" {
+		t.Fatalf("expecting the first channel to keep its name, but found %q", posts["upper"])
+	}
+	for message, channel := range posts {
+		if _, ok := channels[channel]; !ok {
+			t.Fatalf("post %q is in channel %q, which is not exported", message, channel)
+		}
+	}
+}
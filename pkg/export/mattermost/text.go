@@ -0,0 +1,119 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package mattermost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+var (
+	slackReference  = regexp.MustCompile(`<([^<>]+)>`)
+	invalidNameChar = regexp.MustCompile(`[^a-z0-9_-]+`)
+	slackEntities   = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+)
+
+// Mattermost team and channel names must be lower case, alphanumeric (with dashes and underscores), and at most 64 characters.
+const maxNameLength = 64
+
+// idHashLength is the length of the hash of a conversation id appended to channel names that would not be unique.
+const idHashLength = 8
+
+// normalizeName converts a Slack name into lower case, alphanumeric characters, dashes, and underscores, without limiting its length.
+func normalizeName(name string) string {
+	s := strings.Trim(invalidNameChar.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(s) < 2 {
+		s += strings.Repeat("-", 2-len(s))
+	}
+	return s
+}
+
+// sanitizeName converts a Slack team name into a valid Mattermost name.
+func sanitizeName(name string) string {
+	s := normalizeName(name)
+	if len(s) > maxNameLength {
+		s = s[:maxNameLength]
+	}
+	return s
+}
+
+// sanitizeChannelName converts a Slack channel name into a valid Mattermost name.
+// Names that are too long are truncated and suffixed with a hash of the id of the conversation,
+// since large multiparty instant messages with the same first members share a long prefix, e.g., "mpdm-alice--bob--".
+func sanitizeChannelName(name string, id string) string {
+	s := normalizeName(name)
+	if len(s) > maxNameLength {
+		return appendIDHash(s, id)
+	}
+	return s
+}
+
+// appendIDHash returns the name suffixed with a short hash of the id, truncating the name so the result fits in a Mattermost name.
+func appendIDHash(name string, id string) string {
+	sum := sha256.Sum256([]byte(id))
+	if limit := maxNameLength - idHashLength - 1; len(name) > limit {
+		name = strings.TrimRight(name[:limit], "-_")
+	}
+	return name + "-" + hex.EncodeToString(sum[:])[:idHashLength]
+}
+
+// sanitizeEmojiName removes the skin tone modifier from a Slack emoji name.
+func sanitizeEmojiName(name string) string {
+	if i := strings.Index(name, "::"); i != -1 {
+		return name[:i]
+	}
+	return name
+}
+
+// convertText converts Slack message formatting, e.g., "<@U123>" or "<https://example.com|example>", into Markdown used by Mattermost.
+func convertText(text string, usernames map[string]string, channelNames map[string]string) string {
+	text = slackReference.ReplaceAllStringFunc(text, func(match string) string {
+		ref, label := match[1:len(match)-1], ""
+		if i := strings.Index(ref, "|"); i != -1 {
+			ref, label = ref[:i], ref[i+1:]
+		}
+		switch {
+		case strings.HasPrefix(ref, "@"):
+			if username, ok := usernames[ref[1:]]; ok {
+				return "@" + username
+			}
+			if len(label) > 0 {
+				return "@" + label
+			}
+			return "@" + ref[1:]
+		case strings.HasPrefix(ref, "#"):
+			if channelName, ok := channelNames[ref[1:]]; ok {
+				return "~" + channelName
+			}
+			if len(label) > 0 {
+				return "~" + label
+			}
+			return "~" + ref[1:]
+		case strings.HasPrefix(ref, "!"):
+			switch ref {
+			case "!here":
+				return "@here"
+			case "!channel":
+				return "@channel"
+			case "!everyone":
+				return "@all"
+			}
+			if len(label) > 0 {
+				return label
+			}
+			return match
+		}
+		if len(label) > 0 && label != ref {
+			return "[" + label + "](" + ref + ")"
+		}
+		return ref
+	})
+	return slackEntities.Replace(text)
+}
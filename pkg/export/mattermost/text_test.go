@@ -0,0 +1,57 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package mattermost
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeChannelName(t *testing.T) {
+	long := "mpdm-" + strings.Repeat("alice--", 10) + "bob-1"
+	for _, test := range []struct {
+		name     string
+		id       string
+		expected string
+	}{
+		{name: "General", id: "C1", expected: "general"},
+		{name: "a.b c", id: "C1", expected: "a-b-c"},
+		{name: "x", id: "C1", expected: "x-"},
+		{name: long, id: "G1", expected: appendIDHash(long, "G1")},
+	} {
+		if got := sanitizeChannelName(test.name, test.id); got != test.expected {
+			t.Fatalf("expecting %q for %q, but found %q", test.expected, test.name, got)
+		}
+	}
+	a, b := sanitizeChannelName(long, "G1"), sanitizeChannelName(long, "G2")
+	if a == b {
+		t.Fatalf("expecting different names for different ids, but found %q", a)
+	}
+	if len(a) > maxNameLength || strings.Contains(a, "---") {
+		t.Fatalf("unexpected truncated name %q", a)
+	}
+}
+
+func TestConvertText(t *testing.T) {
+	usernames := map[string]string{"U1": "alice"}
+	channelNames := map[string]string{"C1": "general"}
+	for _, test := range []struct {
+		text     string
+		expected string
+	}{
+		{text: "hi <@U1>", expected: "hi @alice"},
+		{text: "see <#C1|general>", expected: "see ~general"},
+		{text: "<!here> now", expected: "@here now"},
+		{text: "<https://example.com|example>", expected: "[example](https://example.com)"},
+		{text: "a &lt; b &amp;&amp; c", expected: "a < b && c"},
+	} {
+		if got := convertText(test.text, usernames, channelNames); got != test.expected {
+			t.Fatalf("expecting %q for %q, but found %q", test.expected, test.text, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package mattermost
+
+const (
+	LineTypeVersion       = "version"
+	LineTypeTeam          = "team"
+	LineTypeChannel       = "channel"
+	LineTypeUser          = "user"
+	LineTypePost          = "post"
+	LineTypeDirectChannel = "direct_channel"
+	LineTypeDirectPost    = "direct_post"
+)
+
+const (
+	ChannelTypeOpen    = "O"
+	ChannelTypePrivate = "P"
+	TeamTypeInvite     = "I"
+)
+
+// Line is a single line in a Mattermost bulk import file.
+// Exactly one of the fields besides Type is set.
+type Line struct {
+	Type          string         `json:"type"`
+	Version       int            `json:"version,omitempty"`
+	Team          *Team          `json:"team,omitempty"`
+	Channel       *Channel       `json:"channel,omitempty"`
+	User          *User          `json:"user,omitempty"`
+	Post          *Post          `json:"post,omitempty"`
+	DirectChannel *DirectChannel `json:"direct_channel,omitempty"`
+	DirectPost    *DirectPost    `json:"direct_post,omitempty"`
+}
+
+type Team struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+type Channel struct {
+	Team        string `json:"team"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+	Header      string `json:"header,omitempty"`
+	Purpose     string `json:"purpose,omitempty"`
+}
+
+type UserChannel struct {
+	Name  string `json:"name"`
+	Roles string `json:"roles"`
+}
+
+type UserTeam struct {
+	Name     string         `json:"name"`
+	Roles    string         `json:"roles"`
+	Channels []*UserChannel `json:"channels,omitempty"`
+}
+
+type User struct {
+	Username  string      `json:"username"`
+	Email     string      `json:"email,omitempty"`
+	Nickname  string      `json:"nickname,omitempty"`
+	FirstName string      `json:"first_name,omitempty"`
+	LastName  string      `json:"last_name,omitempty"`
+	Position  string      `json:"position,omitempty"`
+	Roles     string      `json:"roles"`
+	DeleteAt  int64       `json:"delete_at,omitempty"`
+	Teams     []*UserTeam `json:"teams,omitempty"`
+}
+
+type Reaction struct {
+	User      string `json:"user"`
+	EmojiName string `json:"emoji_name"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+type Attachment struct {
+	Path string `json:"path"`
+}
+
+type Reply struct {
+	User        string        `json:"user"`
+	Message     string        `json:"message"`
+	CreateAt    int64         `json:"create_at"`
+	Reactions   []*Reaction   `json:"reactions,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+type Post struct {
+	Team        string        `json:"team"`
+	Channel     string        `json:"channel"`
+	User        string        `json:"user"`
+	Message     string        `json:"message"`
+	CreateAt    int64         `json:"create_at"`
+	Reactions   []*Reaction   `json:"reactions,omitempty"`
+	Replies     []*Reply      `json:"replies,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+type DirectChannel struct {
+	Members []string `json:"members"`
+	Header  string   `json:"header,omitempty"`
+}
+
+type DirectPost struct {
+	ChannelMembers []string      `json:"channel_members"`
+	User           string        `json:"user"`
+	Message        string        `json:"message"`
+	CreateAt       int64         `json:"create_at"`
+	Reactions      []*Reaction   `json:"reactions,omitempty"`
+	Replies        []*Reply      `json:"replies,omitempty"`
+	Attachments    []*Attachment `json:"attachments,omitempty"`
+}
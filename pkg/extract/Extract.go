@@ -0,0 +1,211 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+type extractor struct {
+	grid       *slack.EnterpriseGrid
+	custodians map[string]struct{}
+	selections map[string]map[string]struct{} // the selected messages for each channel and group by id
+}
+
+// recordView is the minimal view of a user or conversation used to select records.
+type recordView struct {
+	ID      string   `json:"id"`
+	Members []string `json:"members"`
+}
+
+// messageView is the minimal view of a message used to select messages.
+type messageView struct {
+	User            string   `json:"user"`
+	ReplyUsers      []string `json:"reply_users"`
+	Timestamp       string   `json:"ts"`
+	ThreadTimestamp string   `json:"thread_ts"`
+	Files           []struct {
+		User string `json:"user"`
+	} `json:"files"`
+}
+
+func (x *extractor) isCustodian(id string) bool {
+	_, ok := x.custodians[id]
+	return ok
+}
+
+func (x *extractor) hasCustodian(ids []string) bool {
+	for _, id := range ids {
+		if x.isCustodian(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRelevant returns true if the message was authored by a custodian, includes a file uploaded by a custodian,
+// or is the root of a thread that a custodian replied to.
+func (x *extractor) isRelevant(m *messageView) bool {
+	if x.isCustodian(m.User) || x.hasCustodian(m.ReplyUsers) {
+		return true
+	}
+	for _, f := range m.Files {
+		if x.isCustodian(f.User) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectMessages returns the timestamps of the relevant messages in a channel or group,
+// along with every message in the threads of relevant messages.
+func (x *extractor) selectMessages(ctx context.Context, c *slack.Conversation) (map[string]struct{}, error) {
+	dayFiles, err := x.grid.GetDayFiles(c)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*messageView, 0)
+	for _, f := range dayFiles {
+		if err = ctx.Err(); err != nil {
+			return nil, fmt.Errorf("error reading messages for %s %q: %w", c.Type, c.Name, err)
+		}
+		records, err := x.grid.GetRecords(f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading messages for %s %q: %w", c.Type, c.Name, err)
+		}
+		for _, r := range records {
+			m := &messageView{}
+			if err = json.Unmarshal(r, m); err != nil {
+				return nil, fmt.Errorf("error unmarshaling message from file %q: %w", f.Name, err)
+			}
+			messages = append(messages, m)
+		}
+	}
+
+	threads := map[string]struct{}{}
+	selected := map[string]struct{}{}
+	for _, m := range messages {
+		if !x.isRelevant(m) {
+			continue
+		}
+		selected[m.Timestamp] = struct{}{}
+		if len(m.ThreadTimestamp) > 0 {
+			threads[m.ThreadTimestamp] = struct{}{}
+		}
+	}
+
+	for _, m := range messages {
+		if _, ok := threads[m.Timestamp]; ok {
+			selected[m.Timestamp] = struct{}{}
+			continue
+		}
+		if _, ok := threads[m.ThreadTimestamp]; ok {
+			selected[m.Timestamp] = struct{}{}
+		}
+	}
+
+	return selected, nil
+}
+
+// isExtracted returns true if the conversation has selected messages or a custodian as a member.
+func (x *extractor) isExtracted(id string, members []string) bool {
+	return len(x.selections[id]) > 0 || x.hasCustodian(members)
+}
+
+// extractRecords returns the records in a file of the enterprise grid that are extracted.
+// Users are extracted if they are custodians, and integration logs are not extracted.
+func (x *extractor) extractRecords(f *slack.GridFile, records []json.RawMessage) ([]json.RawMessage, error) {
+	extracted := make([]json.RawMessage, 0)
+	if f.Kind == slack.GridFileKindIntegrationLogs {
+		return extracted, nil
+	}
+	for _, r := range records {
+		v := &recordView{}
+		if err := json.Unmarshal(r, v); err != nil {
+			return nil, fmt.Errorf("error unmarshaling record in %q: %w", f.Name, err)
+		}
+		if f.Kind == slack.GridFileKindUsers {
+			if x.isCustodian(v.ID) {
+				extracted = append(extracted, r)
+			}
+			continue
+		}
+		if x.isExtracted(v.ID, v.Members) {
+			extracted = append(extracted, r)
+		}
+	}
+	return extracted, nil
+}
+
+// Extract writes a sub-archive with the records of the custodians to w.  It includes every direct message
+// and multiparty instant message with a custodian as a member, the messages in channels and groups authored by
+// a custodian or with a file uploaded by a custodian along with their threads, and the user records of the custodians.
+// The extracted records are copied with their original bytes.  Extracting stops with the error of the context if it is canceled.
+func Extract(ctx context.Context, e *slack.EnterpriseGrid, w *slack.ArchiveWriter, custodians []string) error {
+	x := &extractor{
+		grid:       e,
+		custodians: map[string]struct{}{},
+		selections: map[string]map[string]struct{}{},
+	}
+	for _, id := range custodians {
+		x.custodians[id] = struct{}{}
+	}
+
+	for _, c := range e.GetConversations() {
+		if !c.IsTeamConversation() {
+			continue
+		}
+		selected, err := x.selectMessages(ctx, c)
+		if err != nil {
+			return fmt.Errorf("error selecting messages: %w", err)
+		}
+		x.selections[c.ID] = selected
+	}
+
+	for _, f := range e.GetGridFiles() {
+		file := f
+		err := w.CopyFile(e, f.Name, func(records []json.RawMessage) ([]json.RawMessage, error) {
+			return x.extractRecords(file, records)
+		})
+		if err != nil {
+			return fmt.Errorf("error writing extracted enterprise grid: %w", err)
+		}
+	}
+
+	for _, c := range e.GetConversations() {
+		if !x.isExtracted(c.ID, c.Members) {
+			continue
+		}
+		selected, filtered := x.selections[c.ID]
+		err := w.CopyRawConversation(ctx, e, c, c, func(day string, messages []json.RawMessage) ([]json.RawMessage, error) {
+			if !filtered {
+				return messages, nil
+			}
+			kept := make([]json.RawMessage, 0, len(messages))
+			for _, m := range messages {
+				key, err := slack.UnmarshalRecordKey(m)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := selected[key.Timestamp]; ok {
+					kept = append(kept, m)
+				}
+			}
+			return kept, nil
+		})
+		if err != nil {
+			return fmt.Errorf("error extracting %s %q: %w", c.Type, c.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestGrid returns an enterprise grid where alice (U1) is in a direct message with bob (U2),
+// and posts, uploads files, and replies to threads in the general channel, which she is not a member of.
+func newTestGrid(t *testing.T) *slack.EnterpriseGrid {
+	return slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json":        `[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}, {"id": "U3", "name": "carol"}]`,
+		"dms.json":              `[{"id": "D1", "members": ["U1", "U2"]}, {"id": "D2", "members": ["U2", "U3"]}]`,
+		"integration_logs.json": `[{"service_id": "1", "date": "1596060000", "change_type": "added"}]`,
+		"D1/2020-07-29.json":    `[{"type": "message", "user": "U2", "text": "dm", "ts": "1596060000.000100"}]`,
+		"D2/2020-07-29.json":    `[{"type": "message", "user": "U2", "text": "other dm", "ts": "1596060000.000100"}]`,
+		"teams/hello/channels.json": `[
+			{"id": "C1", "name": "general", "members": ["U2", "U3"]},
+			{"id": "C2", "name": "random", "members": ["U2", "U3"]}
+		]`,
+		"teams/hello/users.json": `[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}]`,
+		"teams/hello/general/2020-07-29.json": `[
+			{"type": "message", "user": "U1", "text": "authored", "ts": "1596060000.000100"},
+			{"type": "message", "user": "U2", "text": "uploaded", "ts": "1596060001.000100", "files": [{"id": "F1", "user": "U1"}]},
+			{"type": "message", "user": "U2", "text": "root", "ts": "1596060002.000100", "thread_ts": "1596060002.000100", "reply_users": ["U1", "U3"]},
+			{"type": "message", "user": "U1", "text": "reply", "ts": "1596060003.000100", "thread_ts": "1596060002.000100"},
+			{"type": "message", "user": "U3", "text": "other reply", "ts": "1596060004.000100", "thread_ts": "1596060002.000100"},
+			{"type": "message", "user": "U2", "text": "unrelated", "ts": "1596060005.000100"},
+			{"type": "message", "user": "U3", "text": "unrelated reply", "ts": "1596060006.000100", "thread_ts": "1596060005.000100"}
+		]`,
+		"teams/hello/random/2020-07-29.json": `[{"type": "message", "user": "U2", "text": "random", "ts": "1596060000.000100"}]`,
+	})
+}
+
+// extractTestGrid extracts the records of the custodians and returns the contents of the files in the extracted archive.
+func extractTestGrid(t *testing.T, custodians ...string) map[string]string {
+	t.Helper()
+	files, err := slacktest.WriteArchive(t, func(w *slack.ArchiveWriter) error {
+		return Extract(context.Background(), newTestGrid(t), w, custodians)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// values returns the sorted values of the field of the records in the file, or nil if the file is missing.
+func values(t *testing.T, files map[string]string, name string, field string) []string {
+	data, ok := files[name]
+	if !ok {
+		return nil
+	}
+	records := make([]map[string]interface{}, 0)
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		t.Fatalf("error unmarshaling %q: %v", name, err)
+	}
+	s := make([]string, 0, len(records))
+	for _, r := range records {
+		s = append(s, r[field].(string))
+	}
+	sort.Strings(s)
+	return s
+}
+
+func TestExtract(t *testing.T) {
+	files := extractTestGrid(t, "U1")
+	for _, test := range []struct {
+		name     string
+		field    string
+		expected []string // the values of the field, or nil if the file is not extracted
+	}{
+		{name: "org_users.json", field: "id", expected: []string{"U1"}},
+		{name: "teams/hello/users.json", field: "id", expected: []string{"U1"}},
+		{name: "dms.json", field: "id", expected: []string{"D1"}},
+		{name: "integration_logs.json", field: "service_id", expected: []string{}},
+		{name: "teams/hello/channels.json", field: "id", expected: []string{"C1"}},
+		{name: "D1/2020-07-29.json", field: "text", expected: []string{"dm"}},
+		{name: "D2/2020-07-29.json", field: "text", expected: nil},
+		{name: "teams/hello/general/2020-07-29.json", field: "text", expected: []string{"authored", "other reply", "reply", "root", "uploaded"}},
+		{name: "teams/hello/random/2020-07-29.json", field: "text", expected: nil},
+	} {
+		got := values(t, files, test.name, test.field)
+		if (got == nil) != (test.expected == nil) || strings.Join(got, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("expecting %s of %q to be %q, but found %q", test.field, test.name, test.expected, got)
+		}
+	}
+}
+
+func TestExtractNoCustodians(t *testing.T) {
+	files := extractTestGrid(t)
+	for name := range files {
+		if strings.HasSuffix(name, "2020-07-29.json") {
+			t.Errorf("expecting no messages to be extracted, but found %q", name)
+		}
+	}
+	if got := values(t, files, "org_users.json", "id"); len(got) != 0 {
+		t.Errorf("expecting no users to be extracted, but found %q", got)
+	}
+}
@@ -0,0 +1,9 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package extract includes tools for extracting the records of custodians from a Slack archive.
+package extract
@@ -0,0 +1,83 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package jsonutil
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Array is a JSON array that keeps the original bytes of its elements.
+// Bytes returns the original array with only the changed elements replaced.
+type Array struct {
+	c *container
+}
+
+// ParseArray parses the JSON array without decoding its elements.
+func ParseArray(data []byte) (*Array, error) {
+	c, err := parseContainer(data, '[', ']')
+	if err != nil {
+		return nil, err
+	}
+	return &Array{c: c}, nil
+}
+
+// Len returns the number of elements in the array.
+func (a *Array) Len() int {
+	return len(a.c.members)
+}
+
+// Get returns the element at index i as raw JSON.
+func (a *Array) Get(i int) json.RawMessage {
+	return a.c.members[i].value
+}
+
+// Set replaces the element at index i.
+func (a *Array) Set(i int, value json.RawMessage) {
+	a.c.set(i, value)
+}
+
+// Filter removes the elements for which fn returns false.
+func (a *Array) Filter(fn func(value json.RawMessage) (bool, error)) error {
+	for i := 0; i < len(a.c.members); {
+		keep, err := fn(a.c.members[i].value)
+		if err != nil {
+			return err
+		}
+		if keep {
+			i++
+			continue
+		}
+		a.c.remove(i)
+	}
+	return nil
+}
+
+// Bytes returns the array as JSON.
+func (a *Array) Bytes() json.RawMessage {
+	return a.c.bytes()
+}
+
+// UpdateObjects calls fn with each element of the array, which must be objects, and writes back any changes.
+// Null elements are left unchanged.
+func (a *Array) UpdateObjects(fn func(obj *Object) error) error {
+	for i, m := range a.c.members {
+		if IsNull(m.value) {
+			continue
+		}
+		obj, err := ParseObject(m.value)
+		if err != nil {
+			return fmt.Errorf("error parsing element %d: %w", i, err)
+		}
+		if err = fn(obj); err != nil {
+			return err
+		}
+		a.c.set(i, obj.Bytes())
+	}
+	return nil
+}
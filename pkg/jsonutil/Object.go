@@ -0,0 +1,197 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package jsonutil
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Object is a JSON object that keeps the original bytes of its fields in their original order.
+// Bytes returns the original object with only the changed fields replaced.
+type Object struct {
+	c *container
+}
+
+// ParseObject parses the JSON object without decoding its values.
+func ParseObject(data []byte) (*Object, error) {
+	c, err := parseContainer(data, '{', '}')
+	if err != nil {
+		return nil, err
+	}
+	return &Object{c: c}, nil
+}
+
+func (o *Object) index(key string) int {
+	for i, m := range o.c.members {
+		if m.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Keys returns the keys of the object in their original order.
+func (o *Object) Keys() []string {
+	keys := make([]string, 0, len(o.c.members))
+	for _, m := range o.c.members {
+		keys = append(keys, m.key)
+	}
+	return keys
+}
+
+// Get returns the value of the key as raw JSON.
+func (o *Object) Get(key string) (json.RawMessage, bool) {
+	i := o.index(key)
+	if i < 0 {
+		return nil, false
+	}
+	return o.c.members[i].value, true
+}
+
+// GetString returns the value of the key if it is a string.
+func (o *Object) GetString(key string) (string, bool) {
+	value, ok := o.Get(key)
+	if !ok || len(value) == 0 || value[0] != '"' {
+		return "", false
+	}
+	str := ""
+	if err := json.Unmarshal(value, &str); err != nil {
+		return "", false
+	}
+	return str, true
+}
+
+// Set replaces the value of the key, or adds the key to the end of the object if it does not exist.
+func (o *Object) Set(key string, value json.RawMessage) error {
+	if i := o.index(key); i >= 0 {
+		o.c.set(i, value)
+		return nil
+	}
+	name, err := Marshal(key)
+	if err != nil {
+		return fmt.Errorf("error marshaling key %q: %w", key, err)
+	}
+	colon := []byte(":")
+	if len(o.c.members) > 0 {
+		first := o.c.members[0]
+		quoted, _ := Marshal(first.key)
+		if len(first.name) > len(quoted) {
+			colon = first.name[len(quoted):]
+		}
+	}
+	o.c.members = append(o.c.members, &member{key: key, name: append(name, colon...), value: value})
+	o.c.changed = true
+	return nil
+}
+
+// SetValue marshals v and sets it as the value of the key.
+func (o *Object) SetValue(key string, v interface{}) error {
+	value, err := Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error marshaling value of %q: %w", key, err)
+	}
+	return o.Set(key, value)
+}
+
+// Delete removes the key from the object.
+func (o *Object) Delete(key string) {
+	if i := o.index(key); i >= 0 {
+		o.c.remove(i)
+	}
+}
+
+// Bytes returns the object as JSON.
+func (o *Object) Bytes() json.RawMessage {
+	return o.c.bytes()
+}
+
+// UpdateString replaces the value of the key with the result of fn, if the value is a string.
+// Missing keys and values of other types are left unchanged.
+func (o *Object) UpdateString(key string, fn func(str string) string) error {
+	str, ok := o.GetString(key)
+	if !ok {
+		return nil
+	}
+	if updated := fn(str); updated != str {
+		return o.SetValue(key, updated)
+	}
+	return nil
+}
+
+// UpdateStrings replaces the value of the key with the result of fn, if the value is an array of strings.
+// Missing keys and null values are left unchanged.  The value is only written again if fn changes the strings.
+func (o *Object) UpdateStrings(key string, fn func(strs []string) []string) error {
+	value, ok := o.Get(key)
+	if !ok || IsNull(value) {
+		return nil
+	}
+	strs := make([]string, 0)
+	if err := json.Unmarshal(value, &strs); err != nil {
+		return fmt.Errorf("%w: error unmarshaling strings of %q: %s", ErrInvalidJSON, key, err)
+	}
+	updated := fn(append(make([]string, 0, len(strs)), strs...))
+	if equalStrings(strs, updated) {
+		return nil
+	}
+	return o.SetValue(key, updated)
+}
+
+// UpdateObject calls fn with the value of the key, which must be an object, and writes back any changes.
+// Missing keys and null values are left unchanged.
+func (o *Object) UpdateObject(key string, fn func(obj *Object) error) error {
+	value, ok := o.Get(key)
+	if !ok || IsNull(value) {
+		return nil
+	}
+	obj, err := ParseObject(value)
+	if err != nil {
+		return fmt.Errorf("error parsing %q: %w", key, err)
+	}
+	if err = fn(obj); err != nil {
+		return err
+	}
+	return o.Set(key, obj.Bytes())
+}
+
+// UpdateArray calls fn with the value of the key, which must be an array, and writes back any changes.
+// Missing keys and null values are left unchanged.
+func (o *Object) UpdateArray(key string, fn func(a *Array) error) error {
+	value, ok := o.Get(key)
+	if !ok || IsNull(value) {
+		return nil
+	}
+	a, err := ParseArray(value)
+	if err != nil {
+		return fmt.Errorf("error parsing %q: %w", key, err)
+	}
+	if err = fn(a); err != nil {
+		return err
+	}
+	return o.Set(key, a.Bytes())
+}
+
+// UpdateObjects calls fn with each object in the value of the key, which must be an array, and writes back any changes.
+// Missing keys, null values, and null elements are left unchanged.
+func (o *Object) UpdateObjects(key string, fn func(obj *Object) error) error {
+	return o.UpdateArray(key, func(a *Array) error {
+		return a.UpdateObjects(fn)
+	})
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package jsonutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const message = `{
+    "type": "message",
+    "subtype": "bot_message",
+    "text": "Hello <@U123>",
+    "attachments": [
+        {
+            "author_name": "Jane",
+            "fallback": "x"
+        },
+        null
+    ],
+    "reply_users": ["U123", "U456"],
+    "unicode": "é"
+}`
+
+func TestObjectUnchanged(t *testing.T) {
+	o, err := ParseObject([]byte(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = o.UpdateString("text", func(str string) string { return str }); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.UpdateStrings("reply_users", func(strs []string) []string { return strs }); err != nil {
+		t.Fatal(err)
+	}
+	if err = o.UpdateObjects("attachments", func(obj *Object) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(o.Bytes()); got != message {
+		t.Fatalf("expecting the original bytes, but found:\n%s", got)
+	}
+	if got := strings.Join(o.Keys(), ","); got != "type,subtype,text,attachments,reply_users,unicode" {
+		t.Fatalf("unexpected keys %q", got)
+	}
+}
+
+func TestObjectChanged(t *testing.T) {
+	o, err := ParseObject([]byte(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.UpdateString("text", func(str string) string { return strings.ReplaceAll(str, "U123", "U999") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.UpdateObjects("attachments", func(obj *Object) error {
+		obj.Delete("author_name")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Delete("reply_users")
+	if err = o.SetValue("edited", map[string]string{"user": "U999"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+    "type": "message",
+    "subtype": "bot_message",
+    "text": "Hello <@U999>",
+    "attachments": [
+        {
+            "fallback": "x"
+        },
+        null
+    ],
+    "unicode": "é",
+    "edited": {"user":"U999"}
+}`
+	if got := string(o.Bytes()); got != expected {
+		t.Fatalf("expecting:\n%s\nbut found:\n%s", expected, got)
+	}
+}
+
+func TestArrayFilter(t *testing.T) {
+	a, err := ParseArray([]byte(`[ 1, {"a": [2, "]"]}, 3 ]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Filter(func(value json.RawMessage) (bool, error) {
+		return string(value) != "1", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(a.Bytes()); got != `[ {"a": [2, "]"]}, 3 ]` {
+		t.Fatalf("unexpected array %s", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, data := range []string{``, `[]`, `{"a": }`, `"text"`} {
+		if _, err := ParseObject([]byte(data)); err == nil {
+			t.Errorf("expecting an error parsing %q", data)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package jsonutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidJSON is matched by errors.Is when a document is not valid JSON or is not the expected type.
+	ErrInvalidJSON = errors.New("invalid JSON")
+)
+
+// member is a value in an object or an array along with the bytes written before it,
+// i.e., the separator after the previous member and, in an object, the key and the colon.
+type member struct {
+	separator []byte // e.g., ",\n    ", or empty for the first member
+	key       string
+	name      []byte // the quoted key and the colon, e.g., `"text": `, or empty in an array
+	value     json.RawMessage
+}
+
+// container is an object or an array that keeps the original bytes of the document,
+// which are returned as is until a member is changed.
+type container struct {
+	data    []byte
+	head    []byte // the opening bracket and the whitespace after it
+	tail    []byte // the whitespace before the closing bracket and the bracket
+	members []*member
+	changed bool
+}
+
+func parseContainer(data []byte, open byte, close byte) (*container, error) {
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%w: document is not valid", ErrInvalidJSON)
+	}
+	c := &container{data: data, members: make([]*member, 0)}
+	i := skipSpace(data, 0)
+	if data[i] != open {
+		return nil, fmt.Errorf("%w: expecting %q but found %q", ErrInvalidJSON, open, data[i])
+	}
+	i = skipSpace(data, i+1)
+	if data[i] == close {
+		c.head, c.tail = data[:i], data[i:]
+		return c, nil
+	}
+	end := -1
+	for {
+		m := &member{}
+		if end >= 0 {
+			m.separator = data[end:i]
+		} else {
+			c.head = data[:i]
+		}
+		if open == '{' {
+			start := i
+			i = scanString(data, i)
+			if err := json.Unmarshal(data[start:i], &m.key); err != nil {
+				return nil, fmt.Errorf("%w: error unmarshaling key: %s", ErrInvalidJSON, err)
+			}
+			i = skipSpace(data, skipSpace(data, i)+1) // the colon
+			m.name = data[start:i]
+		}
+		end = scanValue(data, i)
+		m.value = data[i:end]
+		c.members = append(c.members, m)
+		i = skipSpace(data, end)
+		if data[i] == close {
+			c.tail = data[end:]
+			return c, nil
+		}
+		i = skipSpace(data, i+1) // the comma
+	}
+}
+
+func (c *container) set(i int, value json.RawMessage) {
+	if bytes.Equal(c.members[i].value, value) {
+		return
+	}
+	c.members[i].value = value
+	c.changed = true
+}
+
+func (c *container) remove(i int) {
+	c.members = append(c.members[:i], c.members[i+1:]...)
+	c.changed = true
+}
+
+// bytes returns the original document if no members were changed.
+// Otherwise, the members are written with their original separators, and added members reuse the separator of another member.
+func (c *container) bytes() json.RawMessage {
+	if !c.changed {
+		return c.data
+	}
+	separator := []byte(",")
+	for _, m := range c.members {
+		if len(m.separator) > 0 {
+			separator = m.separator
+			break
+		}
+	}
+	buf := &bytes.Buffer{}
+	buf.Write(c.head)
+	for i, m := range c.members {
+		if i > 0 {
+			if len(m.separator) > 0 {
+				buf.Write(m.separator)
+			} else {
+				buf.Write(separator)
+			}
+		}
+		buf.Write(m.name)
+		buf.Write(m.value)
+	}
+	buf.Write(c.tail)
+	return buf.Bytes()
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanString returns the offset after the string that starts at i.  The document must be valid.
+func scanString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// scanValue returns the offset after the value that starts at i.  The document must be valid.
+func scanValue(data []byte, i int) int {
+	switch data[i] {
+	case '"':
+		return scanString(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				i = scanString(data, i)
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return i
+	}
+	for i < len(data) {
+		switch data[i] {
+		case ',', '}', ']', ' ', '\t', '\r', '\n':
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// Marshal returns the JSON encoding of v without escaping HTML characters, to match the files exported by Slack.
+func Marshal(v interface{}) (json.RawMessage, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// IsNull returns true if the value is the JSON null literal.
+func IsNull(value json.RawMessage) bool {
+	return string(bytes.TrimSpace(value)) == "null"
+}
@@ -0,0 +1,10 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package jsonutil includes tools for changing JSON documents without decoding them into structs,
+// so the fields that are not changed, including fields unknown to this module, are written byte-for-byte.
+package jsonutil
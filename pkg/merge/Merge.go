@@ -0,0 +1,121 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// Merge combines the enterprise grids into one and writes it to the archive writer.
+// Grids should be ordered from oldest to newest, since later grids take precedence.
+// Users are combined by id with the latest updated time winning, conversations are combined by id
+// with their members combined, and messages are combined by conversation id and timestamp.
+// Records are written with their original bytes, so fields that are not modeled by the slack package are kept.
+// Merging stops with the error of the context if it is canceled.
+func Merge(ctx context.Context, grids []*slack.EnterpriseGrid, w *slack.ArchiveWriter) error {
+
+	// The records of each file by name, with the files in the order they are first found in the grids.
+	names := make([]string, 0)
+	kinds := map[string]slack.GridFileKind{}
+	lists := map[string][][]json.RawMessage{}
+	for i, g := range grids {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("error merging enterprise grids: %w", err)
+		}
+		for _, f := range g.GetGridFiles() {
+			records, err := g.GetRecords(f.Name)
+			if err != nil {
+				return fmt.Errorf("error reading records from archive %d: %w", i, err)
+			}
+			if _, ok := kinds[f.Name]; !ok {
+				names = append(names, f.Name)
+				kinds[f.Name] = f.Kind
+			}
+			lists[f.Name] = append(lists[f.Name], records)
+		}
+	}
+
+	for _, name := range names {
+		merged, err := unionRecords(kinds[name], lists[name]...)
+		if err != nil {
+			return fmt.Errorf("error merging records in %q: %w", name, err)
+		}
+		err = w.WriteRecords(name, merged)
+		if err != nil {
+			return fmt.Errorf("error writing merged enterprise grid: %w", err)
+		}
+	}
+
+	// Conversations in each grid by id, since names (and so paths) can change between exports.
+	// The merged conversations are written to the path of the conversation in the latest grid.
+	conversations := make([]*slack.Conversation, 0)
+	index := map[string]int{}
+	sources := make([]map[string]*slack.Conversation, 0, len(grids))
+	for _, g := range grids {
+		m := map[string]*slack.Conversation{}
+		for _, c := range g.GetConversations() {
+			m[c.ID] = c
+			if i, ok := index[c.ID]; ok {
+				conversations[i] = c
+				continue
+			}
+			index[c.ID] = len(conversations)
+			conversations = append(conversations, c)
+		}
+		sources = append(sources, m)
+	}
+
+	for _, c := range conversations {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("error merging messages: %w", err)
+		}
+		messages := make([]json.RawMessage, 0)
+		timestamps := map[string]int{}
+		for i, g := range grids {
+			source, ok := sources[i][c.ID]
+			if !ok {
+				continue
+			}
+			dayFiles, err := g.GetDayFiles(source)
+			if err != nil {
+				return fmt.Errorf("error reading messages from archive %d: %w", i, err)
+			}
+			for _, f := range dayFiles {
+				if err = ctx.Err(); err != nil {
+					return fmt.Errorf("error merging messages: %w", err)
+				}
+				records, err := g.GetRecords(f.Name)
+				if err != nil {
+					return fmt.Errorf("error reading messages from archive %d: %w", i, err)
+				}
+				for _, m := range records {
+					key, err := slack.UnmarshalRecordKey(m)
+					if err != nil {
+						return fmt.Errorf("error reading message in file %q from archive %d: %w", f.Name, i, err)
+					}
+					if j, exists := timestamps[key.Timestamp]; exists {
+						messages[j] = m
+						continue
+					}
+					timestamps[key.Timestamp] = len(messages)
+					messages = append(messages, m)
+				}
+			}
+		}
+		err := w.WriteRawMessages(c, messages)
+		if err != nil {
+			return fmt.Errorf("error writing merged messages: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package merge
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// mergeTestGrids merges the grids and returns the contents of the files in the merged archive.
+func mergeTestGrids(t *testing.T, grids ...*slack.EnterpriseGrid) map[string]string {
+	t.Helper()
+	files, err := slacktest.WriteArchive(t, func(w *slack.ArchiveWriter) error {
+		return Merge(context.Background(), grids, w)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestMerge(t *testing.T) {
+	oldGrid := slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json":            `[{"id": "U1", "name": "alice", "updated": 2}, {"id": "U2", "name": "bob", "updated": 1}]`,
+		"integration_logs.json":     `[{"service_id": "1", "date": "1596060000", "change_type": "added"}]`,
+		"teams/hello/channels.json": `[{"id": "C1", "name": "general", "members": ["U1", "U2"]}]`,
+		"teams/hello/users.json":    `[{"id": "U1", "name": "alice"}]`,
+		"teams/hello/general/2020-07-29.json": `[
+			{"type": "message", "user": "U1", "text": "first", "ts": "1596060000.000100"},
+			{"type": "message", "user": "U2", "text": "typo", "ts": "1596060001.000100", "x_custom": true}
+		]`,
+	})
+	newGrid := slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json":            `[{"id": "U1", "name": "alice-stale", "updated": 1}, {"id": "U2", "name": "robert", "updated": 3}, {"id": "U3", "name": "carol"}]`,
+		"integration_logs.json":     `[{"service_id": "1",  "date": "1596060000",  "change_type": "added"}, {"service_id": "2", "date": "1596070000", "change_type": "added"}]`,
+		"teams/hello/channels.json": `[{"id": "C1", "name": "announcements", "members": ["U3", "U1"]}]`,
+		"teams/hello/users.json":    `[{"id": "U1", "name": "alice"}]`,
+		"teams/hello/announcements/2020-07-29.json": `[
+			{"type": "message", "user": "U2", "text": "fixed", "ts": "1596060001.000100", "edited": {"user": "U2", "ts": "1596060005.000000"}}
+		]`,
+		"teams/hello/announcements/2020-07-30.json": `[{"type": "message", "user": "U3", "text": "later", "ts": "1596150000.000100"}]`,
+	})
+
+	files := mergeTestGrids(t, oldGrid, newGrid)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.HasPrefix(name, "teams/hello/general/") {
+			t.Errorf("expecting messages to be written to the path of the renamed channel, but found %q", name)
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "org_users.json",
+			contains: []string{`"name": "alice"`, `"name": "robert"`, `"name": "carol"`},
+			excludes: []string{"alice-stale", `"bob"`},
+		},
+		{
+			name:     "integration_logs.json",
+			contains: []string{`"service_id": "1"`, `"service_id": "2"`},
+		},
+		{
+			name:     "teams/hello/channels.json",
+			contains: []string{`"name": "announcements"`, `"members": ["U3","U1","U2"]`},
+			excludes: []string{`"general"`},
+		},
+		{
+			name:     "teams/hello/announcements/2020-07-29.json",
+			contains: []string{"first", "fixed", "1596060005.000000"},
+			excludes: []string{"typo", "x_custom"},
+		},
+		{
+			name:     "teams/hello/announcements/2020-07-30.json",
+			contains: []string{"later"},
+		},
+	} {
+		data, ok := files[test.name]
+		if !ok {
+			t.Errorf("expecting %q in the merged archive, but found %q", test.name, names)
+			continue
+		}
+		for _, s := range test.contains {
+			if !strings.Contains(data, s) {
+				t.Errorf("expecting %q to contain %s, but found %s", test.name, s, data)
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(data, s) {
+				t.Errorf("expecting %q to not contain %s, but found %s", test.name, s, data)
+			}
+		}
+	}
+
+	if n := strings.Count(files["integration_logs.json"], `"service_id"`); n != 2 {
+		t.Errorf("expecting 2 integration log messages without duplicates, but found %d", n)
+	}
+}
+
+func TestMergeCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := slacktest.WriteArchive(t, func(w *slack.ArchiveWriter) error {
+		return Merge(ctx, []*slack.EnterpriseGrid{slacktest.NewGrid(t, nil)}, w)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expecting the error of the canceled context, but found %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package merge includes tools for combining multiple Slack archives into one.
+package merge
@@ -0,0 +1,131 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package merge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/deptofdefense/slack-archiver/pkg/jsonutil"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// unionMembers returns the members of a followed by the members of b that are not in a.
+func unionMembers(a []string, b []string) []string {
+	members := make([]string, 0, len(a)+len(b))
+	seen := map[string]struct{}{}
+	for _, list := range [][]string{a, b} {
+		for _, m := range list {
+			if _, ok := seen[m]; !ok {
+				seen[m] = struct{}{}
+				members = append(members, m)
+			}
+		}
+	}
+	return members
+}
+
+// unionRecords returns the union of the records in the files of the given kind.
+func unionRecords(kind slack.GridFileKind, lists ...[]json.RawMessage) ([]json.RawMessage, error) {
+	switch kind {
+	case slack.GridFileKindUsers:
+		return unionUsers(lists...)
+	case slack.GridFileKindIntegrationLogs:
+		return unionIntegrationLogMessages(lists...)
+	}
+	return unionConversations(lists...)
+}
+
+// unionUsers returns the union of the users by id, where the user with the latest updated time wins.
+// If the updated times are equal, then the user from the later list wins.
+func unionUsers(lists ...[]json.RawMessage) ([]json.RawMessage, error) {
+	users := make([]json.RawMessage, 0)
+	updated := make([]int, 0)
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, u := range list {
+			key, err := slack.UnmarshalRecordKey(u)
+			if err != nil {
+				return nil, fmt.Errorf("error reading user: %w", err)
+			}
+			if i, ok := index[key.ID]; ok {
+				if key.Updated >= updated[i] {
+					users[i] = u
+					updated[i] = key.Updated
+				}
+				continue
+			}
+			index[key.ID] = len(users)
+			users = append(users, u)
+			updated = append(updated, key.Updated)
+		}
+	}
+	return users, nil
+}
+
+// unionConversations returns the union of the channels, groups, multiparty instant messages, or direct messages by id,
+// where later conversations win.  The members of earlier conversations that are not members of the later conversation
+// are added to the end of its members, so the later conversation is written as is unless members are added.
+func unionConversations(lists ...[]json.RawMessage) ([]json.RawMessage, error) {
+	conversations := make([]json.RawMessage, 0)
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, c := range list {
+			key, err := slack.UnmarshalRecordKey(c)
+			if err != nil {
+				return nil, fmt.Errorf("error reading conversation: %w", err)
+			}
+			i, ok := index[key.ID]
+			if !ok {
+				index[key.ID] = len(conversations)
+				conversations = append(conversations, c)
+				continue
+			}
+			previous := struct {
+				Members []string `json:"members"`
+			}{}
+			err = json.Unmarshal(conversations[i], &previous)
+			if err != nil {
+				return nil, fmt.Errorf("error reading members of conversation %q: %w", key.ID, err)
+			}
+			merged, err := jsonutil.ParseObject(c)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing conversation %q: %w", key.ID, err)
+			}
+			err = merged.UpdateStrings("members", func(members []string) []string {
+				return unionMembers(members, previous.Members)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error merging members of conversation %q: %w", key.ID, err)
+			}
+			conversations[i] = merged.Bytes()
+		}
+	}
+	return conversations, nil
+}
+
+// unionIntegrationLogMessages returns the union of the integration log messages, removing exact duplicates.
+// Integration log messages are compared without the whitespace between their fields.
+func unionIntegrationLogMessages(lists ...[]json.RawMessage) ([]json.RawMessage, error) {
+	logs := make([]json.RawMessage, 0)
+	seen := map[string]struct{}{}
+	for _, list := range lists {
+		for _, l := range list {
+			buf := &bytes.Buffer{}
+			if err := json.Compact(buf, l); err != nil {
+				return nil, fmt.Errorf("error reading integration log message: %w", err)
+			}
+			if _, ok := seen[buf.String()]; !ok {
+				seen[buf.String()] = struct{}{}
+				logs = append(logs, l)
+			}
+		}
+	}
+	return logs, nil
+}
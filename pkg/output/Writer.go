@@ -0,0 +1,331 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+	FormatTable  = "table"
+	FormatYAML   = "yaml"
+)
+
+// Formats are the supported output formats.
+var Formats = []string{FormatJSON, FormatNDJSON, FormatCSV, FormatTSV, FormatTable, FormatYAML}
+
+// Options are the options for writing records.
+type Options struct {
+	Format   string   // one of Formats, defaults to ndjson
+	Fields   []string // only write these fields, in order, by JSON name or dotted path
+	Sort     []string // sort by these fields, each prefixed with "-" to sort in descending order
+	Template string   // write each record with a Go template, followed by a newline, instead of the format
+}
+
+// Validate returns an error if the format, fields, sort, or template are invalid.
+func (o *Options) Validate() error {
+	_, err := o.parseTemplate()
+	if err != nil {
+		return err
+	}
+	if len(o.Template) > 0 && len(o.Fields) > 0 {
+		return fmt.Errorf("fields cannot be used with a template")
+	}
+	if len(o.Format) > 0 {
+		valid := false
+		for _, f := range Formats {
+			if o.Format == f {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid format %q, expecting one of %s", o.Format, strings.Join(Formats, ", "))
+		}
+	}
+	for _, name := range append(append([]string{}, o.Fields...), o.Sort...) {
+		if len(strings.Trim(strings.TrimPrefix(name, "-"), ".")) == 0 {
+			return fmt.Errorf("invalid field %q", name)
+		}
+	}
+	return nil
+}
+
+func (o *Options) parseTemplate() (*template.Template, error) {
+	if len(o.Template) == 0 {
+		return nil, nil
+	}
+	t, err := template.New("record").Option("missingkey=zero").Parse(o.Template)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+	return t, nil
+}
+
+// Writer writes records in the format of the options.  Close must be called to finish the output.
+type Writer struct {
+	writer   io.Writer
+	closer   io.Closer
+	options  *Options
+	format   string
+	template *template.Template
+	columns  []string
+	records  []*record // buffered until closed, if sorted
+	count    int
+	csv      *csv.Writer
+	table    *tabwriter.Writer
+}
+
+// NewWriter returns a writer of records to w.
+func NewWriter(w io.Writer, options *Options) (*Writer, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+	t, _ := options.parseTemplate()
+	format := options.Format
+	if len(format) == 0 {
+		format = FormatNDJSON
+	}
+	return &Writer{
+		writer:   w,
+		options:  options,
+		format:   format,
+		template: t,
+		columns:  options.Fields,
+	}, nil
+}
+
+// Create returns a writer of records to the file at the path, which is created or truncated, or to stdout if the path is blank or "-".
+// Closing the writer closes the file.
+func Create(path string, options *Options) (*Writer, error) {
+	if len(path) == 0 || path == "-" {
+		return NewWriter(os.Stdout, options)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output file %q: %w", path, err)
+	}
+	w, err := NewWriter(f, options)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// needsRecord returns true if the records are decoded to select, sort, or format their fields.
+func (w *Writer) needsRecord() bool {
+	if len(w.options.Fields) > 0 || len(w.options.Sort) > 0 {
+		return true
+	}
+	if w.template != nil {
+		return false
+	}
+	return w.format != FormatJSON && w.format != FormatNDJSON
+}
+
+// Write writes the record, or buffers it if the records are sorted.
+func (w *Writer) Write(v interface{}) error {
+	r := &record{value: v}
+	if w.needsRecord() {
+		var err error
+		r, err = newRecord(v)
+		if err != nil {
+			return err
+		}
+	}
+	if len(w.options.Sort) > 0 {
+		w.records = append(w.records, r)
+		return nil
+	}
+	return w.write(r)
+}
+
+func (w *Writer) write(r *record) error {
+	defer func() { w.count++ }()
+
+	if w.template != nil {
+		err := w.template.Execute(w.writer, r.value)
+		if err != nil {
+			return fmt.Errorf("error executing template: %w", err)
+		}
+		_, err = io.WriteString(w.writer, "\n")
+		return err
+	}
+
+	value := r.value
+	if len(w.options.Fields) > 0 {
+		value = r.fields(w.options.Fields)
+	}
+
+	switch w.format {
+	case FormatNDJSON:
+		return json.NewEncoder(w.writer).Encode(value)
+	case FormatJSON:
+		b, err := json.MarshalIndent(value, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling record: %w", err)
+		}
+		separator := ",\n  "
+		if w.count == 0 {
+			separator = "[\n  "
+		}
+		_, err = io.WriteString(w.writer, separator+string(b))
+		return err
+	case FormatYAML:
+		names := r.keys
+		if len(w.options.Fields) > 0 {
+			names = w.options.Fields
+		}
+		b, err := yaml.Marshal([]interface{}{r.fields(names).mapSlice()})
+		if err != nil {
+			return fmt.Errorf("error marshaling record: %w", err)
+		}
+		_, err = w.writer.Write(b)
+		return err
+	case FormatCSV, FormatTSV:
+		if w.csv == nil {
+			w.csv = csv.NewWriter(w.writer)
+			if w.format == FormatTSV {
+				w.csv.Comma = '\t'
+			}
+			w.columns = w.recordColumns(r)
+			if err := w.csv.Write(w.columns); err != nil {
+				return fmt.Errorf("error writing header: %w", err)
+			}
+		}
+		row := make([]string, 0, len(w.columns))
+		for _, f := range r.fields(w.columns) {
+			row = append(row, cell(f.Value))
+		}
+		return w.csv.Write(row)
+	case FormatTable:
+		if w.table == nil {
+			w.table = tabwriter.NewWriter(w.writer, 0, 4, 2, ' ', 0)
+			w.columns = w.recordColumns(r)
+			header := make([]string, 0, len(w.columns))
+			for _, c := range w.columns {
+				header = append(header, strings.ToUpper(c))
+			}
+			if _, err := io.WriteString(w.table, strings.Join(header, "\t")+"\n"); err != nil {
+				return fmt.Errorf("error writing header: %w", err)
+			}
+		}
+		row := make([]string, 0, len(w.columns))
+		for _, f := range r.fields(w.columns) {
+			// tabs and newlines would break the alignment of the table
+			row = append(row, strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(cell(f.Value)))
+		}
+		_, err := io.WriteString(w.table, strings.Join(row, "\t")+"\n")
+		return err
+	}
+	return fmt.Errorf("invalid format %q", w.format)
+}
+
+// recordColumns returns the fields of the options, or the top-level fields of the type of the first record.
+// Since fields that are empty can be omitted from a record, the columns are the fields of the type rather than the keys of the record,
+// followed by any other keys of the record, e.g., if the type marshals itself.
+func (w *Writer) recordColumns(r *record) []string {
+	if len(w.options.Fields) > 0 {
+		return w.options.Fields
+	}
+	if len(w.columns) == 0 {
+		w.columns = mergeKeys(typeKeys(reflect.TypeOf(r.value)), r.keys)
+	}
+	return w.columns
+}
+
+// sort sorts the buffered records by the sort fields.  Records that are equal keep the order they were written in.
+func (w *Writer) sort() {
+	sort.SliceStable(w.records, func(i, j int) bool {
+		for _, name := range w.options.Sort {
+			descending := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+			c := compare(w.records[i].get(name), w.records[j].get(name))
+			if c == 0 {
+				continue
+			}
+			if descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// Close writes the buffered records, finishes the output, and closes the file if the writer was created with Create.
+func (w *Writer) Close() error {
+	err := w.finish()
+	if w.closer != nil {
+		if closeError := w.closer.Close(); closeError != nil && err == nil {
+			err = fmt.Errorf("error closing output file: %w", closeError)
+		}
+	}
+	return err
+}
+
+func (w *Writer) finish() error {
+	if len(w.options.Sort) > 0 {
+		w.sort()
+		for _, r := range w.records {
+			if err := w.write(r); err != nil {
+				return err
+			}
+		}
+		w.records = nil
+	}
+
+	if w.template != nil {
+		return nil
+	}
+
+	switch w.format {
+	case FormatJSON:
+		end := "\n]\n"
+		if w.count == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(w.writer, end)
+		return err
+	case FormatYAML:
+		if w.count == 0 {
+			_, err := io.WriteString(w.writer, "[]\n")
+			return err
+		}
+	case FormatCSV, FormatTSV:
+		if w.csv != nil {
+			w.csv.Flush()
+			return w.csv.Error()
+		}
+	case FormatTable:
+		if w.table != nil {
+			return w.table.Flush()
+		}
+	}
+	return nil
+}
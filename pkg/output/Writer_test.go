@@ -0,0 +1,140 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testProfile struct {
+	Email string `json:"email,omitempty"`
+}
+
+type testUser struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name"`
+	Size    int         `json:"size,omitempty"`
+	Profile testProfile `json:"profile"`
+}
+
+var testUsers = []*testUser{
+	{ID: "U1", Name: "alice", Size: 10, Profile: testProfile{Email: "alice@example.com"}},
+	{ID: "U2", Name: "bob\tby"},
+	{ID: "U3", Name: "carol", Size: 100},
+}
+
+func TestWriter(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		options  *Options
+		records  []*testUser
+		expected string
+	}{
+		{
+			name:    "NDJSON",
+			options: &Options{},
+			records: testUsers[:2],
+			expected: `{"id":"U1","name":"alice","size":10,"profile":{"email":"alice@example.com"}}
+{"id":"U2","name":"bob\tby","profile":{}}
+`,
+		},
+		{
+			name:     "JSON",
+			options:  &Options{Format: FormatJSON, Fields: []string{"id", "profile.email"}},
+			records:  testUsers[:2],
+			expected: "[\n  {\n    \"id\": \"U1\",\n    \"profile.email\": \"alice@example.com\"\n  },\n  {\n    \"id\": \"U2\",\n    \"profile.email\": null\n  }\n]\n",
+		},
+		{
+			name:     "EmptyJSON",
+			options:  &Options{Format: FormatJSON},
+			records:  []*testUser{},
+			expected: "[]\n",
+		},
+		{
+			name:     "CSV",
+			options:  &Options{Format: FormatCSV},
+			records:  testUsers[:2],
+			expected: "id,name,size,profile\nU1,alice,10,\"{\"\"email\"\":\"\"alice@example.com\"\"}\"\nU2,bob\tby,,{}\n",
+		},
+		{
+			name:     "TSV",
+			options:  &Options{Format: FormatTSV, Fields: []string{"name", "profile.email"}},
+			records:  testUsers[:2],
+			expected: "name\tprofile.email\nalice\talice@example.com\n\"bob\tby\"\t\n",
+		},
+		{
+			name:     "Table",
+			options:  &Options{Format: FormatTable, Fields: []string{"id", "name"}},
+			records:  testUsers[:2],
+			expected: "ID  NAME\nU1  alice\nU2  bob by\n",
+		},
+		{
+			name:     "YAML",
+			options:  &Options{Format: FormatYAML, Fields: []string{"id", "size"}},
+			records:  testUsers[:2],
+			expected: "- id: U1\n  size: 10\n- id: U2\n  size: null\n",
+		},
+		{
+			name:     "Template",
+			options:  &Options{Template: "{{.Name}} <{{.Profile.Email}}>"},
+			records:  testUsers[:1],
+			expected: "alice <alice@example.com>\n",
+		},
+		{
+			name:     "SortNumbers",
+			options:  &Options{Format: FormatCSV, Fields: []string{"id"}, Sort: []string{"size"}},
+			records:  testUsers,
+			expected: "id\nU2\nU1\nU3\n",
+		},
+		{
+			name:     "SortDescending",
+			options:  &Options{Format: FormatCSV, Fields: []string{"id"}, Sort: []string{"-profile.email", "name"}},
+			records:  testUsers,
+			expected: "id\nU1\nU2\nU3\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			w, err := NewWriter(b, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range test.records {
+				if err = w.Write(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != test.expected {
+				t.Fatalf("expecting output %q, but found %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		options *Options
+	}{
+		{name: "Format", options: &Options{Format: "xml"}},
+		{name: "Field", options: &Options{Fields: []string{"."}}},
+		{name: "Sort", options: &Options{Sort: []string{"-"}}},
+		{name: "Template", options: &Options{Template: "{{.Name"}},
+		{name: "TemplateWithFields", options: &Options{Template: "{{.Name}}", Fields: []string{"id"}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.options.Validate(); err == nil {
+				t.Fatal("expecting an error for invalid options")
+			}
+		})
+	}
+}
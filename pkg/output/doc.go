@@ -0,0 +1,14 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package output writes the records listed by a command as JSON, newline-delimited JSON, CSV, TSV, an aligned table, YAML,
+// or with a Go template.
+//
+// Records are any values that can be marshaled to JSON.  Fields are selected and sorted by their JSON names,
+// and the fields of nested objects are selected with dotted paths, e.g., "profile.email".
+// Records are written as they are given, unless they are sorted, in which case they are buffered until the writer is closed.
+package output
@@ -0,0 +1,277 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// record is a value to write along with its JSON decoded into maps, slices, and json.Number values.
+type record struct {
+	value   interface{}
+	generic interface{}
+	keys    []string // the names of the top-level fields, in the order they are marshaled
+}
+
+func newRecord(v interface{}) (*record, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling record: %w", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var generic interface{}
+	err = d.Decode(&generic)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding record: %w", err)
+	}
+	keys, err := objectKeys(b)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding record: %w", err)
+	}
+	return &record{value: v, generic: generic, keys: keys}, nil
+}
+
+// objectKeys returns the keys of the JSON object in order, or nil if the JSON is not an object.
+func objectKeys(b []byte) ([]string, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != '{' {
+		return nil, nil
+	}
+	keys := make([]string, 0)
+	for d.More() {
+		t, err = d.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, t.(string))
+		var raw json.RawMessage
+		err = d.Decode(&raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// typeKeys returns the names of the top-level fields of the JSON encoding of a struct, in the order they are marshaled,
+// including the fields that are omitted when empty.  Fields of embedded structs are promoted as they are by encoding/json.
+// It returns nil if the type is not a struct or marshals itself.
+func typeKeys(t reflect.Type) []string {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return nil
+	}
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && len(name) == 0 {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				keys = mergeKeys(keys, typeKeys(ft))
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		keys = mergeKeys(keys, []string{name})
+	}
+	return keys
+}
+
+// mergeKeys returns the keys of a followed by the keys of b that are not in a.
+func mergeKeys(a []string, b []string) []string {
+	for _, k := range b {
+		found := false
+		for _, x := range a {
+			if x == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, k)
+		}
+	}
+	return a
+}
+
+// get returns the value at the dotted path, or nil if it does not exist.
+// A segment of the path can also be the index of an array, e.g., "members.0".
+func (r *record) get(path string) interface{} {
+	v := r.generic
+	for _, segment := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			v = x[segment]
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil
+			}
+			v = x[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// fields returns the values of the fields, in order.
+func (r *record) fields(names []string) fields {
+	f := make(fields, 0, len(names))
+	for _, name := range names {
+		f = append(f, field{Name: name, Value: r.get(name)})
+	}
+	return f
+}
+
+type field struct {
+	Name  string
+	Value interface{}
+}
+
+// fields are marshaled as an object with the keys in order.
+type fields []field
+
+func (f fields) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, x := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(x.Name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(x.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (f fields) mapSlice() yaml.MapSlice {
+	m := make(yaml.MapSlice, 0, len(f))
+	for _, x := range f {
+		m = append(m, yaml.MapItem{Key: x.Name, Value: yamlValue(x.Value)})
+	}
+	return m
+}
+
+// yamlValue converts the decoded JSON so numbers are written as numbers rather than strings.
+func yamlValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+		return x.String()
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[k] = yamlValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, 0, len(x))
+		for _, e := range x {
+			s = append(s, yamlValue(e))
+		}
+		return s
+	}
+	return v
+}
+
+// cell returns the value formatted for a column of a CSV, TSV, or table.  Objects and arrays are formatted as JSON.
+func cell(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// compare returns -1, 0, or 1 if a is less than, equal to, or greater than b.
+// Missing values are sorted first, and numbers are compared by value.
+func compare(a interface{}, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	x, xNumber := a.(json.Number)
+	y, yNumber := b.(json.Number)
+	if xNumber && yNumber {
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		if errx == nil && erry == nil {
+			switch {
+			case fx < fy:
+				return -1
+			case fx > fy:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(cell(a), cell(b))
+}
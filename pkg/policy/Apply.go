@@ -0,0 +1,210 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/deptofdefense/slack-archiver/pkg/jsonutil"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+type evaluation struct {
+	policy       *Policy
+	asOf         time.Time
+	report       *Report
+	rules        map[string]*RuleReport
+	conversation *ConversationReport
+	applied      map[string]struct{} // the names of the rules applied to the current conversation
+}
+
+// messageView is the minimal view of a message used to evaluate the rules.
+type messageView struct {
+	Timestamp string `json:"ts"`
+	Files     []struct {
+		Mode string `json:"mode"`
+	} `json:"files"`
+}
+
+func (v *messageView) hasTombstones() bool {
+	for _, f := range v.Files {
+		if f.Mode == "tombstone" {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *evaluation) apply(r *Rule) {
+	x.rules[r.Name].Messages++
+	if _, ok := x.applied[r.Name]; !ok {
+		x.applied[r.Name] = struct{}{}
+		x.rules[r.Name].Conversations++
+	}
+}
+
+// evaluate applies the rules to the messages and returns the messages that are kept.
+// Kept messages are returned with their original bytes, unless the metadata of their tombstoned files is dropped.
+func (x *evaluation) evaluate(c *slack.Conversation, messages []json.RawMessage) ([]json.RawMessage, error) {
+	kept := make([]json.RawMessage, 0, len(messages))
+	for _, data := range messages {
+		x.report.Messages++
+		x.conversation.Messages++
+
+		m := &messageView{}
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, fmt.Errorf("error unmarshaling message in %s %q: %w", c.Type, c.Name, err)
+		}
+
+		t, err := slack.ParseTimestamp(m.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing timestamp of message in %s %q: %w", c.Type, c.Name, err)
+		}
+
+		purge := false
+		decided := false
+		var dropTombstoneMetadata *Rule
+		for _, r := range x.policy.Rules {
+			if !r.Conversations.Match(c) {
+				continue
+			}
+			if r.olderThan != nil && !t.Before(r.olderThan.Before(x.asOf)) {
+				continue
+			}
+			switch r.Action {
+			case ActionDropTombstoneMetadata:
+				if dropTombstoneMetadata == nil {
+					dropTombstoneMetadata = r
+				}
+			case ActionKeep, ActionPurge:
+				if !decided {
+					decided = true
+					purge = r.Action == ActionPurge
+					x.apply(r)
+				}
+			}
+		}
+
+		if purge {
+			x.report.Purged++
+			x.conversation.Purged++
+			continue
+		}
+
+		if dropTombstoneMetadata != nil {
+			x.apply(dropTombstoneMetadata)
+			if m.hasTombstones() {
+				data, err = x.dropTombstoneMetadata(data)
+				if err != nil {
+					return nil, fmt.Errorf("error dropping tombstone metadata of message %q in %s %q: %w", m.Timestamp, c.Type, c.Name, err)
+				}
+			}
+		}
+
+		kept = append(kept, data)
+	}
+	return kept, nil
+}
+
+// dropTombstoneMetadata removes every field other than the id and mode from the tombstoned files of the message.
+func (x *evaluation) dropTombstoneMetadata(data json.RawMessage) (json.RawMessage, error) {
+	m, err := jsonutil.ParseObject(data)
+	if err != nil {
+		return nil, err
+	}
+	err = m.UpdateObjects("files", func(f *jsonutil.Object) error {
+		if mode, _ := f.GetString("mode"); mode != "tombstone" {
+			return nil
+		}
+		for _, key := range f.Keys() {
+			if key != "id" && key != "mode" {
+				f.Delete(key)
+			}
+		}
+		x.report.TombstonesDropped++
+		x.conversation.TombstonesDropped++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.Bytes(), nil
+}
+
+// Apply evaluates the policy over the messages in the enterprise grid as of the given time and returns a report.
+// If w is nil, then Apply is a dry run and nothing is written.
+// Otherwise, the enterprise grid is written to w without the purged messages.
+// Applying stops with the error of the context if it is canceled.
+func Apply(ctx context.Context, e *slack.EnterpriseGrid, p *Policy, asOf time.Time, w *slack.ArchiveWriter) (*Report, error) {
+	x := &evaluation{
+		policy: p,
+		asOf:   asOf,
+		report: &Report{
+			AsOf:          asOf,
+			DryRun:        w == nil,
+			Rules:         make([]*RuleReport, 0, len(p.Rules)),
+			Conversations: make([]*ConversationReport, 0),
+		},
+		rules: map[string]*RuleReport{},
+	}
+	for _, r := range p.Rules {
+		rr := &RuleReport{Name: r.Name, Action: r.Action}
+		x.rules[r.Name] = rr
+		x.report.Rules = append(x.report.Rules, rr)
+	}
+
+	if w != nil {
+		for _, f := range e.GetGridFiles() {
+			err := w.CopyFile(e, f.Name, func(records []json.RawMessage) ([]json.RawMessage, error) {
+				return records, nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error writing enterprise grid: %w", err)
+			}
+		}
+	}
+
+	for _, c := range e.GetConversations() {
+		conversation := c
+		x.conversation = &ConversationReport{Type: c.Type, ID: c.ID, Name: c.Name, Team: c.Team}
+		x.applied = map[string]struct{}{}
+		x.report.Conversations = append(x.report.Conversations, x.conversation)
+
+		if w == nil {
+			dayFiles, err := e.GetDayFiles(c)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range dayFiles {
+				if err = ctx.Err(); err != nil {
+					return nil, fmt.Errorf("error applying policy to %s %q: %w", c.Type, c.Name, err)
+				}
+				messages, err := e.GetRecords(f.Name)
+				if err != nil {
+					return nil, fmt.Errorf("error reading messages for %s %q: %w", c.Type, c.Name, err)
+				}
+				if _, err = x.evaluate(c, messages); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		err := w.CopyRawConversation(ctx, e, c, c, func(day string, messages []json.RawMessage) ([]json.RawMessage, error) {
+			return x.evaluate(conversation, messages)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error applying policy to %s %q: %w", c.Type, c.Name, err)
+		}
+	}
+
+	return x.report, nil
+}
@@ -0,0 +1,263 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// testAsOf is the time the policies are evaluated as of, so a message is older than 3y if it was sent before 2020-07-29.
+var testAsOf = time.Date(2023, time.July, 29, 0, 0, 0, 0, time.UTC)
+
+// newTestGrid returns an enterprise grid with a project channel, a general channel, and a direct message.
+// The timestamps of the messages are sent at noon on 2015-01-01 and 2019-01-01, and a second before and at 2020-07-29.
+func newTestGrid(t *testing.T) *slack.EnterpriseGrid {
+	return slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json":                      `[{"id": "U1", "name": "alice"}]`,
+		"dms.json":                            `[{"id": "D1", "members": ["U1"]}]`,
+		"teams/hello/channels.json":           `[{"id": "C1", "name": "proj-a", "members": ["U1"]}, {"id": "C2", "name": "general", "members": ["U1"]}]`,
+		"teams/hello/users.json":              `[{"id": "U1", "name": "alice"}]`,
+		"teams/hello/proj-a/2015-01-01.json":  `[{"type": "message", "user": "U1", "text": "a", "ts": "1420113600.000100"}]`,
+		"teams/hello/proj-a/2019-01-01.json":  `[{"type": "message", "user": "U1", "text": "b", "ts": "1546344000.000100"}]`,
+		"teams/hello/general/2019-01-01.json": `[{"type": "message", "user": "U1", "text": "c", "ts": "1546344000.000200"}]`,
+		"teams/hello/general/2020-07-28.json": `[{"type": "message", "user": "U1", "text": "d", "ts": "1595980799.999999"}]`,
+		"teams/hello/general/2020-07-29.json": `[{"type": "message", "user": "U1", "text": "e", "ts": "1595980800.000000"}]`,
+		"D1/2015-01-01.json": `[{
+			"type": "message", "user": "U1", "text": "f", "ts": "1420113600.000200",
+			"files": [{"id": "F1", "mode": "tombstone", "name": "notes.txt"}, {"id": "F2", "mode": "hosted", "name": "kept.txt"}]
+		}]`,
+	})
+}
+
+// applyTestPolicy applies the policy to the test enterprise grid and returns the report and the contents of the day files that were written.
+func applyTestPolicy(t *testing.T, policy string) (*Report, map[string]string) {
+	t.Helper()
+	p, err := ParsePolicy([]byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report *Report
+	files, err := slacktest.WriteArchive(t, func(w *slack.ArchiveWriter) error {
+		report, err = Apply(context.Background(), newTestGrid(t), p, testAsOf, w)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report, files
+}
+
+// keptTexts returns the sorted text of the messages in the day files.
+func keptTexts(t *testing.T, files map[string]string) []string {
+	texts := make([]string, 0)
+	for name, data := range files {
+		if _, err := time.Parse(slack.DayFileDateFormat+".json", path.Base(name)); err != nil {
+			continue
+		}
+		messages := make([]struct {
+			Text string `json:"text"`
+		}, 0)
+		if err := json.Unmarshal([]byte(data), &messages); err != nil {
+			t.Fatalf("error unmarshaling %q: %v", name, err)
+		}
+		for _, m := range messages {
+			texts = append(texts, m.Text)
+		}
+	}
+	sort.Strings(texts)
+	return texts
+}
+
+// TestApplyRules checks that the first matching keep or purge rule decides whether a message is kept,
+// and that only messages sent before the age of a rule are matched.
+func TestApplyRules(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		policy   string
+		kept     []string
+		messages map[string]int // the number of messages each rule was applied to
+	}{
+		{
+			name: "KeepBeforePurge",
+			policy: `rules:
+  - name: purge-expired-projects
+    conversations: {types: [channel], name: ^proj-}
+    older_than: 7y
+    action: purge
+  - name: keep-projects
+    conversations: {types: [channel], name: ^proj-}
+    action: keep
+  - name: purge-old-channels
+    conversations: {types: [channel]}
+    older_than: 3y
+    action: purge
+`,
+			kept:     []string{"b", "e", "f"},
+			messages: map[string]int{"purge-expired-projects": 1, "keep-projects": 1, "purge-old-channels": 2},
+		},
+		{
+			name: "PurgeBeforeKeep",
+			policy: `rules:
+  - name: purge-old-channels
+    conversations: {types: [channel]}
+    older_than: 3y
+    action: purge
+  - name: keep-projects
+    conversations: {types: [channel], name: ^proj-}
+    action: keep
+`,
+			kept:     []string{"e", "f"},
+			messages: map[string]int{"purge-old-channels": 4, "keep-projects": 0},
+		},
+		{
+			name: "KeepEverything",
+			policy: `rules:
+  - name: keep-channels
+    conversations: {types: [channel]}
+    action: keep
+  - name: purge-old
+    older_than: 1d
+    action: purge
+`,
+			kept:     []string{"a", "b", "c", "d", "e"},
+			messages: map[string]int{"keep-channels": 5, "purge-old": 1},
+		},
+		{
+			name: "Days",
+			policy: `rules:
+  - name: purge-old
+    older_than: 1096d
+    action: purge
+`,
+			kept:     []string{"d", "e"},
+			messages: map[string]int{"purge-old": 4},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			report, files := applyTestPolicy(t, test.policy)
+			if got, expected := strings.Join(keptTexts(t, files), ","), strings.Join(test.kept, ","); got != expected {
+				t.Fatalf("expecting kept messages %q, but found %q", expected, got)
+			}
+			if report.Purged != 6-len(test.kept) {
+				t.Errorf("expecting %d purged messages, but found %d", 6-len(test.kept), report.Purged)
+			}
+			for _, r := range report.Rules {
+				if r.Messages != test.messages[r.Name] {
+					t.Errorf("expecting rule %q to apply to %d messages, but found %d", r.Name, test.messages[r.Name], r.Messages)
+				}
+			}
+		})
+	}
+}
+
+// TestApplyDryRun checks that a dry run reports the same counts as applying the policy.
+func TestApplyDryRun(t *testing.T) {
+	policy := `rules:
+  - older_than: 3y
+    action: purge
+  - action: drop_tombstone_metadata
+`
+	p, err := ParsePolicy([]byte(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dryRun, err := Apply(context.Background(), newTestGrid(t), p, testAsOf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dryRun.DryRun {
+		t.Error("expecting the report of a dry run")
+	}
+	report, _ := applyTestPolicy(t, policy)
+	if dryRun.Messages != report.Messages || dryRun.Purged != report.Purged || dryRun.Purged != 5 {
+		t.Errorf("expecting %d messages and 5 purged, but found %d messages and %d purged", report.Messages, dryRun.Messages, dryRun.Purged)
+	}
+}
+
+// TestApplyDropTombstoneMetadata checks that only the id and mode of tombstoned files are kept.
+func TestApplyDropTombstoneMetadata(t *testing.T) {
+	report, files := applyTestPolicy(t, `rules:
+  - name: drop-tombstone-metadata
+    conversations: {types: [dm]}
+    action: drop_tombstone_metadata
+`)
+	if report.TombstonesDropped != 1 {
+		t.Errorf("expecting 1 tombstone dropped, but found %d", report.TombstonesDropped)
+	}
+	data := files["D1/2015-01-01.json"]
+	if strings.Contains(data, "notes.txt") {
+		t.Errorf("expecting the name of the tombstoned file to be dropped, but found %s", data)
+	}
+	if !strings.Contains(data, `"id": "F1"`) || !strings.Contains(data, "kept.txt") {
+		t.Errorf("expecting the id of the tombstoned file and the hosted file to be kept, but found %s", data)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	asOf := time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		age      string
+		expected string // the date the age is before asOf, or blank if the age is invalid
+	}{
+		{age: "3y", expected: "2020-03-31"},
+		{age: "1m", expected: "2023-03-03"}, // February 31st is normalized to March 3rd
+		{age: "2w", expected: "2023-03-17"},
+		{age: "10d", expected: "2023-03-21"},
+		{age: "0d", expected: "2023-03-31"},
+		{age: "", expected: ""},
+		{age: "y", expected: ""},
+		{age: "-1d", expected: ""},
+		{age: "3h", expected: ""},
+		{age: "1.5y", expected: ""},
+	} {
+		age, err := ParseAge(test.age)
+		if len(test.expected) == 0 {
+			if err == nil {
+				t.Errorf("expecting an error for age %q", test.age)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for age %q: %v", test.age, err)
+			continue
+		}
+		if got := age.Before(asOf).Format("2006-01-02"); got != test.expected {
+			t.Errorf("expecting %q before %s to be %s, but found %s", test.age, asOf.Format("2006-01-02"), test.expected, got)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		policy string
+	}{
+		{name: "NoRules", policy: `rules: []`},
+		{name: "UnknownField", policy: "rules:\n  - action: keep\n    older: 3y\n"},
+		{name: "InvalidAction", policy: "rules:\n  - action: archive\n"},
+		{name: "InvalidAge", policy: "rules:\n  - action: purge\n    older_than: 3\n"},
+		{name: "InvalidType", policy: "rules:\n  - action: purge\n    conversations: {types: [thread]}\n"},
+		{name: "InvalidName", policy: "rules:\n  - action: purge\n    conversations: {name: \"[\"}\n"},
+		{name: "DuplicateName", policy: "rules:\n  - name: a\n    action: keep\n  - name: a\n    action: purge\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParsePolicy([]byte(test.policy)); err == nil {
+				t.Fatal("expecting an error for an invalid policy")
+			}
+		})
+	}
+}
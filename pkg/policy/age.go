@@ -0,0 +1,48 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Age is a calendar age, such as "3y", "6m", "2w", or "10d".
+type Age struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+// ParseAge parses an age formatted as a number followed by a unit of y (years), m (months), w (weeks), or d (days).
+func ParseAge(s string) (Age, error) {
+	if len(s) < 2 {
+		return Age{}, fmt.Errorf("invalid age %q, expecting a number followed by y, m, w, or d", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return Age{}, fmt.Errorf("invalid age %q, expecting a number followed by y, m, w, or d", s)
+	}
+	switch s[len(s)-1] {
+	case 'y':
+		return Age{Years: n}, nil
+	case 'm':
+		return Age{Months: n}, nil
+	case 'w':
+		return Age{Days: n * 7}, nil
+	case 'd':
+		return Age{Days: n}, nil
+	}
+	return Age{}, fmt.Errorf("invalid age %q, expecting a number followed by y, m, w, or d", s)
+}
+
+// Before returns the time that is the age before t.
+func (a Age) Before(t time.Time) time.Time {
+	return t.AddDate(-a.Years, -a.Months, -a.Days)
+}
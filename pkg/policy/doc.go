@@ -0,0 +1,35 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package policy includes a retention policy engine for applying records schedules to Slack archives.
+//
+// A policy is a YAML file with an ordered list of rules, e.g.,
+//
+//	rules:
+//	  - name: purge-old-direct-messages
+//	    conversations:
+//	      types: [dm, mpim]
+//	    older_than: 3y
+//	    action: purge
+//	  - name: purge-expired-projects
+//	    conversations:
+//	      types: [channel]
+//	      name: ^proj-
+//	    older_than: 7y
+//	    action: purge
+//	  - name: keep-projects
+//	    conversations:
+//	      types: [channel]
+//	      name: ^proj-
+//	    action: keep
+//	  - name: drop-tombstone-metadata
+//	    action: drop_tombstone_metadata
+//
+// For each message, the first matching keep or purge rule decides whether the message is kept.
+// Messages that do not match any keep or purge rule are kept.
+// The drop_tombstone_metadata action applies to every matching message that is kept.
+package policy
@@ -0,0 +1,150 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package policy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+type Action string
+
+const (
+	ActionKeep                  Action = "keep"
+	ActionPurge                 Action = "purge"
+	ActionDropTombstoneMetadata Action = "drop_tombstone_metadata"
+)
+
+// Selector matches conversations by type, name, and team.  Empty fields match every conversation.
+type Selector struct {
+	Types []slack.ConversationType `yaml:"types"`
+	Name  string                   `yaml:"name"` // regular expression matched against the conversation name
+	Team  string                   `yaml:"team"` // regular expression matched against the team name
+	name  *regexp.Regexp
+	team  *regexp.Regexp
+}
+
+type Rule struct {
+	Name          string   `yaml:"name"`
+	Conversations Selector `yaml:"conversations"`
+	OlderThan     string   `yaml:"older_than"` // only match messages older than this age, e.g., "3y"
+	Action        Action   `yaml:"action"`
+	olderThan     *Age
+}
+
+type Policy struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+func (s *Selector) init() error {
+	for _, t := range s.Types {
+		switch t {
+		case slack.ConversationTypeChannel, slack.ConversationTypeGroup, slack.ConversationTypeDirectMessage, slack.ConversationTypeMultiPartyInstantMessage:
+		default:
+			return fmt.Errorf("invalid conversation type %q, expecting channel, group, dm, or mpim", t)
+		}
+	}
+	if len(s.Name) > 0 {
+		re, err := regexp.Compile(s.Name)
+		if err != nil {
+			return fmt.Errorf("error compiling name expression %q: %w", s.Name, err)
+		}
+		s.name = re
+	}
+	if len(s.Team) > 0 {
+		re, err := regexp.Compile(s.Team)
+		if err != nil {
+			return fmt.Errorf("error compiling team expression %q: %w", s.Team, err)
+		}
+		s.team = re
+	}
+	return nil
+}
+
+// Match returns true if the selector matches the conversation.
+func (s *Selector) Match(c *slack.Conversation) bool {
+	if len(s.Types) > 0 {
+		found := false
+		for _, t := range s.Types {
+			if t == c.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if s.name != nil && !s.name.MatchString(c.Name) {
+		return false
+	}
+	if s.team != nil && !s.team.MatchString(c.Team) {
+		return false
+	}
+	return true
+}
+
+func (r *Rule) init() error {
+	switch r.Action {
+	case ActionKeep, ActionPurge, ActionDropTombstoneMetadata:
+	default:
+		return fmt.Errorf("invalid action %q, expecting keep, purge, or drop_tombstone_metadata", r.Action)
+	}
+	if len(r.OlderThan) > 0 {
+		age, err := ParseAge(r.OlderThan)
+		if err != nil {
+			return err
+		}
+		r.olderThan = &age
+	}
+	return r.Conversations.init()
+}
+
+// ParsePolicy parses and validates a policy from YAML.
+func ParsePolicy(data []byte) (*Policy, error) {
+	p := &Policy{}
+	err := yaml.UnmarshalStrict(data, p)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling policy: %w", err)
+	}
+	if len(p.Rules) == 0 {
+		return nil, fmt.Errorf("policy has no rules")
+	}
+	names := map[string]struct{}{}
+	for i, r := range p.Rules {
+		if len(r.Name) == 0 {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+		if err = r.init(); err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", r.Name, err)
+		}
+	}
+	return p, nil
+}
+
+// LoadPolicy reads and parses a policy from a YAML file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy %q: %w", path, err)
+	}
+	p, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing policy %q: %w", path, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,42 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package policy
+
+import (
+	"time"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+type RuleReport struct {
+	Name          string `json:"name"`
+	Action        Action `json:"action"`
+	Messages      int    `json:"messages"`      // the number of messages the rule was applied to
+	Conversations int    `json:"conversations"` // the number of conversations the rule was applied to
+}
+
+type ConversationReport struct {
+	Type              slack.ConversationType `json:"type"`
+	ID                string                 `json:"id"`
+	Name              string                 `json:"name"`
+	Team              string                 `json:"team,omitempty"`
+	Messages          int                    `json:"messages"`
+	Purged            int                    `json:"purged"`
+	TombstonesDropped int                    `json:"tombstones_dropped"`
+}
+
+// Report summarizes the result of applying a policy.
+type Report struct {
+	AsOf              time.Time             `json:"as_of"`
+	DryRun            bool                  `json:"dry_run"`
+	Messages          int                   `json:"messages"`
+	Purged            int                   `json:"purged"`
+	TombstonesDropped int                   `json:"tombstones_dropped"`
+	Rules             []*RuleReport         `json:"rules"`
+	Conversations     []*ConversationReport `json:"conversations"`
+}
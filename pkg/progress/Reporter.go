@@ -0,0 +1,175 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clearLine returns the cursor to the start of the line and erases the line.
+const clearLine = "\r\033[K"
+
+// Reporter shows the files and bytes done out of the totals, the throughput, and the estimated time remaining
+// on a single line of a terminal, which is redrawn periodically until the reporter is stopped.
+// The methods of a nil reporter, other than Writer and String, do nothing, so callers do not need to check if progress is shown.
+type Reporter struct {
+	writer      io.Writer
+	totalFiles  int64
+	totalBytes  int64
+	files       atomic.Int64 // files done, including skipped files
+	bytes       atomic.Int64 // bytes done, including skipped files
+	transferred atomic.Int64 // bytes transferred since the reporter was started, used for the throughput
+	start       time.Time
+	mutex       sync.Mutex // guards writes to the writer
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewReporter returns a new reporter that writes to w, usually stderr, with the total number of files and bytes expected.
+func NewReporter(w io.Writer, totalFiles int, totalBytes int64) *Reporter {
+	return &Reporter{
+		writer:     w,
+		totalFiles: int64(totalFiles),
+		totalBytes: totalBytes,
+	}
+}
+
+// Start starts redrawing the progress at the interval.
+func (r *Reporter) Start(interval time.Duration) {
+	if r == nil {
+		return
+	}
+	r.start = time.Now()
+	r.done = make(chan struct{})
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.draw("")
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing the progress and leaves the final progress on its own line.
+// Stopping a reporter that is already stopped does nothing, so Stop can be deferred and also called once the work is done.
+func (r *Reporter) Stop() {
+	if r == nil || r.done == nil {
+		return
+	}
+	close(r.done)
+	r.wg.Wait()
+	r.done = nil
+	r.draw("\n")
+}
+
+// Reader returns a reader that adds the bytes read from rd to the progress.
+func (r *Reporter) Reader(rd io.Reader) io.Reader {
+	if r == nil {
+		return rd
+	}
+	return &reader{reader: rd, reporter: r}
+}
+
+// Done adds a file to the files done.  Its bytes are added as they are read.
+func (r *Reporter) Done() {
+	if r == nil {
+		return
+	}
+	r.files.Add(1)
+}
+
+// Skip adds a file that did not need to be transferred, e.g., since it already exists, to the files and bytes done.
+func (r *Reporter) Skip(size int64) {
+	if r == nil {
+		return
+	}
+	r.files.Add(1)
+	r.bytes.Add(size)
+}
+
+// Discard removes the bytes read for a file that failed or was aborted from the bytes done.
+func (r *Reporter) Discard(n int64) {
+	if r == nil {
+		return
+	}
+	r.bytes.Add(-n)
+}
+
+// Writer returns a writer that erases the progress before each write, e.g., for logs,
+// so the progress is redrawn below what was written.
+func (r *Reporter) Writer() io.Writer {
+	return &writer{reporter: r}
+}
+
+// draw erases the line and writes the progress followed by the suffix.
+func (r *Reporter) draw(suffix string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_, _ = fmt.Fprint(r.writer, clearLine+r.String()+suffix)
+}
+
+// String returns the progress, e.g., "3/10 files  1.5 MiB / 6.0 MiB (25%)  512.0 KiB/s  ETA 00:09".
+func (r *Reporter) String() string {
+	files, bytes := r.files.Load(), r.bytes.Load()
+	str := fmt.Sprintf("%d/%d files  %s / %s", files, r.totalFiles, FormatBytes(bytes), FormatBytes(r.totalBytes))
+	if r.totalBytes > 0 {
+		// the expected sizes can be smaller than the files transferred, so the percentage is capped
+		percent := bytes * 100 / r.totalBytes
+		if percent > 100 {
+			percent = 100
+		}
+		str += fmt.Sprintf(" (%d%%)", percent)
+	}
+	elapsed := time.Since(r.start)
+	if elapsed <= 0 {
+		return str
+	}
+	rate := float64(r.transferred.Load()) / elapsed.Seconds()
+	str += fmt.Sprintf("  %s/s", FormatBytes(int64(rate)))
+	if remaining := r.totalBytes - bytes; rate > 0 && remaining > 0 {
+		str += "  ETA " + formatDuration(time.Duration(float64(remaining)/rate*float64(time.Second)))
+	}
+	return str
+}
+
+type reader struct {
+	reader   io.Reader
+	reporter *Reporter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.reporter.bytes.Add(int64(n))
+	r.reporter.transferred.Add(int64(n))
+	return n, err
+}
+
+type writer struct {
+	reporter *Reporter
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.reporter.mutex.Lock()
+	defer w.reporter.mutex.Unlock()
+	_, err := fmt.Fprint(w.reporter.writer, clearLine)
+	if err != nil {
+		return 0, err
+	}
+	return w.reporter.writer.Write(p)
+}
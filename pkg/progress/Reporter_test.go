@@ -0,0 +1,106 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package progress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	for _, test := range []struct {
+		n        int64
+		expected string
+	}{
+		{n: 0, expected: "0 B"},
+		{n: 1023, expected: "1023 B"},
+		{n: 1024, expected: "1.0 KiB"},
+		{n: 1536 * 1024, expected: "1.5 MiB"},
+		{n: 3 << 40, expected: "3.0 TiB"},
+	} {
+		if got := FormatBytes(test.n); got != test.expected {
+			t.Errorf("expecting %d bytes to be formatted as %q, but found %q", test.n, test.expected, got)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for _, test := range []struct {
+		d        time.Duration
+		expected string
+	}{
+		{d: 0, expected: "00:00"},
+		{d: 9*time.Second + 400*time.Millisecond, expected: "00:09"},
+		{d: 2*time.Minute + 3*time.Second, expected: "02:03"},
+		{d: time.Hour + 2*time.Minute + 3*time.Second, expected: "1:02:03"},
+	} {
+		if got := formatDuration(test.d); got != test.expected {
+			t.Errorf("expecting %s to be formatted as %q, but found %q", test.d, test.expected, got)
+		}
+	}
+}
+
+func TestReporter(t *testing.T) {
+	out := &bytes.Buffer{}
+	r := NewReporter(out, 4, 4096)
+	r.Start(time.Hour)
+
+	// a file that is transferred
+	if _, err := io.Copy(io.Discard, r.Reader(strings.NewReader(strings.Repeat("a", 1024)))); err != nil {
+		t.Fatal(err)
+	}
+	r.Done()
+	// a file that already exists
+	r.Skip(1024)
+	// a file that failed part way through
+	if _, err := io.Copy(io.Discard, r.Reader(strings.NewReader(strings.Repeat("b", 512)))); err != nil {
+		t.Fatal(err)
+	}
+	r.Discard(512)
+
+	if got := r.String(); !strings.HasPrefix(got, "2/4 files  2.0 KiB / 4.0 KiB (50%)") || !strings.Contains(got, "ETA") {
+		t.Fatalf("expecting 2 of 4 files and 50%% done with an ETA, but found %q", got)
+	}
+
+	if _, err := io.WriteString(r.Writer(), "log\n"); err != nil {
+		t.Fatal(err)
+	}
+	r.Stop()
+	r.Stop()
+	if got := out.String(); !strings.HasPrefix(got, clearLine+"log\n"+clearLine+"2/4 files") || !strings.HasSuffix(got, "\n") {
+		t.Fatalf("expecting the log followed by the final progress on its own line, but found %q", got)
+	}
+	if n := strings.Count(out.String(), "files"); n != 1 {
+		t.Fatalf("expecting the final progress to be drawn once, but found %d", n)
+	}
+}
+
+// TestReporterPercent checks that the percentage is capped when more bytes are done than expected.
+func TestReporterPercent(t *testing.T) {
+	r := NewReporter(io.Discard, 1, 100)
+	r.Skip(200)
+	if got := r.String(); !strings.HasPrefix(got, "1/1 files  200 B / 100 B (100%)") {
+		t.Fatalf("expecting the percentage to be capped at 100%%, but found %q", got)
+	}
+}
+
+func TestReporterNil(t *testing.T) {
+	var r *Reporter
+	r.Start(time.Millisecond)
+	r.Done()
+	r.Skip(1)
+	r.Discard(1)
+	r.Stop()
+	rd := strings.NewReader("hello")
+	if got := r.Reader(rd); got != rd {
+		t.Fatal("expecting the reader to be returned as is by a nil reporter")
+	}
+}
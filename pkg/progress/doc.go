@@ -0,0 +1,9 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package progress includes a reporter that shows the progress of long runs, such as downloading files, on a terminal.
+package progress
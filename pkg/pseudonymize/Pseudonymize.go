@@ -0,0 +1,531 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package pseudonymize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deptofdefense/slack-archiver/pkg/jsonutil"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+var mention = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+var (
+	// profileFields are the fields kept in the profiles of users.  Every other field is removed.
+	profileFields = map[string]struct{}{
+		"display_name":            {},
+		"display_name_normalized": {},
+		"first_name":              {},
+		"guest_invited_by":        {},
+		"last_name":               {},
+		"real_name":               {},
+		"real_name_normalized":    {},
+		"team":                    {},
+	}
+	// messageProfileFields are the fields kept in the profiles of message authors.  Every other field is removed.
+	messageProfileFields = map[string]struct{}{
+		"display_name":        {},
+		"first_name":          {},
+		"is_restricted":       {},
+		"is_ultra_restricted": {},
+		"name":                {},
+		"real_name":           {},
+		"team":                {},
+	}
+	// messageUserFields are the fields of messages with the id of a user.
+	messageUserFields = []string{"user", "inviter", "parent_user_id"}
+	// attachmentTextFields are the fields of message attachments that can mention users.
+	attachmentTextFields = []string{"pretext", "title", "text", "fallback", "footer"}
+)
+
+type pseudonymizer struct {
+	mapper    *Mapper
+	usernames map[string]string // the ids of users by name
+	// identities are the real ids and emails of the users in the archive,
+	// which must not be left anywhere in a pseudonymized user or message.
+	identities *identityIndex
+}
+
+// identityIndex finds the real ids and emails of users within strings.
+// Identities are indexed by their first bytes, up to the length of the shortest identity,
+// so a string is searched in a single pass rather than once for each identity.
+type identityIndex struct {
+	size       int
+	identities map[string][]string
+}
+
+func newIdentityIndex(identities []string) *identityIndex {
+	x := &identityIndex{identities: map[string][]string{}}
+	for _, identity := range identities {
+		if x.size == 0 || len(identity) < x.size {
+			x.size = len(identity)
+		}
+	}
+	indexed := map[string]struct{}{}
+	for _, identity := range identities {
+		if _, ok := indexed[identity]; ok {
+			continue
+		}
+		indexed[identity] = struct{}{}
+		prefix := identity[:x.size]
+		x.identities[prefix] = append(x.identities[prefix], identity)
+	}
+	return x
+}
+
+// contains returns true if s contains the real id or email of a user.
+func (x *identityIndex) contains(s string) bool {
+	if x.size == 0 {
+		return false
+	}
+	for i := 0; i+x.size <= len(s); i++ {
+		for _, identity := range x.identities[s[i:i+x.size]] {
+			if strings.HasPrefix(s[i:], identity) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Pseudonymize writes a copy of the enterprise grid to w with every user replaced by their pseudonym.
+// Pseudonyms are applied to user records, conversation members, creators, and pins, message authors, inviters, replies,
+// reactions, edits, files, huddles, attachment authors, and the mentions in message, block, and attachment text.
+// The profiles of users and message authors are reduced to their pseudonymous names, and the public links to files are removed.
+// Records are changed in their original JSON, so other fields are written as is, but a user or message
+// that still holds the real id or email of a user anywhere in a string is rejected with an error.
+// Pseudonymizing stops with the error of the context if it is canceled.
+func Pseudonymize(ctx context.Context, e *slack.EnterpriseGrid, w *slack.ArchiveWriter, m *Mapper) error {
+	p := &pseudonymizer{
+		mapper:    m,
+		usernames: map[string]string{},
+	}
+
+	users := append([]*slack.User{}, e.OrganizationUsers...)
+	for _, t := range e.GetTeams() {
+		users = append(users, t.Users...)
+	}
+	ids := make([]string, 0, len(users))
+	identities := make([]string, 0, len(users))
+	for _, u := range users {
+		if _, ok := p.usernames[u.Name]; !ok {
+			p.usernames[u.Name] = u.ID
+		}
+		if len(u.ID) > 0 {
+			ids = append(ids, u.ID)
+			identities = append(identities, u.ID)
+		}
+		if u.Profile != nil && len(u.Profile.Email) > 0 {
+			identities = append(identities, u.Profile.Email)
+		}
+	}
+	p.identities = newIdentityIndex(identities)
+
+	// Map the users in the order of their ids, so the user who keeps a pseudonym that collides does not depend on the order of the records.
+	sort.Strings(ids)
+	for _, id := range ids {
+		m.Get(id)
+	}
+
+	for _, f := range e.GetGridFiles() {
+		file := f
+		err := w.CopyFile(e, f.Name, func(records []json.RawMessage) ([]json.RawMessage, error) {
+			return p.records(file, records)
+		})
+		if err != nil {
+			return fmt.Errorf("error writing pseudonymized enterprise grid: %w", err)
+		}
+	}
+
+	for _, c := range e.GetConversations() {
+		dst := *c
+		if c.Type == slack.ConversationTypeMultiPartyInstantMessage {
+			dst.Name = p.mpimName(c.Members)
+		}
+		err := w.CopyRawConversation(ctx, e, c, &dst, func(day string, messages []json.RawMessage) ([]json.RawMessage, error) {
+			return p.messages(messages)
+		})
+		if err != nil {
+			return fmt.Errorf("error pseudonymizing %s %q: %w", c.Type, c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// findIdentity returns the path to the first string in v that contains the real id or email of a user.
+func (p *pseudonymizer) findIdentity(v interface{}, path string) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return path, p.identities.contains(v)
+	case []interface{}:
+		for i, value := range v {
+			if found, ok := p.findIdentity(value, fmt.Sprintf("%s[%d]", path, i)); ok {
+				return found, true
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if found, ok := p.findIdentity(v[k], strings.TrimPrefix(path+"."+k, ".")); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
+
+// checkIdentities returns an error if the pseudonymized record still holds the real id or email of a user.
+func (p *pseudonymizer) checkIdentities(record json.RawMessage) error {
+	var v interface{}
+	if err := json.Unmarshal(record, &v); err != nil {
+		return fmt.Errorf("error unmarshaling record: %w", err)
+	}
+	if path, ok := p.findIdentity(v, ""); ok {
+		return fmt.Errorf("field %q holds the real id or email of a user and is not pseudonymized", path)
+	}
+	return nil
+}
+
+func (p *pseudonymizer) text(text string) string {
+	return mention.ReplaceAllStringFunc(text, func(match string) string {
+		return fmt.Sprintf("<@%s>", p.mapper.ID(mention.FindStringSubmatch(match)[1]))
+	})
+}
+
+// mpimName returns the name of a multiparty instant message, which includes the usernames of the members.
+func (p *pseudonymizer) mpimName(members []string) string {
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		names = append(names, p.mapper.Get(member).PseudonymName)
+	}
+	return fmt.Sprintf("mpdm-%s-1", strings.Join(names, "--"))
+}
+
+// records pseudonymizes the users, conversations, or integration logs in a file of the enterprise grid.
+func (p *pseudonymizer) records(f *slack.GridFile, records []json.RawMessage) ([]json.RawMessage, error) {
+	pseudonymized := make([]json.RawMessage, 0, len(records))
+	for _, record := range records {
+		obj, err := jsonutil.ParseObject(record)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing record in %q: %w", f.Name, err)
+		}
+		switch f.Kind {
+		case slack.GridFileKindUsers:
+			err = p.user(obj)
+		case slack.GridFileKindIntegrationLogs:
+			err = p.integrationLog(obj)
+		default:
+			err = p.conversation(f.Kind, obj)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error pseudonymizing record in %q: %w", f.Name, err)
+		}
+		pseudonymized = append(pseudonymized, obj.Bytes())
+	}
+	return pseudonymized, nil
+}
+
+func (p *pseudonymizer) integrationLog(l *jsonutil.Object) error {
+	err := l.UpdateString("user_name", func(name string) string {
+		if id, ok := p.usernames[name]; ok {
+			return p.mapper.Get(id).PseudonymName
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+	return l.UpdateString("user_id", p.mapper.ID)
+}
+
+// describedBy pseudonymizes the creator and the mentions in a topic or purpose.
+func (p *pseudonymizer) describedBy(t *jsonutil.Object) error {
+	if err := t.UpdateString("creator", p.mapper.ID); err != nil {
+		return err
+	}
+	return t.UpdateString("value", p.text)
+}
+
+func (p *pseudonymizer) conversation(kind slack.GridFileKind, c *jsonutil.Object) error {
+	if kind == slack.GridFileKindMultiPartyInstantMessages {
+		members := make([]string, 0)
+		if value, ok := c.Get("members"); ok && !jsonutil.IsNull(value) {
+			if err := json.Unmarshal(value, &members); err != nil {
+				return fmt.Errorf("error unmarshaling members: %w", err)
+			}
+		}
+		if err := c.SetValue("name", p.mpimName(members)); err != nil {
+			return err
+		}
+	}
+	if err := c.UpdateString("creator", p.mapper.ID); err != nil {
+		return err
+	}
+	if err := c.UpdateStrings("members", p.mapper.IDs); err != nil {
+		return err
+	}
+	if err := c.UpdateObject("topic", p.describedBy); err != nil {
+		return err
+	}
+	if err := c.UpdateObject("purpose", p.describedBy); err != nil {
+		return err
+	}
+	return c.UpdateObjects("pins", func(pin *jsonutil.Object) error {
+		if err := pin.UpdateString("user", p.mapper.ID); err != nil {
+			return err
+		}
+		return pin.UpdateString("owner", p.mapper.ID)
+	})
+}
+
+// reduceProfile removes the fields of the profile that are not kept and sets the names to the pseudonyms.
+func reduceProfile(profile *jsonutil.Object, kept map[string]struct{}, names map[string]string) error {
+	for _, key := range profile.Keys() {
+		if _, ok := kept[key]; !ok {
+			profile.Delete(key)
+		}
+	}
+	keys := make([]string, 0, len(names))
+	for key := range names {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := profile.SetValue(key, names[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pseudonymizer) user(u *jsonutil.Object) error {
+	id, _ := u.GetString("id")
+	mapping := p.mapper.Get(id)
+	if len(mapping.Name) == 0 {
+		mapping.Name, _ = u.GetString("name")
+	}
+	values := []struct {
+		key   string
+		value string
+	}{
+		{key: "id", value: mapping.PseudonymID},
+		{key: "name", value: mapping.PseudonymName},
+		{key: "real_name", value: mapping.PseudonymRealName},
+	}
+	for _, v := range values {
+		value := v.value
+		err := u.UpdateString(v.key, func(string) string {
+			return value
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := u.UpdateObject("enterprise_user", func(eu *jsonutil.Object) error {
+		return eu.UpdateString("id", p.mapper.ID)
+	})
+	if err != nil {
+		return err
+	}
+	err = u.UpdateObject("profile", func(profile *jsonutil.Object) error {
+		return p.profile(mapping, profile)
+	})
+	if err != nil {
+		return err
+	}
+	return p.checkIdentities(u.Bytes())
+}
+
+func (p *pseudonymizer) profile(mapping *Mapping, profile *jsonutil.Object) error {
+	err := reduceProfile(profile, profileFields, map[string]string{
+		"display_name":            mapping.PseudonymName,
+		"display_name_normalized": mapping.PseudonymName,
+		"first_name":              mapping.firstName,
+		"last_name":               mapping.lastName,
+		"real_name":               mapping.PseudonymRealName,
+		"real_name_normalized":    mapping.PseudonymRealName,
+	})
+	if err != nil {
+		return err
+	}
+	return profile.UpdateString("guest_invited_by", p.mapper.ID)
+}
+
+func (p *pseudonymizer) messages(messages []json.RawMessage) ([]json.RawMessage, error) {
+	pseudonymized := make([]json.RawMessage, 0, len(messages))
+	for _, data := range messages {
+		m, err := jsonutil.ParseObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing message: %w", err)
+		}
+		ts, _ := m.GetString("ts")
+		if err = p.message(m); err != nil {
+			return nil, fmt.Errorf("error pseudonymizing message %q: %w", ts, err)
+		}
+		if err = p.checkIdentities(m.Bytes()); err != nil {
+			return nil, fmt.Errorf("error pseudonymizing message %q: %w", ts, err)
+		}
+		pseudonymized = append(pseudonymized, m.Bytes())
+	}
+	return pseudonymized, nil
+}
+
+// blocks pseudonymizes the users and mentions in the blocks of a message.
+func (p *pseudonymizer) blocks(m *jsonutil.Object) error {
+	return slack.WalkRawBlocks(m, func(e *jsonutil.Object) error {
+		if err := e.UpdateString("user_id", p.mapper.ID); err != nil {
+			return err
+		}
+		return slack.TransformRawBlockText(e, p.text)
+	})
+}
+
+func (p *pseudonymizer) message(m *jsonutil.Object) error {
+	user, _ := m.GetString("user")
+	if len(user) > 0 {
+		mapping := p.mapper.Get(user)
+		err := m.UpdateObject("user_profile", func(profile *jsonutil.Object) error {
+			return reduceProfile(profile, messageProfileFields, map[string]string{
+				"display_name": mapping.PseudonymName,
+				"name":         mapping.PseudonymName,
+				"first_name":   mapping.firstName,
+				"real_name":    mapping.PseudonymRealName,
+			})
+		})
+		if err != nil {
+			return err
+		}
+		// The username of a message from a user is the name of the user, but the username of a bot message is the name of the bot.
+		err = m.UpdateString("username", func(string) string {
+			return mapping.PseudonymName
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		m.Delete("user_profile")
+	}
+	for _, key := range messageUserFields {
+		if err := m.UpdateString(key, p.mapper.ID); err != nil {
+			return err
+		}
+	}
+	if err := m.UpdateStrings("reply_users", p.mapper.IDs); err != nil {
+		return err
+	}
+	err := m.UpdateObjects("replies", func(r *jsonutil.Object) error {
+		return r.UpdateString("user", p.mapper.ID)
+	})
+	if err != nil {
+		return err
+	}
+	err = m.UpdateObjects("reactions", func(r *jsonutil.Object) error {
+		return r.UpdateStrings("users", p.mapper.IDs)
+	})
+	if err != nil {
+		return err
+	}
+	err = m.UpdateObjects("files", p.file)
+	if err != nil {
+		return err
+	}
+	err = m.UpdateObject("edited", func(e *jsonutil.Object) error {
+		return e.UpdateString("user", p.mapper.ID)
+	})
+	if err != nil {
+		return err
+	}
+	err = m.UpdateObject("room", p.room)
+	if err != nil {
+		return err
+	}
+	err = m.UpdateObjects("attachments", p.attachment)
+	if err != nil {
+		return err
+	}
+	if err = m.UpdateString("text", p.text); err != nil {
+		return err
+	}
+	return p.blocks(m)
+}
+
+// file pseudonymizes the uploader of a file.  The permalink of a file includes the id of the uploader, which is replaced,
+// and the public permalink is removed, since anyone with it can download the file.
+func (p *pseudonymizer) file(f *jsonutil.Object) error {
+	f.Delete("permalink_public")
+	user, _ := f.GetString("user")
+	if len(user) == 0 {
+		return nil
+	}
+	pseudonym := p.mapper.ID(user)
+	err := f.UpdateString("permalink", func(permalink string) string {
+		return strings.ReplaceAll(permalink, "/"+user+"/", "/"+pseudonym+"/")
+	})
+	if err != nil {
+		return err
+	}
+	return f.SetValue("user", pseudonym)
+}
+
+// room pseudonymizes the creator and participants of a huddle.
+func (p *pseudonymizer) room(r *jsonutil.Object) error {
+	if err := r.UpdateString("created_by", p.mapper.ID); err != nil {
+		return err
+	}
+	if err := r.UpdateStrings("participants", p.mapper.IDs); err != nil {
+		return err
+	}
+	return r.UpdateStrings("participant_history", p.mapper.IDs)
+}
+
+// attachment pseudonymizes the author of an attachment, e.g., a shared message, along with the mentions in its text and blocks.
+// The links to the author and their avatar are removed, as are the names of authors without an id.
+func (p *pseudonymizer) attachment(a *jsonutil.Object) error {
+	a.Delete("author_link")
+	a.Delete("author_icon")
+	author, _ := a.GetString("author_id")
+	if len(author) == 0 {
+		a.Delete("author_name")
+		a.Delete("author_subname")
+	} else {
+		mapping := p.mapper.Get(author)
+		values := map[string]string{
+			"author_id":      mapping.PseudonymID,
+			"author_name":    mapping.PseudonymRealName,
+			"author_subname": mapping.PseudonymName,
+		}
+		for _, key := range []string{"author_id", "author_name", "author_subname"} {
+			value := values[key]
+			err := a.UpdateString(key, func(string) string {
+				return value
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range attachmentTextFields {
+		if err := a.UpdateString(key, p.text); err != nil {
+			return err
+		}
+	}
+	return a.UpdateObjects("message_blocks", func(b *jsonutil.Object) error {
+		return b.UpdateObject("message", p.blocks)
+	})
+}
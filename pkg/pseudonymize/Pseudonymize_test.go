@@ -0,0 +1,192 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package pseudonymize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestGrid returns an enterprise grid with alice (U012AB3CD) and bob (W045EF6GH), where the messages in the general channel are given.
+func newTestGrid(t *testing.T, messages string) *slack.EnterpriseGrid {
+	return slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json": `[
+			{"id": "U012AB3CD", "name": "alice", "real_name": "Alice Smith", "profile": {"email": "alice@example.com", "real_name": "Alice Smith", "image_72": "https://example.com/U012AB3CD.png"}},
+			{"id": "W045EF6GH", "name": "bob", "real_name": "Bob Jones", "profile": {"email": "bob@example.com", "guest_invited_by": "U012AB3CD"}}
+		]`,
+		"dms.json":                            `[{"id": "D1", "members": ["U012AB3CD", "W045EF6GH"]}]`,
+		"teams/hello/channels.json":           `[{"id": "C1", "name": "general", "creator": "U012AB3CD", "members": ["U012AB3CD", "W045EF6GH"]}]`,
+		"teams/hello/users.json":              `[{"id": "U012AB3CD", "name": "alice"}]`,
+		"teams/hello/general/2020-07-29.json": messages,
+	})
+}
+
+// pseudonymizeTestGrid pseudonymizes the test enterprise grid and returns the contents of the files in the pseudonymized archive.
+func pseudonymizeTestGrid(t *testing.T, messages string) (map[string]string, error) {
+	t.Helper()
+	return slacktest.WriteArchive(t, func(w *slack.ArchiveWriter) error {
+		return Pseudonymize(context.Background(), newTestGrid(t, messages), w, NewMapper([]byte("key")))
+	})
+}
+
+// TestPseudonymizeLeaks checks that no real id, email, or name of a user is left anywhere in the pseudonymized archive.
+func TestPseudonymizeLeaks(t *testing.T) {
+	leaks := []string{"U012AB3CD", "W045EF6GH", "alice", "Alice", "bob@example.com", "Jones"}
+	for _, test := range []struct {
+		name     string
+		messages string
+	}{
+		{
+			name: "Message",
+			messages: `[{
+				"type": "message", "user": "U012AB3CD", "ts": "1596060000.000100",
+				"text": "hi <@W045EF6GH|bob>",
+				"user_profile": {"name": "alice", "real_name": "Alice Smith", "image_72": "https://example.com/U012AB3CD.png"},
+				"reply_users": ["W045EF6GH"],
+				"replies": [{"user": "W045EF6GH", "ts": "1596060001.000100"}],
+				"reactions": [{"name": "+1", "users": ["W045EF6GH"], "count": 1}],
+				"edited": {"user": "U012AB3CD", "ts": "1596060002.000000"}
+			}]`,
+		},
+		{
+			name: "Blocks",
+			messages: `[{
+				"type": "message", "user": "U012AB3CD", "ts": "1596060000.000100",
+				"blocks": [
+					{"type": "section", "text": {"type": "mrkdwn", "text": "hi <@W045EF6GH>"}, "fields": [{"type": "mrkdwn", "text": "cc <@U012AB3CD>"}]},
+					{"type": "rich_text", "elements": [{"type": "rich_text_section", "elements": [{"type": "user", "user_id": "W045EF6GH"}]}]}
+				]
+			}]`,
+		},
+		{
+			name: "File",
+			messages: `[{
+				"type": "message", "user": "U012AB3CD", "ts": "1596060000.000100",
+				"files": [{
+					"id": "F1",
+					"user": "U012AB3CD",
+					"permalink": "https://hello.slack.com/files/U012AB3CD/F1/notes.txt",
+					"permalink_public": "https://slack-files.com/T1-F1-abc123"
+				}]
+			}]`,
+		},
+		{
+			name: "Attachment",
+			messages: `[{
+				"type": "message", "user": "U012AB3CD", "ts": "1596060000.000100",
+				"attachments": [{
+					"author_id": "W045EF6GH", "author_name": "Bob Jones", "author_link": "https://hello.slack.com/team/W045EF6GH",
+					"text": "from <@W045EF6GH>"
+				}]
+			}]`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := pseudonymizeTestGrid(t, test.messages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, data := range files {
+				for _, leak := range leaks {
+					if strings.Contains(data, leak) {
+						t.Errorf("expecting %q to be pseudonymized in %q, but found %s", leak, name, data)
+					}
+				}
+				if strings.Contains(data, "permalink_public") {
+					t.Errorf("expecting the public permalink to be removed from %q, but found %s", name, data)
+				}
+			}
+		})
+	}
+}
+
+// TestPseudonymizeRejected checks that messages holding a real id or email in a field that is not pseudonymized are rejected,
+// including ids and emails within longer strings.
+func TestPseudonymizeRejected(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		messages string
+	}{
+		{
+			name:     "Email",
+			messages: `[{"type": "message", "user": "U012AB3CD", "ts": "1596060000.000100", "text": "mail alice@example.com"}]`,
+		},
+		{
+			name:     "Link",
+			messages: `[{"type": "message", "user": "U012AB3CD", "ts": "1596060000.000100", "text": "see https://hello.slack.com/team/W045EF6GH"}]`,
+		},
+		{
+			name:     "Field",
+			messages: `[{"type": "message", "user": "U012AB3CD", "ts": "1596060000.000100", "metadata": {"event_payload": {"owner": "W045EF6GH"}}}]`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := pseudonymizeTestGrid(t, test.messages); err == nil {
+				t.Fatal("expecting an error for a message with a real id or email")
+			}
+		})
+	}
+}
+
+func TestIdentityIndex(t *testing.T) {
+	x := newIdentityIndex([]string{"U012AB3CD", "W045EF6GH", "alice@example.com", "U012AB3CD"})
+	for _, test := range []struct {
+		value    string
+		expected bool
+	}{
+		{value: "", expected: false},
+		{value: "U012AB3C", expected: false},
+		{value: "U012AB3CD", expected: true},
+		{value: "/files/U012AB3CD/F1", expected: true},
+		{value: "mailto:alice@example.com", expected: true},
+		{value: "bob@example.com", expected: false},
+		{value: "xxW045EF6GH", expected: true},
+	} {
+		if got := x.contains(test.value); got != test.expected {
+			t.Errorf("expecting %t for %q, but found %t", test.expected, test.value, got)
+		}
+	}
+}
+
+// TestMapperCollisions maps user ids until the pseudonym name of one collides with another,
+// and checks that the pseudonyms stay unique and stable.
+func TestMapperCollisions(t *testing.T) {
+	m := NewMapper([]byte("key"))
+	names := map[string]string{}
+	collision := ""
+	for i := 0; i < 100000 && len(collision) == 0; i++ {
+		id := fmt.Sprintf("U%d", i)
+		mapping := m.Get(id)
+		if other, ok := names[mapping.PseudonymName]; ok {
+			t.Fatalf("expecting unique pseudonym names, but found %q for %s and %s", mapping.PseudonymName, other, id)
+		}
+		names[mapping.PseudonymName] = id
+		if parts := strings.Split(mapping.PseudonymName, "-"); len(parts[len(parts)-1]) > 4 {
+			collision = id
+		}
+	}
+	if len(collision) == 0 {
+		t.Fatal("expecting a pseudonym name to collide")
+	}
+	if got := m.Get(collision).PseudonymName; names[got] != collision {
+		t.Fatalf("expecting the pseudonym name of %s to be stable, but found %q", collision, got)
+	}
+	mapping := m.Get(collision)
+	if number := mapping.PseudonymName[strings.LastIndex(mapping.PseudonymName, "-")+1:]; !strings.HasSuffix(mapping.PseudonymRealName, " "+number) {
+		t.Fatalf("expecting the real name to end in %s, but found %q", number, mapping.PseudonymRealName)
+	}
+	// Alone, the user id gets the shorter pseudonym name.
+	if got := NewMapper([]byte("key")).Get(collision).PseudonymName; got == mapping.PseudonymName {
+		t.Fatalf("expecting %s to get a shorter pseudonym name without a collision, but found %q", collision, got)
+	}
+}
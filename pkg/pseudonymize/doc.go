@@ -0,0 +1,9 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package pseudonymize includes tools for consistently replacing the users in a Slack archive with pseudonyms.
+package pseudonymize
@@ -0,0 +1,144 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package pseudonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Mapping is the pseudonym for a user.
+type Mapping struct {
+	ID                string `json:"id"`
+	Name              string `json:"name,omitempty"`
+	PseudonymID       string `json:"pseudonym_id"`
+	PseudonymName     string `json:"pseudonym_name"`
+	PseudonymRealName string `json:"pseudonym_real_name"`
+	firstName         string
+	lastName          string
+}
+
+// Mapper derives stable pseudonyms for user ids using a keyed HMAC.
+// The same key always produces the same pseudonyms.
+// The names have about 10 million values, so a pseudonym that is already taken by another user id
+// is derived again from more bits of the HMAC, and the first user id to get a pseudonym keeps it.
+type Mapper struct {
+	key      []byte
+	mappings map[string]*Mapping
+	ids      map[string]string // the user ids by pseudonym id
+	names    map[string]string // the user ids by pseudonym name
+}
+
+func NewMapper(key []byte) *Mapper {
+	return &Mapper{
+		key:      key,
+		mappings: map[string]*Mapping{},
+		ids:      map[string]string{},
+		names:    map[string]string{},
+	}
+}
+
+func (m *Mapper) sum(id string) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	_, _ = mac.Write([]byte(id))
+	return mac.Sum(nil)
+}
+
+// Get returns the mapping for the user id, creating it if necessary.
+func (m *Mapper) Get(id string) *Mapping {
+	if mapping, ok := m.mappings[id]; ok {
+		return mapping
+	}
+	sum := m.sum(id)
+	// Keep the first character, e.g., "U" or "W", so pseudonyms look like user ids.
+	prefix := "U"
+	if len(id) > 0 {
+		prefix = id[:1]
+	}
+	adjective := adjectives[int(sum[0])%len(adjectives)]
+	animal := animals[int(sum[1])%len(animals)]
+	encoded := base32.StdEncoding.EncodeToString(sum[4:])
+	pseudonymID := prefix + encoded[:10]
+	for i := 12; taken(m.ids, pseudonymID, id); i += 2 {
+		pseudonymID = prefix + encoded[:i]
+	}
+	number := fmt.Sprintf("%d", binary.BigEndian.Uint16(sum[2:4])%10000)
+	for digits := 6; taken(m.names, fmt.Sprintf("%s-%s-%s", adjective, animal, number), id); digits += 2 {
+		// Longer numbers are padded with zeros, so they never equal the shorter numbers.
+		number = fmt.Sprintf("%0*d", digits, binary.BigEndian.Uint64(sum[2:10])%pow10(digits))
+	}
+	firstName := capitalize(adjective)
+	lastName := capitalize(animal) + " " + number
+	mapping := &Mapping{
+		ID:                id,
+		PseudonymID:       pseudonymID,
+		PseudonymName:     fmt.Sprintf("%s-%s-%s", adjective, animal, number),
+		PseudonymRealName: firstName + " " + lastName,
+		firstName:         firstName,
+		lastName:          lastName,
+	}
+	m.mappings[id] = mapping
+	m.ids[mapping.PseudonymID] = id
+	m.names[mapping.PseudonymName] = id
+	return mapping
+}
+
+// taken returns true if the pseudonym is used by another user id.
+func taken(pseudonyms map[string]string, pseudonym string, id string) bool {
+	other, ok := pseudonyms[pseudonym]
+	return ok && other != id
+}
+
+func pow10(n int) uint64 {
+	p := uint64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// ID returns the pseudonym id for the user id.  Empty ids are returned as is.
+func (m *Mapper) ID(id string) string {
+	if len(id) == 0 {
+		return id
+	}
+	return m.Get(id).PseudonymID
+}
+
+// IDs returns the pseudonym ids for the user ids.
+func (m *Mapper) IDs(ids []string) []string {
+	if ids == nil {
+		return nil
+	}
+	pseudonyms := make([]string, 0, len(ids))
+	for _, id := range ids {
+		pseudonyms = append(pseudonyms, m.ID(id))
+	}
+	return pseudonyms
+}
+
+// Mappings returns all the mappings created so far sorted by user id.
+func (m *Mapper) Mappings() []*Mapping {
+	mappings := make([]*Mapping, 0, len(m.mappings))
+	for _, mapping := range m.mappings {
+		mappings = append(mappings, mapping)
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].ID < mappings[j].ID
+	})
+	return mappings
+}
@@ -0,0 +1,85 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package pseudonymize
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	mappingKeyContext = "slack-archiver pseudonymize mapping"
+)
+
+func newMappingCipher(key []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(mappingKeyContext))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// WriteMappings encrypts the mappings with AES-GCM using a key derived from the secret key and writes them to w.
+// Only holders of the secret key can read the mappings to reverse the pseudonyms.
+func WriteMappings(w io.Writer, key []byte, mappings []*Mapping) error {
+	aead, err := newMappingCipher(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(mappings)
+	if err != nil {
+		return fmt.Errorf("error marshaling mappings: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return fmt.Errorf("error generating nonce: %w", err)
+	}
+	_, err = w.Write(aead.Seal(nonce, nonce, plaintext, nil))
+	if err != nil {
+		return fmt.Errorf("error writing mappings: %w", err)
+	}
+	return nil
+}
+
+// ReadMappings reads and decrypts the mappings written by WriteMappings.
+func ReadMappings(r io.Reader, key []byte) ([]*Mapping, error) {
+	aead, err := newMappingCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading mappings: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("error decrypting mappings: data is too short")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting mappings, the key may be incorrect: %w", err)
+	}
+	mappings := make([]*Mapping, 0)
+	err = json.Unmarshal(plaintext, &mappings)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling mappings: %w", err)
+	}
+	return mappings, nil
+}
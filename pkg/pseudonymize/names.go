@@ -0,0 +1,22 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package pseudonymize
+
+var adjectives = []string{
+	"amber", "bold", "brave", "bright", "calm", "clever", "crisp", "daring",
+	"eager", "fancy", "gentle", "golden", "happy", "honest", "jolly", "keen",
+	"kind", "lively", "lucky", "merry", "mighty", "noble", "proud", "quick",
+	"quiet", "rapid", "silver", "steady", "swift", "tidy", "vivid", "witty",
+}
+
+var animals = []string{
+	"badger", "beaver", "bison", "cougar", "crane", "dolphin", "eagle", "falcon",
+	"ferret", "gecko", "heron", "ibis", "jaguar", "koala", "lemur", "lynx",
+	"marten", "moose", "narwhal", "ocelot", "otter", "owl", "panda", "puffin",
+	"quail", "raven", "salmon", "seal", "tapir", "walrus", "wombat", "yak",
+}
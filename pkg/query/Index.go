@@ -0,0 +1,57 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package query
+
+import (
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// Index finds the user and team of the records in an enterprise grid.
+type Index struct {
+	users map[string]*slack.User
+	teams map[string]*slack.Team
+}
+
+// NewIndex returns an index of the users and teams of the enterprise grid.
+func NewIndex(e *slack.EnterpriseGrid) *Index {
+	index := &Index{
+		users: map[string]*slack.User{},
+		teams: map[string]*slack.Team{},
+	}
+	for _, t := range e.GetTeams() {
+		index.teams[t.Name] = t
+		for _, u := range t.Users {
+			index.users[u.ID] = u
+		}
+	}
+	// the organization users take precedence over the users of a team
+	for _, u := range e.OrganizationUsers {
+		index.users[u.ID] = u
+	}
+	return index
+}
+
+// Env returns the records for the message and file in the conversation.
+// The user is the user who sent the message, or who uploaded the file if the message is nil.  The message and file can be nil.
+func (i *Index) Env(c *slack.Conversation, m *slack.Message, f *slack.MessageFile) *Env {
+	e := &Env{
+		Message: m,
+		File:    f,
+		Channel: c,
+	}
+	if c != nil && c.IsTeamConversation() {
+		e.Team = i.teams[c.Team]
+	}
+	switch {
+	case m != nil:
+		e.User = i.users[m.User]
+	case f != nil:
+		e.User = i.users[f.User]
+	}
+	return e
+}
@@ -0,0 +1,161 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// The names of the records in an expression.
+const (
+	VariableMessage = "msg"
+	VariableFile    = "file"
+	VariableUser    = "user"
+	VariableChannel = "channel"
+	VariableTeam    = "team"
+)
+
+// Env is the records an expression is evaluated against.  Any record can be nil, in which case its fields are nil.
+type Env struct {
+	Message *slack.Message
+	File    *slack.MessageFile
+	User    *slack.User
+	Channel *slack.Conversation
+	Team    *slack.Team
+}
+
+// Query is a compiled expression that selects records.  It is safe for concurrent use.
+type Query struct {
+	expression string
+	variables  []string
+	program    *vm.Program
+	mutex      sync.Mutex
+	cache      map[interface{}]interface{} // the values of users, conversations, and teams, which are shared by many records
+}
+
+// size returns the length of a list, map, or string, or zero if nil.
+func size(params ...interface{}) (interface{}, error) {
+	if params[0] == nil {
+		return 0, nil
+	}
+	v := reflect.ValueOf(params[0])
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		return v.Len(), nil
+	}
+	return nil, fmt.Errorf("size of %T is not defined", params[0])
+}
+
+// Compile compiles the expression, which can only reference the given variables, e.g., VariableMessage and VariableUser.
+// Returns an error if the expression is invalid, references a field that does not exist, or does not return a boolean.
+func Compile(expression string, variables ...string) (*Query, error) {
+	env := map[string]interface{}{}
+	for _, name := range variables {
+		env[name] = map[string]interface{}{}
+	}
+	expanded := expandUnits(expression)
+	program, err := expr.Compile(
+		expanded,
+		expr.Env(env),
+		expr.AsBool(),
+		expr.Function("size", size),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling expression %q: %w", expression, err)
+	}
+	if err = checkFields(expanded, variables...); err != nil {
+		return nil, fmt.Errorf("error compiling expression %q: %w", expression, err)
+	}
+	return &Query{
+		expression: expression,
+		variables:  variables,
+		program:    program,
+		cache:      map[interface{}]interface{}{},
+	}, nil
+}
+
+// cached returns the value of the record that is shared by many records, converting it only once.
+func (q *Query) cached(key interface{}, convert func() interface{}) interface{} {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if v, ok := q.cache[key]; ok {
+		return v
+	}
+	v := convert()
+	q.cache[key] = v
+	return v
+}
+
+// teamValue returns the value of the team with the ids of its admins and owners.
+func teamValue(t *slack.Team) interface{} {
+	m := toValue(reflect.ValueOf(t)).(map[string]interface{})
+	admins, owners := []interface{}{}, []interface{}{}
+	for _, u := range t.Users {
+		if u.IsAdmin {
+			admins = append(admins, u.ID)
+		}
+		if u.IsOwner {
+			owners = append(owners, u.ID)
+		}
+	}
+	m["admins"] = admins
+	m["owners"] = owners
+	return m
+}
+
+// value returns the value of the record, or an empty map if it is nil, so that its fields are nil.
+func value(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return map[string]interface{}{}
+	}
+	return toValue(rv)
+}
+
+// Match returns true if the expression is true for the records.
+func (q *Query) Match(e *Env) (bool, error) {
+	values := map[string]interface{}{}
+	for _, name := range q.variables {
+		switch name {
+		case VariableMessage:
+			values[name] = value(e.Message)
+		case VariableFile:
+			values[name] = value(e.File)
+		case VariableUser:
+			if e.User == nil {
+				values[name] = value(e.User)
+			} else {
+				values[name] = q.cached(e.User, func() interface{} { return value(e.User) })
+			}
+		case VariableChannel:
+			if e.Channel == nil {
+				values[name] = value(e.Channel)
+			} else {
+				values[name] = q.cached(e.Channel, func() interface{} { return value(e.Channel) })
+			}
+		case VariableTeam:
+			if e.Team == nil {
+				values[name] = value(e.Team)
+			} else {
+				values[name] = q.cached(e.Team, func() interface{} { return teamValue(e.Team) })
+			}
+		}
+	}
+	result, err := expr.Run(q.program, values)
+	if err != nil {
+		return false, fmt.Errorf("error evaluating expression %q: %w", q.expression, err)
+	}
+	return result.(bool), nil
+}
@@ -0,0 +1,108 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package query
+
+import (
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+func TestExpandUnits(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		expected   string
+	}{
+		{expression: `file.size > 100MB`, expected: `file.size > 100000000`},
+		{expression: `file.size > 1.5GiB`, expected: `file.size > 1610612736`},
+		{expression: `file.size >= 1KB && file.size < 1KiB`, expected: `file.size >= 1000 && file.size < 1024`},
+		{expression: `file.size == 2TB`, expected: `file.size == 2000000000000`},
+		{expression: `file.size == 10B`, expected: `file.size == 10`},
+		{expression: `file.size > 100`, expected: `file.size > 100`},
+		{expression: `file.size > 100mb`, expected: `file.size > 100mb`},
+		{expression: `file.size > 5KiBs`, expected: `file.size > 5KiBs`},
+		{expression: `file.name == "100MB"`, expected: `file.name == "100MB"`},
+		{expression: `file.name == 'a\'100MB'`, expected: `file.name == 'a\'100MB'`},
+		{expression: "file.name == `100MB`", expected: "file.name == `100MB`"},
+		{expression: `file.size > 100MB && file.name == "1KB"`, expected: `file.size > 100000000 && file.name == "1KB"`},
+		{expression: `msg.x2MB > 1`, expected: `msg.x2MB > 1`},
+		{expression: `file.size > 100MB && file.name == "unterminated`, expected: `file.size > 100000000 && file.name == "unterminated`},
+	} {
+		if got := expandUnits(test.expression); got != test.expected {
+			t.Errorf("expecting %q to expand to %q, but found %q", test.expression, test.expected, got)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	alice := &slack.User{ID: "U1", Name: "alice", IsAdmin: true}
+	bob := &slack.User{ID: "U2", Name: "bob"}
+	team := &slack.Team{Name: "hello", Users: []*slack.User{alice, bob}}
+	channel := &slack.Conversation{ID: "C1", Name: "general", Type: slack.ConversationTypeChannel}
+	file := &slack.MessageFile{ID: "F1", FileType: "pdf", Size: 150 * 1000 * 1000}
+	message := &slack.Message{User: "U1", Text: "hello", Files: []slack.MessageFile{*file}}
+	env := &Env{Message: message, File: file, User: alice, Channel: channel, Team: team}
+	variables := []string{VariableMessage, VariableFile, VariableUser, VariableChannel, VariableTeam}
+	for _, test := range []struct {
+		expression string
+		env        *Env
+		expected   bool
+	}{
+		{expression: `file.size > 100MB && file.filetype == "pdf"`, env: env, expected: true},
+		{expression: `file.size > 1GB`, env: env, expected: false},
+		{expression: `msg.user in team.admins && size(msg.files) > 0`, env: env, expected: true},
+		{expression: `msg.user in team.owners`, env: env, expected: false},
+		{expression: `channel.type == "channel" && channel.name == "general"`, env: env, expected: true},
+		{expression: `user.name == "alice"`, env: env, expected: true},
+		{expression: `size(msg.text) == 5`, env: env, expected: true},
+		{expression: `file.id == nil`, env: &Env{Message: message}, expected: true},
+		{expression: `size(file.id) == 0`, env: &Env{Message: message}, expected: true},
+		{expression: `user.name == "alice"`, env: &Env{Message: message, User: bob}, expected: false},
+	} {
+		q, err := Compile(test.expression, variables...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := q.Match(test.env)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", test.expression, err)
+		}
+		if got != test.expected {
+			t.Errorf("expecting %t for %q, but found %t", test.expected, test.expression, got)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, test := range []struct {
+		expression string
+		variables  []string
+		valid      bool
+	}{
+		{expression: `msg.user == "U1"`, variables: []string{VariableMessage}, valid: true},
+		{expression: `file.size > 1MB`, variables: []string{VariableMessage}, valid: false},
+		{expression: `len(msg.user) + 1`, variables: []string{VariableMessage}, valid: false},
+		{expression: `msg.user ==`, variables: []string{VariableMessage}, valid: false},
+		{expression: `msg.usr == "U1"`, variables: []string{VariableMessage}, valid: false},
+		{expression: `msg?.usr == "U1"`, variables: []string{VariableMessage}, valid: false},
+		{expression: `msg.files[0].filetyp == "pdf"`, variables: []string{VariableMessage}, valid: false},
+		{expression: `any(msg.files, .filetyp == "pdf")`, variables: []string{VariableMessage}, valid: false},
+		{expression: `any(msg.files, .filetype == "pdf") && msg.files[0].size > 1MB`, variables: []string{VariableMessage}, valid: true},
+		{expression: `channel.type == "channel" && user.profile.email == ""`, variables: []string{VariableChannel, VariableUser}, valid: true},
+		{expression: `msg.user in team.admins && size(team.ownerz) > 0`, variables: []string{VariableMessage, VariableTeam}, valid: false},
+		{expression: `msg.user in team.admins && all(team.users, .id != "")`, variables: []string{VariableMessage, VariableTeam}, valid: true},
+	} {
+		_, err := Compile(test.expression, test.variables...)
+		if test.valid && err != nil {
+			t.Errorf("unexpected error for %q: %v", test.expression, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expecting an error for %q", test.expression)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package query filters the records of a Slack archive with expressions written in the expr language,
+// see https://expr-lang.org.
+//
+// An expression is evaluated against the message as msg, the file as file, the user who sent the message as user,
+// the conversation as channel, and the team of the conversation as team.  Fields are referenced by their JSON names,
+// e.g., msg.user or file.filetype, and teams also include the ids of their admins and owners as team.admins and team.owners.
+// Referencing a field that does not exist, e.g., msg.usr, is an error when the expression is compiled.
+// Sizes can be written with units, e.g., 100MB or 1GiB, and size returns the length of a list, map, or string.
+//
+//	msg.user in team.admins && size(msg.files) > 0
+//	file.size > 100MB && file.filetype == "pdf"
+package query
@@ -0,0 +1,221 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// schema is the fields of a value that an expression can reference, as converted by toValue.
+// A nil schema is a value whose fields are not known, e.g., a map, so any field can be referenced.
+type schema struct {
+	fields map[string]*schema // the fields of a struct by JSON name, or nil if the value is not a struct
+	elem   *schema            // the schema of the elements of a slice or array
+}
+
+// schemas are the schemas of the variables.
+var schemas = map[string]*schema{
+	VariableMessage: typeSchema(reflect.TypeOf(slack.Message{})),
+	VariableFile:    typeSchema(reflect.TypeOf(slack.MessageFile{})),
+	VariableUser:    typeSchema(reflect.TypeOf(slack.User{})),
+	VariableChannel: typeSchema(reflect.TypeOf(slack.Conversation{})),
+	VariableTeam:    teamSchema(),
+}
+
+// teamSchema returns the schema of the value of a team, which includes the ids of its admins and owners.
+func teamSchema() *schema {
+	s := typeSchema(reflect.TypeOf(slack.Team{}))
+	s.fields["admins"] = &schema{elem: &schema{}}
+	s.fields["owners"] = &schema{elem: &schema{}}
+	return s
+}
+
+// typeSchema returns the schema of the values of the type.
+func typeSchema(t reflect.Type) *schema {
+	return newSchema(t, map[reflect.Type]*schema{})
+}
+
+// newSchema returns the schema of the type, reusing the schemas of the structs in seen, so recursive types end.
+func newSchema(t reflect.Type, seen map[reflect.Type]*schema) *schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return newSchema(t.Elem(), seen)
+	case reflect.Interface, reflect.Map:
+		return nil
+	case reflect.Struct:
+		if s, ok := seen[t]; ok {
+			return s
+		}
+		s := &schema{fields: map[string]*schema{}}
+		seen[t] = s
+		addFieldSchemas(s, t, seen)
+		return s
+	case reflect.Slice, reflect.Array:
+		return &schema{elem: newSchema(t.Elem(), seen)}
+	}
+	return &schema{}
+}
+
+// addFieldSchemas adds the schemas of the exported fields of the struct, including the fields of embedded structs, like addFields.
+func addFieldSchemas(s *schema, t reflect.Type, seen map[reflect.Type]*schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; len(n) > 0 {
+				name = n
+			} else if f.Anonymous {
+				addEmbeddedSchemas(s, f.Type, seen)
+				continue
+			}
+		} else if f.Anonymous {
+			addEmbeddedSchemas(s, f.Type, seen)
+			continue
+		}
+		s.fields[name] = newSchema(f.Type, seen)
+	}
+}
+
+func addEmbeddedSchemas(s *schema, t reflect.Type, seen map[reflect.Type]*schema) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		addFieldSchemas(s, t, seen)
+	}
+}
+
+// checker checks that the fields referenced by an expression exist.
+type checker struct {
+	variables map[string]*schema
+	pointers  map[*ast.PointerNode]*schema  // the schemas of the elements in the predicates of builtins, e.g., any or filter
+	lists     map[*ast.PointerNode]ast.Node // the lists of the elements, to describe them in errors
+	err       error
+}
+
+// checkFields returns an error if the expression references a field of a variable that does not exist, e.g., msg.usr.
+// Fields of the elements of lists are checked in predicates, e.g., any(msg.files, .filetype == "pdf").
+func checkFields(expression string, variables ...string) error {
+	tree, err := parser.Parse(expression)
+	if err != nil {
+		return err
+	}
+	c := &checker{
+		variables: map[string]*schema{},
+		pointers:  map[*ast.PointerNode]*schema{},
+		lists:     map[*ast.PointerNode]ast.Node{},
+	}
+	for _, name := range variables {
+		c.variables[name] = schemas[name]
+	}
+	ast.Walk(&tree.Node, pointerVisitor{c})
+	ast.Walk(&tree.Node, c)
+	return c.err
+}
+
+// resolve returns the schema of the value of the node, or nil if it is not known.
+func (c *checker) resolve(node ast.Node) *schema {
+	switch n := node.(type) {
+	case *ast.IdentifierNode:
+		return c.variables[n.Value]
+	case *ast.ChainNode:
+		return c.resolve(n.Node)
+	case *ast.PointerNode:
+		if len(n.Name) > 0 {
+			return nil
+		}
+		return c.pointers[n]
+	case *ast.MemberNode:
+		s := c.resolve(n.Node)
+		if s == nil {
+			return nil
+		}
+		if p, ok := n.Property.(*ast.StringNode); ok && s.fields != nil {
+			return s.fields[p.Value]
+		}
+		return s.elem
+	}
+	return nil
+}
+
+// Visit records an error for the first reference to a field that does not exist.
+func (c *checker) Visit(node *ast.Node) {
+	if c.err != nil {
+		return
+	}
+	n, ok := (*node).(*ast.MemberNode)
+	if !ok {
+		return
+	}
+	p, ok := n.Property.(*ast.StringNode)
+	if !ok {
+		return
+	}
+	s := c.resolve(n.Node)
+	if s == nil || s.fields == nil {
+		return
+	}
+	if _, ok := s.fields[p.Value]; !ok {
+		if pointer, ok := n.Node.(*ast.PointerNode); ok {
+			c.err = fmt.Errorf("unknown field %q of the elements of %s", p.Value, c.lists[pointer])
+			return
+		}
+		c.err = fmt.Errorf("unknown field %q of %s", p.Value, n.Node)
+	}
+}
+
+// pointerVisitor sets the schemas of the pointers in the predicates of builtins to the schema of the elements of their lists.
+// Children are visited first, so the pointers of a nested predicate are set before the predicates that contain it.
+type pointerVisitor struct {
+	*checker
+}
+
+func (v pointerVisitor) Visit(node *ast.Node) {
+	n, ok := (*node).(*ast.BuiltinNode)
+	if !ok || len(n.Arguments) < 2 {
+		return
+	}
+	var elem *schema
+	if s := v.resolve(n.Arguments[0]); s != nil {
+		elem = s.elem
+	}
+	for _, arg := range n.Arguments[1:] {
+		if p, ok := arg.(*ast.PredicateNode); ok {
+			ast.Walk(&p.Node, pointerSetter{checker: v.checker, list: n.Arguments[0], elem: elem})
+		}
+	}
+}
+
+// pointerSetter sets the schemas of the pointers that are not already set by a nested predicate.
+type pointerSetter struct {
+	*checker
+	list ast.Node
+	elem *schema
+}
+
+func (v pointerSetter) Visit(node *ast.Node) {
+	if p, ok := (*node).(*ast.PointerNode); ok {
+		if _, ok := v.pointers[p]; !ok {
+			v.pointers[p] = v.elem
+			v.lists[p] = v.list
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+// units are the multipliers of sizes, where KB is 1000 bytes and KiB is 1024 bytes.
+var units = map[string]int64{
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifier(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// expandUnits replaces the numbers followed by a unit, e.g., 100MB or 1.5GiB, with the number of bytes.
+// String literals and identifiers that include digits are left as is.
+func expandUnits(expression string) string {
+	var b strings.Builder
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			// copy the string literal, including escaped quotes
+			j := i + 1
+			for j < len(expression) && expression[j] != c {
+				if expression[j] == '\\' && c != '`' {
+					j++
+				}
+				j++
+			}
+			if j < len(expression) {
+				j++
+			}
+			b.WriteString(expression[i:j])
+			i = j
+		case isIdentifier(c) && !isDigit(c):
+			j := i
+			for j < len(expression) && isIdentifier(expression[j]) {
+				j++
+			}
+			b.WriteString(expression[i:j])
+			i = j
+		case isDigit(c):
+			j := i
+			for j < len(expression) && isDigit(expression[j]) {
+				j++
+			}
+			if j+1 < len(expression) && expression[j] == '.' && isDigit(expression[j+1]) {
+				j++
+				for j < len(expression) && isDigit(expression[j]) {
+					j++
+				}
+			}
+			k := j
+			for k < len(expression) && isIdentifier(expression[k]) {
+				k++
+			}
+			if multiplier, ok := units[expression[j:k]]; ok {
+				if n, err := strconv.ParseFloat(expression[i:j], 64); err == nil {
+					b.WriteString(strconv.FormatInt(int64(n*float64(multiplier)), 10))
+					i = k
+					continue
+				}
+			}
+			b.WriteString(expression[i:j])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package query
+
+import (
+	"reflect"
+	"strings"
+)
+
+// toValue converts a struct into a map keyed by the JSON names of its fields, recursively.
+// Unlike marshaling to JSON, fields tagged with omitempty are included, so an expression can compare a field that is empty.
+func toValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toValue(v.Elem())
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		addFields(m, v)
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return []interface{}{}
+		}
+		s := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s = append(s, toValue(v.Index(i)))
+		}
+		return s
+	case reflect.Map:
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = toValue(iter.Value())
+		}
+		return m
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return v.Interface()
+}
+
+// addFields adds the exported fields of the struct to the map, including the fields of embedded structs.
+func addFields(m map[string]interface{}, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; len(n) > 0 {
+				name = n
+			} else if f.Anonymous {
+				addEmbedded(m, v.Field(i))
+				continue
+			}
+		} else if f.Anonymous {
+			addEmbedded(m, v.Field(i))
+			continue
+		}
+		m[name] = toValue(v.Field(i))
+	}
+}
+
+func addEmbedded(m map[string]interface{}, v reflect.Value) {
+	if e := reflect.Indirect(v); e.IsValid() && e.Kind() == reflect.Struct {
+		addFields(m, e)
+	}
+}
@@ -0,0 +1,456 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package redact
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deptofdefense/slack-archiver/pkg/jsonutil"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+type Mode string
+
+const (
+	ModeMask Mode = "mask"
+	ModeHash Mode = "hash"
+)
+
+const (
+	Mask = "[REDACTED]"
+)
+
+type Options struct {
+	Mode                Mode
+	Key                 []byte   // the key used when hashing values
+	Rules               []*Rule  // the rules applied to message, block, attachment, and file text
+	RemoveUsers         []string // the ids of users to remove
+	RemoveConversations []string // the ids or names of conversations to remove
+}
+
+type redactor struct {
+	options             *Options
+	log                 func(entry *LogEntry) error
+	removeUsers         map[string]struct{}
+	removeConversations map[string]struct{}
+}
+
+// attachmentTextFields are the fields of message attachments that the rules are applied to.
+var attachmentTextFields = []string{"pretext", "title", "text", "fallback", "footer"}
+
+// fileTextFields are the fields of message files that the rules are applied to.
+var fileTextFields = []string{"title", "name", "preview", "preview_highlight", "plain_text"}
+
+// Redact writes a copy of the enterprise grid to w, with user profiles masked or hashed,
+// the rules applied to message, block, attachment, and file text, and the selected users and conversations removed.
+// Records are redacted in their original JSON, so everything else is written byte-for-byte.
+// Every change is passed to log.  Redacting stops with the error of the context if it is canceled.
+func Redact(ctx context.Context, e *slack.EnterpriseGrid, w *slack.ArchiveWriter, options *Options, log func(entry *LogEntry) error) error {
+	r := &redactor{
+		options:             options,
+		log:                 log,
+		removeUsers:         map[string]struct{}{},
+		removeConversations: map[string]struct{}{},
+	}
+	for _, id := range options.RemoveUsers {
+		r.removeUsers[id] = struct{}{}
+	}
+	for _, c := range options.RemoveConversations {
+		r.removeConversations[c] = struct{}{}
+	}
+
+	for _, f := range e.GetGridFiles() {
+		file := f
+		err := w.CopyFile(e, f.Name, func(records []json.RawMessage) ([]json.RawMessage, error) {
+			return r.redactRecords(file, records)
+		})
+		if err != nil {
+			return fmt.Errorf("error writing redacted enterprise grid: %w", err)
+		}
+	}
+
+	for _, c := range e.GetConversations() {
+		if r.isRemovedConversation(c.ID, c.Name) {
+			continue
+		}
+		conversation := c
+		err := w.CopyRawConversation(ctx, e, c, c, func(day string, messages []json.RawMessage) ([]json.RawMessage, error) {
+			return r.redactMessages(conversation, messages)
+		})
+		if err != nil {
+			return fmt.Errorf("error redacting %s %q: %w", c.Type, c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *redactor) hash(value string) string {
+	mac := hmac.New(sha256.New, r.options.Key)
+	_, _ = mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func (r *redactor) action() string {
+	if r.options.Mode == ModeHash {
+		return ActionHashed
+	}
+	return ActionMasked
+}
+
+func (r *redactor) isRemovedConversation(id string, name string) bool {
+	_, removedID := r.removeConversations[id]
+	_, removedName := r.removeConversations[name]
+	return removedID || removedName
+}
+
+func (r *redactor) filterMembers(members []string) []string {
+	filtered := make([]string, 0, len(members))
+	for _, m := range members {
+		if _, ok := r.removeUsers[m]; !ok {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+func (r *redactor) logRemovedConversation(id string, name string) error {
+	return r.log(&LogEntry{Action: ActionRemoved, Kind: KindConversation, Conversation: id, Field: name})
+}
+
+// redactRecords redacts the users or conversations in a file of the enterprise grid.  Integration logs are returned as is.
+func (r *redactor) redactRecords(f *slack.GridFile, records []json.RawMessage) ([]json.RawMessage, error) {
+	var fn func(obj *jsonutil.Object) (bool, error)
+	switch f.Kind {
+	case slack.GridFileKindIntegrationLogs:
+		return records, nil
+	case slack.GridFileKindUsers:
+		list := strings.TrimSuffix(f.Name, ".json")
+		fn = func(obj *jsonutil.Object) (bool, error) {
+			return r.redactUser(obj, list)
+		}
+	default:
+		fn = r.redactConversation
+	}
+	redacted := make([]json.RawMessage, 0, len(records))
+	for _, record := range records {
+		obj, err := jsonutil.ParseObject(record)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing record in %q: %w", f.Name, err)
+		}
+		keep, err := fn(obj)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			redacted = append(redacted, obj.Bytes())
+		}
+	}
+	return redacted, nil
+}
+
+// redactConversation removes the selected users from the members of the conversation,
+// and returns false if the conversation is removed.
+func (r *redactor) redactConversation(c *jsonutil.Object) (bool, error) {
+	id, _ := c.GetString("id")
+	name, ok := c.GetString("name")
+	if !ok {
+		// direct messages are named by id
+		name = id
+	}
+	if r.isRemovedConversation(id, name) {
+		return false, r.logRemovedConversation(id, name)
+	}
+	err := c.UpdateStrings("members", r.filterMembers)
+	if err != nil {
+		return false, fmt.Errorf("error redacting members of conversation %q: %w", id, err)
+	}
+	return true, nil
+}
+
+// redactUser masks or hashes the profile of the user, and returns false if the user is removed.
+func (r *redactor) redactUser(u *jsonutil.Object, list string) (bool, error) {
+	id, _ := u.GetString("id")
+	if _, ok := r.removeUsers[id]; ok {
+		return false, r.log(&LogEntry{Action: ActionRemoved, Kind: KindUser, User: id, Field: list})
+	}
+	err := u.UpdateObject("profile", func(p *jsonutil.Object) error {
+		return r.redactProfile(id, p)
+	})
+	if err != nil {
+		return false, fmt.Errorf("error redacting profile of user %q: %w", id, err)
+	}
+	return true, nil
+}
+
+func (r *redactor) redactValue(user string, field string, value string) (string, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+	err := r.log(&LogEntry{Action: r.action(), Kind: KindProfile, User: user, Field: field})
+	if err != nil {
+		return "", err
+	}
+	if r.options.Mode == ModeHash {
+		return r.hash(value), nil
+	}
+	return Mask, nil
+}
+
+// redactField masks or hashes the value of the key in the object, if the value is a string, and logs it as the field.
+func (r *redactor) redactField(obj *jsonutil.Object, key string, user string, field string) error {
+	value, ok := obj.GetString(key)
+	if !ok {
+		return nil
+	}
+	redacted, err := r.redactValue(user, field, value)
+	if err != nil {
+		return err
+	}
+	if redacted == value {
+		return nil
+	}
+	return obj.SetValue(key, redacted)
+}
+
+func (r *redactor) redactProfile(user string, p *jsonutil.Object) error {
+	for _, key := range []string{"email", "phone", "skype"} {
+		if err := r.redactField(p, key, user, key); err != nil {
+			return err
+		}
+	}
+	return p.UpdateObject("fields", func(fields *jsonutil.Object) error {
+		keys := fields.Keys()
+		sort.Strings(keys)
+		for _, k := range keys {
+			key := k
+			err := fields.UpdateObject(key, func(v *jsonutil.Object) error {
+				if err := r.redactField(v, "value", user, "fields."+key); err != nil {
+					return err
+				}
+				return r.redactField(v, "alt", user, "fields."+key+".alt")
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// redactText applies the rules to the text and returns the number of matches for each rule.
+func (r *redactor) redactText(text string, counts map[string]int) string {
+	for _, rule := range r.options.Rules {
+		name := rule.Name
+		text = rule.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			counts[name]++
+			if r.options.Mode == ModeHash {
+				return fmt.Sprintf("[%s:%s]", name, r.hash(match))
+			}
+			return fmt.Sprintf("[REDACTED:%s]", name)
+		})
+	}
+	return text
+}
+
+func (r *redactor) logCounts(kind string, c *slack.Conversation, user string, ts string, counts map[string]int) error {
+	for _, rule := range r.options.Rules {
+		if count := counts[rule.Name]; count > 0 {
+			err := r.log(&LogEntry{
+				Action:       r.action(),
+				Kind:         kind,
+				User:         user,
+				Conversation: c.ID,
+				Timestamp:    ts,
+				Rule:         rule.Name,
+				Count:        count,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *redactor) redactMessages(c *slack.Conversation, messages []json.RawMessage) ([]json.RawMessage, error) {
+	redacted := make([]json.RawMessage, 0, len(messages))
+	for _, data := range messages {
+		m, err := jsonutil.ParseObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing message: %w", err)
+		}
+		user, _ := m.GetString("user")
+		ts, _ := m.GetString("ts")
+
+		if _, ok := r.removeUsers[user]; ok {
+			err = r.log(&LogEntry{Action: ActionRemoved, Kind: KindMessage, User: user, Conversation: c.ID, Timestamp: ts})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		textCounts := map[string]int{}
+		err = m.UpdateString("text", func(text string) string {
+			return r.redactText(text, textCounts)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error redacting text of message %q: %w", ts, err)
+		}
+		err = r.logCounts(KindText, c, user, ts, textCounts)
+		if err != nil {
+			return nil, err
+		}
+
+		blockCounts := map[string]int{}
+		err = slack.WalkRawBlocks(m, func(e *jsonutil.Object) error {
+			return slack.TransformRawBlockText(e, func(text string) string {
+				return r.redactText(text, blockCounts)
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error redacting blocks of message %q: %w", ts, err)
+		}
+		err = r.logCounts(KindBlock, c, user, ts, blockCounts)
+		if err != nil {
+			return nil, err
+		}
+
+		attachmentCounts := map[string]int{}
+		err = m.UpdateObjects("attachments", func(a *jsonutil.Object) error {
+			return r.redactAttachment(a, attachmentCounts)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error redacting attachments of message %q: %w", ts, err)
+		}
+		err = r.logCounts(KindAttachment, c, user, ts, attachmentCounts)
+		if err != nil {
+			return nil, err
+		}
+
+		fileCounts := map[string]int{}
+		err = m.UpdateObjects("files", func(f *jsonutil.Object) error {
+			return r.redactTextFields(f, fileTextFields, fileCounts)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error redacting files of message %q: %w", ts, err)
+		}
+		err = r.logCounts(KindFile, c, user, ts, fileCounts)
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.redactReplies(m)
+		if err != nil {
+			return nil, fmt.Errorf("error redacting replies of message %q: %w", ts, err)
+		}
+		err = m.UpdateObjects("reactions", r.redactReaction)
+		if err != nil {
+			return nil, fmt.Errorf("error redacting reactions of message %q: %w", ts, err)
+		}
+
+		redacted = append(redacted, m.Bytes())
+	}
+	return redacted, nil
+}
+
+// redactTextFields applies the rules to the fields of the object with the given keys, if they are strings.
+func (r *redactor) redactTextFields(obj *jsonutil.Object, keys []string, counts map[string]int) error {
+	for _, key := range keys {
+		err := obj.UpdateString(key, func(text string) string {
+			return r.redactText(text, counts)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactAttachment applies the rules to the text fields of the attachment and to the titles and values of its fields.
+func (r *redactor) redactAttachment(a *jsonutil.Object, counts map[string]int) error {
+	if err := r.redactTextFields(a, attachmentTextFields, counts); err != nil {
+		return err
+	}
+	return a.UpdateObjects("fields", func(f *jsonutil.Object) error {
+		return r.redactTextFields(f, []string{"title", "value"}, counts)
+	})
+}
+
+// redactReplies removes the selected users from the reply users and replies of the parent message of a thread,
+// and updates the number of reply users and replies to match.
+func (r *redactor) redactReplies(m *jsonutil.Object) error {
+	removedUsers := 0
+	err := m.UpdateStrings("reply_users", func(users []string) []string {
+		filtered := r.filterMembers(users)
+		removedUsers = len(users) - len(filtered)
+		return filtered
+	})
+	if err != nil {
+		return err
+	}
+	if removedUsers > 0 {
+		if err = updateCount(m, "reply_users_count", -removedUsers); err != nil {
+			return err
+		}
+	}
+	removed := 0
+	err = m.UpdateArray("replies", func(a *jsonutil.Array) error {
+		return a.Filter(func(value json.RawMessage) (bool, error) {
+			reply, err := jsonutil.ParseObject(value)
+			if err != nil {
+				return false, err
+			}
+			user, _ := reply.GetString("user")
+			if _, ok := r.removeUsers[user]; ok {
+				removed++
+				return false, nil
+			}
+			return true, nil
+		})
+	})
+	if err != nil || removed == 0 {
+		return err
+	}
+	return updateCount(m, "reply_count", -removed)
+}
+
+// redactReaction removes the selected users from the reaction and reduces its count by the number of users removed.
+func (r *redactor) redactReaction(reaction *jsonutil.Object) error {
+	removed := 0
+	err := reaction.UpdateStrings("users", func(users []string) []string {
+		filtered := r.filterMembers(users)
+		removed = len(users) - len(filtered)
+		return filtered
+	})
+	if err != nil || removed == 0 {
+		return err
+	}
+	return updateCount(reaction, "count", -removed)
+}
+
+// updateCount adds delta to the number at the key of the object, if the key is present.
+func updateCount(obj *jsonutil.Object, key string, delta int) error {
+	value, ok := obj.Get(key)
+	if !ok {
+		return nil
+	}
+	count := 0
+	if err := json.Unmarshal(value, &count); err != nil {
+		return fmt.Errorf("error unmarshaling %q: %w", key, err)
+	}
+	return obj.SetValue(key, count+delta)
+}
@@ -0,0 +1,154 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package redact
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/jsonutil"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestGrid returns an enterprise grid where alice (U1) posts personal information in every field that holds text,
+// and bob (U2) replies to a thread, reacts to messages, and is a member of every conversation.
+func newTestGrid(t *testing.T) *slack.EnterpriseGrid {
+	return slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json": `[
+			{"id": "U1", "name": "alice", "profile": {"email": "alice@example.com", "phone": "555-123-4567"}},
+			{"id": "U2", "name": "bob", "profile": {"email": "bob@example.com"}}
+		]`,
+		"dms.json":                  `[{"id": "D1", "members": ["U1", "U2"]}]`,
+		"teams/hello/channels.json": `[{"id": "C1", "name": "general", "members": ["U1", "U2"]}]`,
+		"teams/hello/users.json":    `[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}]`,
+		"teams/hello/general/2020-07-29.json": `[
+			{
+				"type": "message", "user": "U1", "ts": "1596060000.000100",
+				"text": "my ssn is 123-45-6789",
+				"blocks": [
+					{"type": "header", "text": {"type": "plain_text", "text": "ssn 123-45-6789"}},
+					{
+						"type": "section",
+						"text": {"type": "mrkdwn", "text": "call 555-123-4567"},
+						"fields": [{"type": "mrkdwn", "text": "email alice@example.com"}],
+						"accessory": {"type": "button", "text": {"type": "plain_text", "text": "dodid 1234567890"}}
+					},
+					{"type": "context", "elements": [{"type": "mrkdwn", "text": "ssn 123-45-6789"}]},
+					{"type": "rich_text", "elements": [
+						{"type": "rich_text_section", "elements": [{"type": "text", "text": "ssn 123-45-6789"}]}
+					]}
+				],
+				"attachments": [{
+					"title": "ssn 123-45-6789",
+					"fields": [{"title": "email alice@example.com", "value": "call 555-123-4567"}]
+				}],
+				"files": [{
+					"id": "F1",
+					"name": "123-45-6789.pdf",
+					"title": "ssn 123-45-6789",
+					"preview": "call 555-123-4567",
+					"plain_text": "email alice@example.com"
+				}],
+				"reactions": [{"name": "+1", "users": ["U1", "U2"], "count": 2}],
+				"reply_count": 2,
+				"reply_users_count": 2,
+				"reply_users": ["U1", "U2"],
+				"replies": [{"user": "U2", "ts": "1596060001.000100"}, {"user": "U1", "ts": "1596060002.000100"}]
+			},
+			{"type": "message", "user": "U2", "text": "bob@example.com", "ts": "1596060001.000100", "thread_ts": "1596060000.000100"},
+			{"type": "message", "user": "U1", "text": "thanks", "ts": "1596060002.000100", "thread_ts": "1596060000.000100"}
+		]`,
+	})
+}
+
+// redactTestGrid redacts the test enterprise grid and returns the contents of the files in the redacted archive.
+func redactTestGrid(t *testing.T, options *Options) map[string]string {
+	t.Helper()
+	files, err := slacktest.WriteArchive(t, func(w *slack.ArchiveWriter) error {
+		return Redact(context.Background(), newTestGrid(t), w, options, func(entry *LogEntry) error {
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func testRules(t *testing.T) []*Rule {
+	rules := make([]*Rule, 0)
+	for _, name := range BuiltinRuleNames() {
+		r, err := GetBuiltinRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// TestRedactLeaks checks that no personal information or removed user is left anywhere in the redacted archive.
+func TestRedactLeaks(t *testing.T) {
+	leaks := []string{"123-45-6789", "555-123-4567", "1234567890", "alice@example.com", "bob@example.com", "U2", "bob"}
+	for _, mode := range []Mode{ModeMask, ModeHash} {
+		t.Run(string(mode), func(t *testing.T) {
+			files := redactTestGrid(t, &Options{
+				Mode:        mode,
+				Key:         []byte("key"),
+				Rules:       testRules(t),
+				RemoveUsers: []string{"U2"},
+			})
+			if len(files) == 0 {
+				t.Fatal("expecting files in the redacted archive")
+			}
+			for name, data := range files {
+				for _, leak := range leaks {
+					if strings.Contains(data, leak) {
+						t.Errorf("expecting %q to be redacted from %q, but found %s", leak, name, data)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestRedactReplies checks that the counts of a thread are updated when the replies of a removed user are removed.
+func TestRedactReplies(t *testing.T) {
+	files := redactTestGrid(t, &Options{Mode: ModeMask, RemoveUsers: []string{"U2"}})
+	a, err := jsonutil.ParseArray([]byte(files["teams/hello/general/2020-07-29.json"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 2 {
+		t.Fatalf("expecting 2 messages, but found %d", a.Len())
+	}
+	parent, err := jsonutil.ParseObject(a.Get(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		key      string
+		expected string
+	}{
+		{key: "reply_count", expected: `1`},
+		{key: "reply_users_count", expected: `1`},
+		{key: "reply_users", expected: `["U1"]`},
+		{key: "replies", expected: `[{"user": "U1", "ts": "1596060002.000100"}]`},
+	} {
+		value, _ := parent.Get(test.key)
+		if got := string(value); got != test.expected {
+			t.Errorf("expecting %s of %s, but found %s", test.key, test.expected, got)
+		}
+	}
+	reaction := `"reactions": [{"name": "+1", "users": ["U1"], "count": 1}]`
+	if !strings.Contains(string(a.Get(0)), reaction) {
+		t.Errorf("expecting reactions %s, but found %s", reaction, a.Get(0))
+	}
+}
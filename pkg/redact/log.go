@@ -0,0 +1,37 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package redact
+
+const (
+	ActionMasked  = "masked"
+	ActionHashed  = "hashed"
+	ActionRemoved = "removed"
+)
+
+const (
+	KindUser         = "user"
+	KindConversation = "conversation"
+	KindMessage      = "message"
+	KindProfile      = "profile"
+	KindText         = "text"
+	KindBlock        = "block"
+	KindAttachment   = "attachment"
+	KindFile         = "file"
+)
+
+// LogEntry records a change made while redacting.  The original values are never logged.
+type LogEntry struct {
+	Action       string `json:"action"`
+	Kind         string `json:"kind"`
+	User         string `json:"user,omitempty"`
+	Conversation string `json:"conversation,omitempty"`
+	Timestamp    string `json:"ts,omitempty"`
+	Field        string `json:"field,omitempty"`
+	Rule         string `json:"rule,omitempty"`
+	Count        int    `json:"count,omitempty"`
+}
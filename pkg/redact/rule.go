@@ -0,0 +1,91 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule is a named pattern that is redacted from message text.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+var builtinRules = map[string]*Rule{
+	"ssn": {
+		Name:    "ssn",
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	},
+	"phone": {
+		Name:    "phone",
+		Pattern: regexp.MustCompile(`(?:\+?1[-. ]?)?\(?\b\d{3}\)?[-. ]?\d{3}[-. ]\d{4}\b`),
+	},
+	// DoD ID numbers, a.k.a. EDIPIs, are 10 digits.
+	"dodid": {
+		Name:    "dodid",
+		Pattern: regexp.MustCompile(`\b\d{10}\b`),
+	},
+	"email": {
+		Name:    "email",
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	},
+}
+
+// BuiltinRuleNames returns the sorted names of the builtin rules.
+func BuiltinRuleNames() []string {
+	names := make([]string, 0, len(builtinRules))
+	for name := range builtinRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetBuiltinRule returns the builtin rule with the given name.
+func GetBuiltinRule(name string) (*Rule, error) {
+	r, ok := builtinRules[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rule %q, expecting one of %s", name, strings.Join(BuiltinRuleNames(), ", "))
+	}
+	return r, nil
+}
+
+// NewPatternRule returns a rule from a definition formatted as "name=regular expression".
+func NewPatternRule(definition string) (*Rule, error) {
+	parts := strings.SplitN(definition, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("invalid pattern %q, expecting name=expression", definition)
+	}
+	pattern, err := regexp.Compile(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("error compiling pattern %q: %w", parts[0], err)
+	}
+	return &Rule{Name: parts[0], Pattern: pattern}, nil
+}
+
+// NewDictionaryRule returns a rule that matches any of the words, ignoring case.
+func NewDictionaryRule(name string, words []string) (*Rule, error) {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.TrimSpace(w); len(w) > 0 {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil, fmt.Errorf("dictionary %q is empty", name)
+	}
+	pattern, err := regexp.Compile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling dictionary %q: %w", name, err)
+	}
+	return &Rule{Name: name, Pattern: pattern}, nil
+}
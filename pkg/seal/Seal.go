@@ -0,0 +1,124 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package seal
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+func hashReader(r io.Reader) (int64, string, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashArchive hashes every entry in the archive, including directories.
+func hashArchive(ctx context.Context, a *slack.Archive) ([]*Entry, error) {
+	entries := []*Entry{}
+	for _, f := range a.GetFiles("") {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("error hashing archive: %w", err)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error opening entry %q: %w", f.Name, err)
+		}
+		size, sum, err := hashReader(r)
+		_ = r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error hashing entry %q: %w", f.Name, err)
+		}
+		entries = append(entries, &Entry{Source: SourceArchive, Path: f.Name, Size: size, SHA256: sum})
+	}
+	return entries, nil
+}
+
+// hashFiles hashes every regular file under the directory, using slash separated paths relative to the directory.
+func hashFiles(ctx context.Context, directory string) ([]*Entry, error) {
+	entries := []*Entry{}
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error opening file %q: %w", path, err)
+		}
+		size, sum, err := hashReader(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("error hashing file %q: %w", path, err)
+		}
+		entries = append(entries, &Entry{Source: SourceFiles, Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing files in %q: %w", directory, err)
+	}
+	return entries, nil
+}
+
+// Hash returns the sorted entries of the archive and of the files downloaded to the directory.
+// If the directory is blank, then only the archive is hashed.  Hashing stops with the error of the context if it is canceled.
+func Hash(ctx context.Context, a *slack.Archive, filesDirectory string) ([]*Entry, error) {
+	entries, err := hashArchive(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if len(filesDirectory) > 0 {
+		files, err := hashFiles(ctx, filesDirectory)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, files...)
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// Seal hashes the archive and the files downloaded to the directory, and returns a manifest signed with the key.
+func Seal(ctx context.Context, a *slack.Archive, filesDirectory string, key ed25519.PrivateKey, now time.Time) (*Manifest, error) {
+	entries, err := Hash(ctx, a, filesDirectory)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{
+		Version:   ManifestVersion,
+		Created:   now.UTC(),
+		Root:      merkleRoot(entries),
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Entries:   entries,
+	}
+	m.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, m.signedMessage()))
+	return m, nil
+}
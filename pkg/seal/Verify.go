@@ -0,0 +1,117 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package seal
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+const (
+	ActionAltered = "altered"
+	ActionAdded   = "added"
+	ActionRemoved = "removed"
+)
+
+// Change is an entry that differs from the manifest.
+type Change struct {
+	Action   string `json:"action"`
+	Source   string `json:"source"`
+	Path     string `json:"path"`
+	Expected *Entry `json:"expected,omitempty"`
+	Actual   *Entry `json:"actual,omitempty"`
+}
+
+// Report is the result of verifying an archive against a manifest.
+type Report struct {
+	Valid   bool      `json:"valid"`
+	Root    string    `json:"root"`
+	Altered int       `json:"altered"`
+	Added   int       `json:"added"`
+	Removed int       `json:"removed"`
+	Changes []*Change `json:"changes"`
+}
+
+func (r *Report) add(c *Change) {
+	switch c.Action {
+	case ActionAltered:
+		r.Altered++
+	case ActionAdded:
+		r.Added++
+	case ActionRemoved:
+		r.Removed++
+	}
+	r.Changes = append(r.Changes, c)
+	r.Valid = false
+}
+
+// VerifySignature checks that the entries of the manifest match its root and that the root is signed by the key.
+func VerifySignature(m *Manifest, key ed25519.PublicKey) error {
+	if root := merkleRoot(m.Entries); root != m.Root {
+		return fmt.Errorf("entries of manifest do not match root: computed %q, but manifest has %q", root, m.Root)
+	}
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %w", err)
+	}
+	if !ed25519.Verify(key, m.signedMessage(), signature) {
+		return fmt.Errorf("signature of manifest is not valid for the given public key")
+	}
+	return nil
+}
+
+// Verify checks the signature of the manifest, hashes the archive and the files downloaded to the directory,
+// and reports every entry that was altered, added, or removed since the manifest was sealed.
+// If the manifest includes downloaded files, then the directory must be given or the files are reported as removed.
+func Verify(ctx context.Context, m *Manifest, key ed25519.PublicKey, a *slack.Archive, filesDirectory string) (*Report, error) {
+	err := VerifySignature(m, key)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := Hash(ctx, a, filesDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Valid:   true,
+		Root:    merkleRoot(entries),
+		Changes: []*Change{},
+	}
+
+	actual := map[string]*Entry{}
+	for _, e := range entries {
+		actual[e.key()] = e
+	}
+
+	expected := map[string]*Entry{}
+	for _, e := range m.Entries {
+		expected[e.key()] = e
+		a, ok := actual[e.key()]
+		if !ok {
+			report.add(&Change{Action: ActionRemoved, Source: e.Source, Path: e.Path, Expected: e})
+			continue
+		}
+		if a.Size != e.Size || a.SHA256 != e.SHA256 {
+			report.add(&Change{Action: ActionAltered, Source: e.Source, Path: e.Path, Expected: e, Actual: a})
+		}
+	}
+
+	for _, e := range entries {
+		if _, ok := expected[e.key()]; !ok {
+			report.add(&Change{Action: ActionAdded, Source: e.Source, Path: e.Path, Actual: e})
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,227 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package seal
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestFiles returns the files of an archive with a channel.
+func newTestFiles() slacktest.Files {
+	return slacktest.Files{
+		"org_users.json":                      `[{"id": "U1", "name": "alice"}]`,
+		"teams/hello/channels.json":           `[{"id": "C1", "name": "general"}]`,
+		"teams/hello/general/2020-07-29.json": `[{"type": "message", "user": "U1", "text": "hello", "ts": "1596060000.000100"}]`,
+	}
+}
+
+// newTestDirectory returns a directory with files downloaded to it.
+func newTestDirectory(t *testing.T) string {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"F1/notes.txt":  "notes",
+		"F2/report.pdf": "report",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func newTestKey(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// TestVerify checks that every entry of the archive or files that is altered, added, or removed after sealing is reported.
+func TestVerify(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		change   func(t *testing.T, files slacktest.Files, dir string)
+		expected []string // the action, source, and path of each change
+	}{
+		{
+			name:     "Unchanged",
+			change:   func(t *testing.T, files slacktest.Files, dir string) {},
+			expected: []string{},
+		},
+		{
+			name: "AlteredEntry",
+			change: func(t *testing.T, files slacktest.Files, dir string) {
+				files["teams/hello/general/2020-07-29.json"] = `[{"type": "message", "user": "U1", "text": "hellO", "ts": "1596060000.000100"}]`
+			},
+			expected: []string{"altered archive teams/hello/general/2020-07-29.json"},
+		},
+		{
+			name: "AddedEntry",
+			change: func(t *testing.T, files slacktest.Files, dir string) {
+				files["teams/hello/general/2020-07-30.json"] = `[]`
+			},
+			expected: []string{"added archive teams/hello/general/2020-07-30.json"},
+		},
+		{
+			name: "RemovedEntry",
+			change: func(t *testing.T, files slacktest.Files, dir string) {
+				files["org_users.json"] = ""
+			},
+			expected: []string{"removed archive org_users.json"},
+		},
+		{
+			name: "AlteredFile",
+			change: func(t *testing.T, files slacktest.Files, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "F1", "notes.txt"), []byte("Notes"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"altered files F1/notes.txt"},
+		},
+		{
+			name: "AddedAndRemovedFiles",
+			change: func(t *testing.T, files slacktest.Files, dir string) {
+				if err := os.Rename(filepath.Join(dir, "F2", "report.pdf"), filepath.Join(dir, "F2", "report-final.pdf")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"added files F2/report-final.pdf", "removed files F2/report.pdf"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			key := newTestKey(t)
+			files, dir := newTestFiles(), newTestDirectory(t)
+			m, err := Seal(ctx, slacktest.NewArchive(t, files), dir, key, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.change(t, files, dir)
+
+			report, err := Verify(ctx, m, key.Public().(ed25519.PublicKey), slacktest.NewArchive(t, files), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			changes := make([]string, 0, len(report.Changes))
+			for _, c := range report.Changes {
+				changes = append(changes, c.Action+" "+c.Source+" "+c.Path)
+			}
+			sort.Strings(changes)
+			if got, expected := strings.Join(changes, ","), strings.Join(test.expected, ","); got != expected {
+				t.Fatalf("expecting changes %q, but found %q", expected, got)
+			}
+			if report.Valid != (len(test.expected) == 0) {
+				t.Errorf("expecting valid of %t, but found %t", len(test.expected) == 0, report.Valid)
+			}
+			if report.Altered+report.Added+report.Removed != len(test.expected) {
+				t.Errorf("expecting %d changes to be counted, but found %d", len(test.expected), report.Altered+report.Added+report.Removed)
+			}
+			if (report.Root == m.Root) != report.Valid {
+				t.Errorf("expecting the root to change if and only if an entry changed, but found %q and %q", report.Root, m.Root)
+			}
+		})
+	}
+}
+
+// TestVerifySignature checks that a manifest is rejected if its entries, root, or created time are changed, or if it is verified with another key.
+func TestVerifySignature(t *testing.T) {
+	key := newTestKey(t)
+	for _, test := range []struct {
+		name   string
+		change func(m *Manifest) ed25519.PublicKey
+		valid  bool
+	}{
+		{
+			name:   "Valid",
+			change: func(m *Manifest) ed25519.PublicKey { return key.Public().(ed25519.PublicKey) },
+			valid:  true,
+		},
+		{
+			name: "Entry",
+			change: func(m *Manifest) ed25519.PublicKey {
+				m.Entries[0].SHA256 = strings.Repeat("0", 64)
+				return key.Public().(ed25519.PublicKey)
+			},
+		},
+		{
+			name: "RemovedEntry",
+			change: func(m *Manifest) ed25519.PublicKey {
+				m.Entries = m.Entries[1:]
+				m.Root = merkleRoot(m.Entries)
+				return key.Public().(ed25519.PublicKey)
+			},
+		},
+		{
+			name: "Created",
+			change: func(m *Manifest) ed25519.PublicKey {
+				m.Created = m.Created.Add(time.Second)
+				return key.Public().(ed25519.PublicKey)
+			},
+		},
+		{
+			name: "Key",
+			change: func(m *Manifest) ed25519.PublicKey {
+				return newTestKey(t).Public().(ed25519.PublicKey)
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := Seal(context.Background(), slacktest.NewArchive(t, newTestFiles()), "", key, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the manifest is verified as it is read, after it is written
+			b := &bytes.Buffer{}
+			if err = WriteManifest(b, m); err != nil {
+				t.Fatal(err)
+			}
+			m, err = ReadManifest(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = VerifySignature(m, test.change(m))
+			if test.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expecting an error for a changed manifest")
+			}
+		})
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	entries := []*Entry{
+		{Source: SourceArchive, Path: "a", Size: 1, SHA256: "1"},
+		{Source: SourceArchive, Path: "b", Size: 2, SHA256: "2"},
+		{Source: SourceArchive, Path: "c", Size: 3, SHA256: "3"},
+	}
+	roots := map[string]int{}
+	for i := 0; i <= len(entries); i++ {
+		roots[merkleRoot(entries[:i])] = i
+	}
+	if len(roots) != len(entries)+1 {
+		t.Fatalf("expecting a different root for each number of entries, but found %d roots", len(roots))
+	}
+}
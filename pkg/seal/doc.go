@@ -0,0 +1,18 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package seal creates and verifies signed, tamper-evident manifests of archives and downloaded files.
+//
+// Every entry in the archive and every downloaded file is hashed with SHA-256.
+// The entries are sorted and combined into a Merkle tree, and the root of the tree and the time the manifest was created are signed with an ed25519 key.
+// Any altered, added, or removed entry changes the root, so a manifest proves that an export has not changed since it was sealed.
+//
+// Keys are PEM encoded PKCS #8 private keys and PKIX public keys, such as those created by:
+//
+//	openssl genpkey -algorithm ed25519 -out seal.key
+//	openssl pkey -in seal.key -pubout -out seal.pub
+package seal
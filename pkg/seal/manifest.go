@@ -0,0 +1,85 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package seal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	ManifestVersion = 1
+)
+
+const (
+	SourceArchive = "archive" // an entry in the zip file
+	SourceFiles   = "files"   // a file downloaded with "download files"
+)
+
+// Entry is the hash of a single entry in the archive or a downloaded file.
+type Entry struct {
+	Source string `json:"source"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func (e *Entry) key() string {
+	return e.Source + "\x00" + e.Path
+}
+
+// Manifest is the signed list of hashes of the entries in an archive and the downloaded files.
+type Manifest struct {
+	Version   int       `json:"version"`
+	Created   time.Time `json:"created"`
+	Root      string    `json:"root"`       // the hex encoded root of the Merkle tree of the entries
+	PublicKey string    `json:"public_key"` // the base64 encoded ed25519 public key of the signer
+	Signature string    `json:"signature"`  // the base64 encoded ed25519 signature of the root and created time
+	Entries   []*Entry  `json:"entries"`
+}
+
+// signedMessage returns the message that is signed, which binds the root to the time the manifest was created.
+func (m *Manifest) signedMessage() []byte {
+	return []byte(fmt.Sprintf("slack-archiver seal v%d\n%s\n%s\n", m.Version, m.Root, m.Created.UTC().Format(time.RFC3339Nano)))
+}
+
+func sortEntries(entries []*Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+		return entries[i].Path < entries[j].Path
+	})
+}
+
+// WriteManifest writes the manifest as indented JSON.
+func WriteManifest(w io.Writer, m *Manifest) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(m)
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest reads a manifest written by WriteManifest.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	err := json.NewDecoder(r).Decode(m)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding manifest: %w", err)
+	}
+	if m.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d, expecting %d", m.Version, ManifestVersion)
+	}
+	return m, nil
+}
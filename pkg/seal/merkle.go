@@ -0,0 +1,66 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package seal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Leaves and interior nodes are hashed with different prefixes, as in RFC 6962, so that a leaf cannot be passed off as a node.
+const (
+	prefixLeaf = 0x00
+	prefixNode = 0x01
+)
+
+func hashLeaf(e *Entry) []byte {
+	h := sha256.New()
+	h.Write([]byte{prefixLeaf})
+	h.Write([]byte(e.Source))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Path))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(e.Size, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(e.SHA256))
+	return h.Sum(nil)
+}
+
+func hashNode(left []byte, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{prefixNode})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleRoot returns the hex encoded root of the Merkle tree of the sorted entries.
+// When a level has an odd number of nodes, the last node is promoted to the next level.
+func merkleRoot(entries []*Entry) string {
+	if len(entries) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+	level := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		level = append(level, hashLeaf(e))
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, hashNode(level[i], level[i+1]))
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
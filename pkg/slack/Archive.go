@@ -9,71 +9,140 @@ package slack
 
 import (
 	"archive/zip"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"strings"
 
+	"github.com/deptofdefense/slack-archiver/pkg/container"
 	"github.com/deptofdefense/slack-archiver/pkg/ziputil"
 )
 
+// Stdin is the name of the archive read from stdin.
+const Stdin = "-"
+
+// Decrypter decrypts archives that are encrypted at rest.
+type Decrypter interface {
+	// Encrypted returns true if the named archive is encrypted.
+	Encrypted(name string) bool
+	// Decrypt returns a reader of the plaintext of the encrypted archive read from r.
+	Decrypt(r io.Reader) (io.Reader, error)
+}
+
+// Archive is a Slack export read from a zip file, an extracted directory, or any fs.FS.
 type Archive struct {
-	name   string
-	file   *os.File
-	reader *zip.Reader
+	name    string
+	closers []io.Closer
+	fsys    fs.FS
+	index   *fileIndex
 }
 
-func (a *Archive) init() error {
-	f, err := os.Open(a.name)
-	if err != nil {
-		return fmt.Errorf("error reading source %q: %w", a.name, err)
+// initFS lists the files in the file system and indexes them by name.
+// The entries of a zip file are listed from its central directory, so every entry is included.
+func (a *Archive) initFS(fsys fs.FS) error {
+	a.fsys = fsys
+	if zr, ok := fsys.(*zip.Reader); ok {
+		a.index = newFileIndex(listZipFiles(zr))
+		return nil
 	}
-
-	fi, err := f.Stat()
+	files, err := listFiles(fsys)
 	if err != nil {
-		_ = f.Close()
-		return fmt.Errorf("error stating source %q: %w", a.name, err)
+		return fmt.Errorf("error listing files in source %q: %w", a.name, err)
 	}
-
-	zr, err := zip.NewReader(f, fi.Size())
-	if err != nil {
-		_ = f.Close()
-		return fmt.Errorf("error opening zip reader for source %q: %w", a.name, err)
-	}
-	a.file = f
-	a.reader = zr
+	a.index = newFileIndex(files)
 	return nil
 }
 
 func (a *Archive) UnmarshalFile(name string, v interface{}) error {
-	err := ziputil.UnmarshalFile(a.reader, name, v)
+	err := ziputil.UnmarshalFile(a.fsys, name, v)
 	if err != nil {
 		return fmt.Errorf("error unmarshaling file from %q: %w", a.name, err)
 	}
 	return nil
 }
 
+// ReadRecords reads the named file as a JSON array and returns the contents of the file
+// along with the original bytes of each element, so records can be written without losing unknown fields.
+func (a *Archive) ReadRecords(name string) ([]byte, []json.RawMessage, error) {
+	data, records, err := ziputil.ReadRecords(a.fsys, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading records from %q: %w", a.name, err)
+	}
+	return data, records, nil
+}
+
+// Close closes the source and releases anything spooled while opening it.
 func (a *Archive) Close() error {
-	err := a.file.Close()
+	var err error
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if closeError := a.closers[i].Close(); closeError != nil && err == nil {
+			err = closeError
+		}
+	}
+	a.closers = nil
 	if err != nil {
 		return fmt.Errorf("error closing source %q: %w", a.name, err)
 	}
 	return nil
 }
 
-func (a *Archive) GetFiles(prefix string) []*zip.File {
-	if len(prefix) == 0 {
-		return a.reader.File
+// GetFiles returns the files and directories in the archive whose names start with the prefix, sorted by name.
+// The returned slice is shared with the index of the archive and must not be modified.
+func (a *Archive) GetFiles(prefix string) []*ArchiveFile {
+	return a.index.prefix(prefix)
+}
+
+// GetFile returns the file or directory in the archive with the given name.
+func (a *Archive) GetFile(name string) (*ArchiveFile, bool) {
+	return a.index.exact(name)
+}
+
+// GetDayFiles returns the day files directly under the prefix of a conversation with dates from since to until, inclusive.
+// The dates are formatted as YYYY-MM-DD, and either can be blank for an open range.
+// Since day files are named by date, the range is found with a binary search.
+func (a *Archive) GetDayFiles(prefix string, since string, until string) []*ArchiveFile {
+	var candidates []*ArchiveFile
+	if len(until) == 0 {
+		candidates = a.index.between(prefix+since, prefix+"\xff")
+	} else {
+		candidates = a.index.between(prefix+since, prefix+until+".json")
 	}
-	files := make([]*zip.File, 0)
-	for _, f := range a.reader.File {
-		if strings.HasPrefix(f.Name, prefix) {
+	files := make([]*ArchiveFile, 0, len(candidates))
+	for _, f := range candidates {
+		if !strings.HasPrefix(f.Name, prefix) {
+			continue
+		}
+		rest := f.Name[len(prefix):]
+		if strings.HasSuffix(rest, ".json") && !strings.Contains(rest, "/") {
 			files = append(files, f)
 		}
 	}
 	return files
 }
 
+// GetDirectories returns the names of the directories directly under the prefix, which must be blank or end with a slash.
+func (a *Archive) GetDirectories(prefix string) []string {
+	return a.index.directories(prefix)
+}
+
 func (a *Archive) GetEnterpriseGrid() (*EnterpriseGrid, error) {
+	return a.GetEnterpriseGridContext(context.Background())
+}
+
+// GetEnterpriseGridContext reads the users and conversations of the enterprise grid,
+// and stops with the error of the context if it is canceled.
+// Returns ErrNotEnterpriseGrid if the archive does not include the organization users of an enterprise grid.
+func (a *Archive) GetEnterpriseGridContext(ctx context.Context) (*EnterpriseGrid, error) {
+
+	if _, ok := a.GetFile("org_users.json"); !ok {
+		if _, found := a.GetFile("channels.json"); found {
+			return nil, fmt.Errorf("%w: found channels.json of a workspace export instead of org_users.json", ErrNotEnterpriseGrid)
+		}
+		return nil, fmt.Errorf("%w: org_users.json not found", ErrNotEnterpriseGrid)
+	}
 
 	enterpriseGrid := &EnterpriseGrid{
 		Archive: a,
@@ -126,46 +195,178 @@ func (a *Archive) GetEnterpriseGrid() (*EnterpriseGrid, error) {
 
 	// Teams
 
+	if err = ctx.Err(); err != nil {
+		return nil, fmt.Errorf("error reading enterprise grid from %q: %w", a.name, err)
+	}
+
 	teams := []*Team{}
 
-	for _, f := range a.GetFiles("teams/") {
-		if f.Name != "teams/" && strings.HasSuffix(f.Name, "/") && strings.Count(f.Name, "/") == 2 {
-			name := f.Name[len("teams/") : len("teams/")+strings.Index(f.Name[len("teams/"):], "/")]
-			teamChannels := []*Channel{}
-			err = a.UnmarshalFile(fmt.Sprintf("teams/%s/channels.json", name), &teamChannels)
-			if err != nil {
-				return nil, fmt.Errorf("error unmarshaling channels for team %q from %q: %w", name, a.name, err)
-			}
-			teamGroups := []*Group{}
-			err = a.UnmarshalFile(fmt.Sprintf("teams/%s/groups.json", name), &teamGroups)
-			if err != nil {
-				return nil, fmt.Errorf("error unmarshaling groups for team %q from %q: %w", name, a.name, err)
-			}
-			teamUsers := []*User{}
-			err = a.UnmarshalFile(fmt.Sprintf("teams/%s/users.json", name), &teamUsers)
-			if err != nil {
-				return nil, fmt.Errorf("error unmarshaling users for team %q from %q: %w", name, a.name, err)
-			}
-			teams = append(teams, &Team{
-				Name:     name,
-				Channels: teamChannels,
-				Groups:   teamGroups,
-				Users:    teamUsers,
-			})
+	for _, name := range a.GetDirectories("teams/") {
+		if err = ctx.Err(); err != nil {
+			return nil, fmt.Errorf("error reading teams from %q: %w", a.name, err)
+		}
+		teamChannels := []*Channel{}
+		err = a.UnmarshalFile(fmt.Sprintf("teams/%s/channels.json", name), &teamChannels)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling channels for team %q from %q: %w", name, a.name, err)
+		}
+		teamGroups := []*Group{}
+		err = a.UnmarshalFile(fmt.Sprintf("teams/%s/groups.json", name), &teamGroups)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling groups for team %q from %q: %w", name, a.name, err)
 		}
+		teamUsers := []*User{}
+		err = a.UnmarshalFile(fmt.Sprintf("teams/%s/users.json", name), &teamUsers)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling users for team %q from %q: %w", name, a.name, err)
+		}
+		teams = append(teams, &Team{
+			Name:     name,
+			Channels: teamChannels,
+			Groups:   teamGroups,
+			Users:    teamUsers,
+		})
 	}
 	enterpriseGrid.Teams = teams
 
 	return enterpriseGrid, nil
 }
 
-func OpenArchive(name string) (*Archive, error) {
+// NewArchive returns an archive that reads from any file system, e.g., a *zip.Reader or os.DirFS.
+// The name is only used in error messages.  The closers, e.g., of the file the file system is read from,
+// are closed in reverse order when the archive is closed, but not if an error is returned.
+func NewArchive(name string, fsys fs.FS, closers ...io.Closer) (*Archive, error) {
 	a := &Archive{
-		name: name,
+		name:    name,
+		closers: closers,
 	}
-	err := a.init()
+	err := a.initFS(fsys)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing archive: %w", err)
 	}
 	return a, nil
 }
+
+// OpenArchive opens the Slack export at the given path, or reads it from stdin if the path is "-".
+// The export can be a zip file, an extracted directory, or a zip or tar file that holds the export.
+// If one of the decrypters reports the archive as encrypted, then the archive is decrypted in memory.
+func OpenArchive(name string, decrypters ...Decrypter) (*Archive, error) {
+	if name == Stdin {
+		fsys, closer, err := container.OpenStream(os.Stdin, container.SpoolTempFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening source from stdin: %w", err)
+		}
+		return newArchive(name, fsys, closer)
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("error stating source %q: %w", name, err)
+	}
+
+	if fi.IsDir() {
+		return NewArchive(name, os.DirFS(name))
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("error opening source %q: %w", name, err)
+	}
+	return OpenArchiveReader(name, f, fi.Size(), f, decrypters...)
+}
+
+// OpenArchiveFS opens the Slack export at the path in the file system, which is "." if the file system is the extracted export.
+// As with OpenArchive, the export can be a directory, a zip file, or a zip or tar file that holds the export,
+// and is decrypted in memory if one of the decrypters reports it as encrypted.
+func OpenArchiveFS(fsys fs.FS, name string, decrypters ...Decrypter) (*Archive, error) {
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("error stating source %q: %w", name, err)
+	}
+
+	if fi.IsDir() {
+		sub, err := fs.Sub(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("error opening source %q: %w", name, err)
+		}
+		return NewArchive(name, sub)
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("error opening source %q: %w", name, err)
+	}
+	if r, ok := f.(io.ReaderAt); ok {
+		return OpenArchiveReader(name, r, fi.Size(), f, decrypters...)
+	}
+
+	// files that do not support random access are read as a stream
+	defer func() { _ = f.Close() }()
+	for _, d := range decrypters {
+		if d.Encrypted(name) {
+			return openEncrypted(name, f, d)
+		}
+	}
+	archiveFS, closer, err := container.OpenStream(f, container.SpoolTempFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening source %q: %w", name, err)
+	}
+	return newArchive(name, archiveFS, closer)
+}
+
+// OpenArchiveReader opens the named Slack export read from r, e.g., a local file or a blob in object storage.
+// Zip files are read in place with random access.  The closer, if not nil, is closed when the archive is closed,
+// or once the archive is decrypted into memory, or if the archive cannot be opened.
+// If one of the decrypters reports the archive as encrypted, then the archive is decrypted in memory.
+func OpenArchiveReader(name string, r io.ReaderAt, size int64, closer io.Closer, decrypters ...Decrypter) (*Archive, error) {
+	closers := make([]io.Closer, 0, 2)
+	if closer != nil {
+		closers = append(closers, closer)
+	}
+
+	for _, d := range decrypters {
+		if d.Encrypted(name) {
+			defer closeAll(closers)
+			return openEncrypted(name, io.NewSectionReader(r, 0, size), d)
+		}
+	}
+
+	fsys, c, err := container.Open(r, size, container.SpoolTempFile)
+	if err != nil {
+		closeAll(closers)
+		return nil, fmt.Errorf("error opening source %q: %w", name, err)
+	}
+	return newArchive(name, fsys, append(closers, c)...)
+}
+
+// openEncrypted decrypts the archive into memory, so that the plaintext is never written to disk.
+func openEncrypted(name string, r io.Reader, d Decrypter) (*Archive, error) {
+	plaintext, err := d.Decrypt(r)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting source %q: %w", name, err)
+	}
+
+	fsys, closer, err := container.OpenStream(plaintext, container.SpoolMemory)
+	if err != nil {
+		return nil, fmt.Errorf("error opening decrypted source %q: %w", name, err)
+	}
+	return newArchive(name, fsys, closer)
+}
+
+// newArchive returns the archive for the file system, which closes the closers when it is closed.
+// If the archive cannot be initialized, then the closers are closed.
+func newArchive(name string, fsys fs.FS, closers ...io.Closer) (*Archive, error) {
+	a, err := NewArchive(name, fsys, closers...)
+	if err != nil {
+		closeAll(closers)
+		return nil, err
+	}
+	return a, nil
+}
+
+// closeAll closes the closers in reverse order and ignores any errors, e.g., when an archive cannot be opened.
+func closeAll(closers []io.Closer) {
+	for i := len(closers) - 1; i >= 0; i-- {
+		_ = closers[i].Close()
+	}
+}
@@ -0,0 +1,86 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// ArchiveFile is an entry in an archive.
+// As in a zip file, the names of directories end with a slash.
+type ArchiveFile struct {
+	Name string
+	Size int64
+	open func() (io.ReadCloser, error)
+}
+
+// IsDir returns true if the entry is a directory.
+func (f *ArchiveFile) IsDir() bool {
+	return strings.HasSuffix(f.Name, "/")
+}
+
+// Open opens the entry for reading.
+func (f *ArchiveFile) Open() (io.ReadCloser, error) {
+	return f.open()
+}
+
+// listZipFiles returns every entry in the zip file in the order of the central directory,
+// including entries with names that are not valid in an fs.FS.
+func listZipFiles(zr *zip.Reader) []*ArchiveFile {
+	files := make([]*ArchiveFile, 0, len(zr.File))
+	for _, f := range zr.File {
+		files = append(files, &ArchiveFile{
+			Name: f.Name,
+			Size: int64(f.UncompressedSize64),
+			open: f.Open,
+		})
+	}
+	return files
+}
+
+// listFiles returns every file and directory in the file system in lexical order.
+func listFiles(fsys fs.FS) ([]*ArchiveFile, error) {
+	files := make([]*ArchiveFile, 0)
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		if d.IsDir() {
+			files = append(files, &ArchiveFile{
+				Name: path + "/",
+				open: func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("")), nil
+				},
+			})
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("error stating file %q: %w", path, err)
+		}
+		files = append(files, &ArchiveFile{
+			Name: path,
+			Size: fi.Size(),
+			open: func() (io.ReadCloser, error) {
+				return fsys.Open(path)
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing files: %w", err)
+	}
+	return files, nil
+}
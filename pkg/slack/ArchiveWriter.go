@@ -0,0 +1,256 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ArchiveWriter writes a Slack archive in the same layout read by OpenArchive.
+type ArchiveWriter struct {
+	name        string
+	file        io.WriteCloser
+	writer      *zip.Writer
+	directories map[string]struct{}
+}
+
+// createDirectories adds entries for the parent directories of the named file,
+// since teams are discovered using the directory entries in the archive.
+func (a *ArchiveWriter) createDirectories(name string) error {
+	for i := range name {
+		if name[i] != '/' {
+			continue
+		}
+		dir := name[:i+1]
+		if _, ok := a.directories[dir]; ok {
+			continue
+		}
+		_, err := a.writer.Create(dir)
+		if err != nil {
+			return fmt.Errorf("error creating directory %q in %q: %w", dir, a.name, err)
+		}
+		a.directories[dir] = struct{}{}
+	}
+	return nil
+}
+
+// WriteFile writes the data as is to a new file in the archive.
+func (a *ArchiveWriter) WriteFile(name string, data []byte) error {
+	err := a.createDirectories(name)
+	if err != nil {
+		return err
+	}
+	fw, err := a.writer.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating file %q in %q: %w", name, a.name, err)
+	}
+	_, err = fw.Write(data)
+	if err != nil {
+		return fmt.Errorf("error writing file %q to %q: %w", name, a.name, err)
+	}
+	return nil
+}
+
+// WriteRecords writes the records to a new file as a JSON array in the layout of the files exported by Slack.
+// Each record is written as is, so the fields that are not modeled by this package are kept.
+func (a *ArchiveWriter) WriteRecords(name string, records []json.RawMessage) error {
+	buf := &bytes.Buffer{}
+	buf.WriteString("[")
+	for i, r := range records {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n    ")
+		buf.Write(r)
+	}
+	if len(records) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]")
+	return a.WriteFile(name, buf.Bytes())
+}
+
+// copyRecords reads the records in the source file, transforms them with fn, and writes them to the destination file.
+// If fn returns the records unchanged, then the source file is copied as is.  Files left without records are not written.
+func (a *ArchiveWriter) copyRecords(
+	e *EnterpriseGrid,
+	src string,
+	dst string,
+	fn func(records []json.RawMessage) ([]json.RawMessage, error),
+) (bool, error) {
+	data, records, err := e.Archive.ReadRecords(src)
+	if err != nil {
+		return false, err
+	}
+	transformed, err := fn(append(make([]json.RawMessage, 0, len(records)), records...))
+	if err != nil {
+		return false, fmt.Errorf("error transforming records from file %q: %w", src, err)
+	}
+	if equalRecords(records, transformed) {
+		return true, a.WriteFile(dst, data)
+	}
+	if len(transformed) == 0 {
+		return false, nil
+	}
+	return true, a.WriteRecords(dst, transformed)
+}
+
+// CopyFile reads the records in a file of the enterprise grid, transforms them with fn, and writes them to the same file in the archive.
+// If fn returns the records unchanged, then the file is copied as is.  Unlike day files, files left without records are written as empty arrays.
+func (a *ArchiveWriter) CopyFile(e *EnterpriseGrid, name string, fn func(records []json.RawMessage) ([]json.RawMessage, error)) error {
+	written, err := a.copyRecords(e, name, name, fn)
+	if err != nil {
+		return err
+	}
+	if !written {
+		return a.WriteRecords(name, nil)
+	}
+	return nil
+}
+
+func equalRecords(a []json.RawMessage, b []json.RawMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteRawDayFile writes the original bytes of the messages for a conversation on a day formatted as YYYY-MM-DD.
+func (a *ArchiveWriter) WriteRawDayFile(c *Conversation, day string, messages []json.RawMessage) error {
+	name, err := dayFileName(c, day)
+	if err != nil {
+		return err
+	}
+	err = a.WriteRecords(name, messages)
+	if err != nil {
+		return fmt.Errorf("error writing messages for %s %q on %q: %w", c.Type, c.Name, day, err)
+	}
+	return nil
+}
+
+func dayFileName(c *Conversation, day string) (string, error) {
+	prefix, err := c.ResolvePrefix()
+	if err != nil {
+		return "", err
+	}
+	if _, err = ParseDayFileName(day + ".json"); err != nil {
+		return "", fmt.Errorf("error writing messages for %s %q: %w", c.Type, c.Name, err)
+	}
+	return fmt.Sprintf("%s%s.json", prefix, day), nil
+}
+
+// WriteRawMessages sorts the messages by timestamp and writes their original bytes to day files by their date in UTC.
+// Only the timestamps of the messages are decoded.
+func (a *ArchiveWriter) WriteRawMessages(c *Conversation, messages []json.RawMessage) error {
+	type timestamped struct {
+		timestamp string
+		message   json.RawMessage
+	}
+	days := map[string][]*timestamped{}
+	for _, m := range messages {
+		key, err := UnmarshalRecordKey(m)
+		if err != nil {
+			return fmt.Errorf("error reading message in %s %q: %w", c.Type, c.Name, err)
+		}
+		t, err := ParseTimestamp(key.Timestamp)
+		if err != nil {
+			return fmt.Errorf("error parsing timestamp of message in %s %q: %w", c.Type, c.Name, err)
+		}
+		day := t.Format(DayFileDateFormat)
+		days[day] = append(days[day], &timestamped{timestamp: key.Timestamp, message: m})
+	}
+	names := make([]string, 0, len(days))
+	for day := range days {
+		names = append(names, day)
+	}
+	sort.Strings(names)
+	for _, day := range names {
+		dayMessages := days[day]
+		sort.SliceStable(dayMessages, func(i, j int) bool {
+			return CompareTimestamps(dayMessages[i].timestamp, dayMessages[j].timestamp) < 0
+		})
+		records := make([]json.RawMessage, 0, len(dayMessages))
+		for _, m := range dayMessages {
+			records = append(records, m.message)
+		}
+		err := a.WriteRawDayFile(c, day, records)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyRawConversation reads each day file of the source conversation, transforms the original bytes of its messages with fn,
+// and writes the result to the same day in the destination conversation.  Day files left unchanged are copied as is,
+// and day files left without messages are not written.  Copying stops with the error of the context if it is canceled.
+func (a *ArchiveWriter) CopyRawConversation(
+	ctx context.Context,
+	e *EnterpriseGrid,
+	src *Conversation,
+	dst *Conversation,
+	fn func(day string, messages []json.RawMessage) ([]json.RawMessage, error),
+) error {
+	dayFiles, err := e.GetDayFiles(src)
+	if err != nil {
+		return err
+	}
+	for _, f := range dayFiles {
+		if err = ctx.Err(); err != nil {
+			return fmt.Errorf("error copying messages for %s %q: %w", src.Type, src.Name, err)
+		}
+		day := f.Day
+		var name string
+		name, err = dayFileName(dst, day)
+		if err != nil {
+			return err
+		}
+		_, err = a.copyRecords(e, f.Name, name, func(messages []json.RawMessage) ([]json.RawMessage, error) {
+			return fn(day, messages)
+		})
+		if err != nil {
+			return fmt.Errorf("error copying messages from file %q: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func (a *ArchiveWriter) Close() error {
+	err := a.writer.Close()
+	if err != nil {
+		_ = a.file.Close()
+		return fmt.Errorf("error closing zip writer for %q: %w", a.name, err)
+	}
+	err = a.file.Close()
+	if err != nil {
+		return fmt.Errorf("error closing destination %q: %w", a.name, err)
+	}
+	return nil
+}
+
+// NewArchiveWriter returns a new archive writer that writes to w, e.g., an encrypted file.
+// The name is only used in error messages.  Closing the archive writer closes w.
+func NewArchiveWriter(name string, w io.WriteCloser) *ArchiveWriter {
+	return &ArchiveWriter{
+		name:        name,
+		file:        w,
+		writer:      zip.NewWriter(w),
+		directories: map[string]struct{}{},
+	}
+}
@@ -0,0 +1,106 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestFiles returns the files of an enterprise grid export with a direct message and a team with a channel.
+func newTestFiles() slacktest.Files {
+	return slacktest.Files{
+		"org_users.json":            `[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}]`,
+		"dms.json":                  `[{"id": "D1", "created": 1595980000, "members": ["U1", "U2"]}]`,
+		"D1/2020-07-29.json":        `[{"type": "message", "user": "U1", "text": "hi", "ts": "1596060000.000100"}]`,
+		"teams/hello/channels.json": `[{"id": "C1", "name": "general", "created": 1595980000, "creator": "U1", "members": ["U1", "U2"]}]`,
+		"teams/hello/users.json":    `[{"id": "U1", "name": "alice"}]`,
+		"teams/hello/general/2020-07-29.json": `[
+			{"type": "message", "user": "U1", "text": "first", "ts": "1596060000.000100"},
+			{"type": "message", "user": "U2", "text": "second", "ts": "1596060001.000100"}
+		]`,
+		"teams/hello/general/2020-07-28.json": `[{"type": "message", "user": "U1", "text": "earlier", "ts": "1595980000.000100"}]`,
+		"teams/hello/general/notes.txt":       `not a day file`,
+	}
+}
+
+func TestArchiveGetFiles(t *testing.T) {
+	a := slacktest.NewArchive(t, newTestFiles())
+	names := make([]string, 0)
+	for _, f := range a.GetFiles("teams/hello/general/") {
+		names = append(names, f.Name)
+	}
+	expected := "teams/hello/general/,teams/hello/general/2020-07-28.json,teams/hello/general/2020-07-29.json,teams/hello/general/notes.txt"
+	if got := strings.Join(names, ","); got != expected {
+		t.Fatalf("expecting files %q, but found %q", expected, got)
+	}
+	if f, ok := a.GetFile("teams/hello/general/"); !ok || !f.IsDir() {
+		t.Fatal("expecting the directory of the channel")
+	}
+	if got := strings.Join(a.GetDirectories(""), ","); got != "D1,teams" {
+		t.Fatalf("unexpected directories %q", got)
+	}
+}
+
+func TestArchiveGetEnterpriseGrid(t *testing.T) {
+	e := slacktest.NewGrid(t, newTestFiles())
+	if len(e.OrganizationUsers) != 2 || len(e.DirectMessages) != 1 {
+		t.Fatalf("expecting 2 users and 1 direct message, but found %d and %d", len(e.OrganizationUsers), len(e.DirectMessages))
+	}
+	teams := e.GetTeams()
+	if len(teams) != 1 || teams[0].Name != "hello" || len(teams[0].Channels) != 1 || len(teams[0].Users) != 1 {
+		t.Fatalf("unexpected teams %+v", teams)
+	}
+
+	conversations := map[string]*slack.Conversation{}
+	for _, c := range e.GetConversations() {
+		conversations[c.ID] = c
+	}
+	for _, test := range []struct {
+		id       string
+		prefix   string
+		messages []string
+	}{
+		{id: "D1", prefix: "D1/", messages: []string{"hi"}},
+		{id: "C1", prefix: "teams/hello/general/", messages: []string{"earlier", "first", "second"}},
+	} {
+		c, ok := conversations[test.id]
+		if !ok {
+			t.Fatalf("conversation %q not found", test.id)
+		}
+		if c.Prefix != test.prefix {
+			t.Fatalf("expecting prefix %q for conversation %q, but found %q", test.prefix, test.id, c.Prefix)
+		}
+		messages, err := e.GetConversationMessages(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts := make([]string, 0, len(messages))
+		for _, m := range messages {
+			texts = append(texts, m.Text)
+		}
+		if got, expected := strings.Join(texts, ","), strings.Join(test.messages, ","); got != expected {
+			t.Fatalf("expecting messages %q in conversation %q, but found %q", expected, test.id, got)
+		}
+	}
+}
+
+func TestArchiveNotEnterpriseGrid(t *testing.T) {
+	a := slacktest.NewArchive(t, slacktest.Files{
+		"org_users.json": "",
+		"channels.json":  `[]`,
+		"users.json":     `[]`,
+	})
+	if _, err := a.GetEnterpriseGrid(); !errors.Is(err, slack.ErrNotEnterpriseGrid) {
+		t.Fatalf("expecting ErrNotEnterpriseGrid, but found %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// checkTestArchive checks that the archive is the export of the test files.
+func checkTestArchive(t *testing.T, a *slack.Archive) {
+	t.Helper()
+	defer func() { _ = a.Close() }()
+	e, err := a.GetEnterpriseGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.OrganizationUsers) != 2 || len(e.GetConversations()) != 2 {
+		t.Fatalf("expecting 2 users and 2 conversations, but found %d and %d", len(e.OrganizationUsers), len(e.GetConversations()))
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		location func(t *testing.T) string
+	}{
+		{name: "Directory", location: func(t *testing.T) string { return slacktest.WriteDir(t, newTestFiles()) }},
+		{
+			name: "Zip",
+			location: func(t *testing.T) string {
+				return slacktest.WriteFile(t, "export.zip", slacktest.Zip(t, newTestFiles()))
+			},
+		},
+		{
+			name: "Encrypted",
+			location: func(t *testing.T) string {
+				return slacktest.WriteFile(t, "export.zip.xor", slacktest.Encrypt(slacktest.Zip(t, newTestFiles())))
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := slack.OpenArchive(test.location(t), &slacktest.Decrypter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTestArchive(t, a)
+		})
+	}
+}
+
+func TestOpenArchiveFS(t *testing.T) {
+	fsys := slacktest.MapFS(newTestFiles())
+	fsys["archives/export.zip"] = &fstest.MapFile{Data: slacktest.Zip(t, newTestFiles())}
+	fsys["archives/export.zip.xor"] = &fstest.MapFile{Data: slacktest.Encrypt(slacktest.Zip(t, newTestFiles()))}
+	for _, name := range []string{".", "archives/export.zip", "archives/export.zip.xor"} {
+		t.Run(name, func(t *testing.T) {
+			a, err := slack.OpenArchiveFS(fsys, name, &slacktest.Decrypter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTestArchive(t, a)
+		})
+	}
+}
+
+func TestOpenArchiveInvalid(t *testing.T) {
+	encrypted := slacktest.WriteFile(t, "export.zip.xor", slacktest.Encrypt(slacktest.Zip(t, newTestFiles())))
+	errDecrypt := errors.New("wrong key")
+	for _, test := range []struct {
+		name       string
+		location   string
+		decrypters []slack.Decrypter
+		err        error
+	}{
+		{name: "Missing", location: filepath.Join(t.TempDir(), "missing.zip")},
+		{name: "NotAnArchive", location: slacktest.WriteFile(t, "export.zip", []byte("hello"))},
+		// without a decrypter the encrypted archive is opened as is
+		{name: "EncryptedWithoutDecrypter", location: encrypted},
+		{name: "DecryptError", location: encrypted, decrypters: []slack.Decrypter{&slacktest.Decrypter{Err: errDecrypt}}, err: errDecrypt},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := slack.OpenArchive(test.location, test.decrypters...)
+			if err == nil {
+				t.Fatal("expecting an error for an invalid source")
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("expecting error %v, but found %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ConversationType string
+
+const (
+	ConversationTypeChannel                  ConversationType = "channel"
+	ConversationTypeGroup                    ConversationType = "group"
+	ConversationTypeDirectMessage            ConversationType = "dm"
+	ConversationTypeMultiPartyInstantMessage ConversationType = "mpim"
+)
+
+// Conversation is a channel, group, direct message, or multiparty instant message
+// along with the prefix of its day files in the archive.
+// The prefix is blank if the conversation cannot be stored at a safe path, see ConversationPrefix.
+type Conversation struct {
+	Type       ConversationType `json:"type"`
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Team       string           `json:"team,omitempty"` // empty for conversations at the root of the archive
+	Created    int              `json:"created"`
+	Creator    string           `json:"creator,omitempty"`
+	IsArchived bool             `json:"is_archived,omitempty"`
+	Members    []string         `json:"members"`
+	Topic      Topic            `json:"topic"`
+	Purpose    Purpose          `json:"purpose"`
+	Prefix     string           `json:"prefix"`
+}
+
+func (c *Conversation) IsTeamConversation() bool {
+	return len(c.Team) > 0
+}
+
+// ResolvePrefix returns the prefix of the day files of the conversation in the archive.
+// Returns an error that matches ErrUnsafePath if the conversation cannot be stored at a safe path.
+func (c *Conversation) ResolvePrefix() (string, error) {
+	prefix, err := ConversationPrefix(c.Type, c.Team, c.ID, c.Name)
+	if err != nil {
+		return "", fmt.Errorf("error resolving path of %s %q: %w: %w", c.Type, c.Name, ErrUnsafePath, err)
+	}
+	return prefix, nil
+}
+
+// reservedRootNames are the names at the root of the archive that cannot be used by a conversation.
+var reservedRootNames = map[string]struct{}{
+	"teams":                 {},
+	"dms.json":              {},
+	"org_users.json":        {},
+	"mpims.json":            {},
+	"groups.json":           {},
+	"integration_logs.json": {},
+}
+
+// reservedTeamNames are the names in the directory of a team that cannot be used by a conversation.
+var reservedTeamNames = map[string]struct{}{
+	"channels.json": {},
+	"groups.json":   {},
+	"users.json":    {},
+}
+
+// validatePathSegment returns an error if the name cannot be used as a single directory in the archive.
+func validatePathSegment(name string) error {
+	if len(name) == 0 {
+		return errors.New("name is blank")
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("name %q is a relative path", name)
+	}
+	if strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("name %q contains a path separator or null character", name)
+	}
+	return nil
+}
+
+// ConversationPrefix returns the prefix of the day files of a conversation in the archive.
+// Channels and groups are stored by name in the directory of their team, i.e., "teams/<team>/<name>/".
+// Multiparty instant messages are stored by name and direct messages are stored by id at the root of the archive.
+// Returns an error if a name is not a single path segment or collides with the other files in the archive.
+func ConversationPrefix(conversationType ConversationType, team string, id string, name string) (string, error) {
+	switch conversationType {
+	case ConversationTypeChannel, ConversationTypeGroup:
+		if err := validatePathSegment(team); err != nil {
+			return "", fmt.Errorf("invalid team: %w", err)
+		}
+		if err := validatePathSegment(name); err != nil {
+			return "", fmt.Errorf("invalid %s: %w", conversationType, err)
+		}
+		if _, ok := reservedTeamNames[name]; ok {
+			return "", fmt.Errorf("invalid %s: name %q is reserved", conversationType, name)
+		}
+		return fmt.Sprintf("teams/%s/%s/", team, name), nil
+	case ConversationTypeMultiPartyInstantMessage, ConversationTypeDirectMessage:
+		dir := name
+		if conversationType == ConversationTypeDirectMessage {
+			dir = id
+		}
+		if err := validatePathSegment(dir); err != nil {
+			return "", fmt.Errorf("invalid %s: %w", conversationType, err)
+		}
+		if _, ok := reservedRootNames[dir]; ok {
+			return "", fmt.Errorf("invalid %s: name %q is reserved", conversationType, dir)
+		}
+		return dir + "/", nil
+	}
+	return "", fmt.Errorf("unknown conversation type %q", conversationType)
+}
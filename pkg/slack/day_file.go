@@ -0,0 +1,61 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DayFileDateFormat is the layout of the date in the names of day files.
+const DayFileDateFormat = "2006-01-02"
+
+// DayFile is the file of messages sent in a conversation on a single day in UTC, named YYYY-MM-DD.json.
+type DayFile struct {
+	Name string    // the path of the file in the archive
+	Day  string    // the day formatted as YYYY-MM-DD
+	Date time.Time // the start of the day in UTC
+	Size int64     // the uncompressed size of the file
+}
+
+// ParseDayFileName parses the date from the base name of a day file, e.g., "2020-07-29.json".
+func ParseDayFileName(name string) (time.Time, error) {
+	if len(name) != len(DayFileDateFormat)+len(".json") || !strings.HasSuffix(name, ".json") {
+		return time.Time{}, fmt.Errorf("day file name %q does not match YYYY-MM-DD.json", name)
+	}
+	date, err := time.Parse(DayFileDateFormat, strings.TrimSuffix(name, ".json"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing date from day file name %q: %w", name, err)
+	}
+	return date, nil
+}
+
+// IsDayFileName returns true if the base name is a valid day file name.
+func IsDayFileName(name string) bool {
+	_, err := ParseDayFileName(name)
+	return err == nil
+}
+
+// newDayFiles returns the files with valid day file names directly under the prefix, which are already sorted by date.
+func newDayFiles(prefix string, files []*ArchiveFile) []*DayFile {
+	dayFiles := make([]*DayFile, 0, len(files))
+	for _, f := range files {
+		date, err := ParseDayFileName(f.Name[len(prefix):])
+		if err != nil {
+			continue
+		}
+		dayFiles = append(dayFiles, &DayFile{
+			Name: f.Name,
+			Day:  date.Format(DayFileDateFormat),
+			Date: date,
+			Size: f.Size,
+		})
+	}
+	return dayFiles
+}
@@ -8,8 +8,10 @@
 package slack
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type EnterpriseGrid struct {
@@ -42,6 +44,11 @@ func (e *EnterpriseGrid) GetTeams() []*Team {
 	return e.Teams
 }
 
+// GetMessages returns the messages in every file under the prefix.
+// It is a convenience for reading a few files and is not used by the commands:
+// it stops at the first file that cannot be read rather than skipping it with an ErrorCollector,
+// and it does not check that the files are day files.
+// Use GetConversationMessages to read only the day files of a conversation, or WalkConversations with WalkOptions.OnError to skip unreadable files.
 func (e *EnterpriseGrid) GetMessages(prefix string) ([]*Message, error) {
 	messages := []*Message{}
 	for _, f := range e.Archive.GetFiles(prefix) {
@@ -56,3 +63,167 @@ func (e *EnterpriseGrid) GetMessages(prefix string) ([]*Message, error) {
 	}
 	return messages, nil
 }
+
+// GetConversations returns the multiparty instant messages, direct messages,
+// and the channels and groups for each team in the enterprise grid.
+func (e *EnterpriseGrid) GetConversations() []*Conversation {
+	conversations := make([]*Conversation, 0)
+	for _, mpim := range e.MultiPartyInstantMessages {
+		conversations = append(conversations, &Conversation{
+			Type:       ConversationTypeMultiPartyInstantMessage,
+			ID:         mpim.ID,
+			Name:       mpim.Name,
+			Created:    mpim.Created,
+			Creator:    mpim.Creator,
+			IsArchived: mpim.IsArchived,
+			Members:    mpim.Members,
+			Topic:      mpim.Topic,
+			Purpose:    mpim.Purpose,
+		})
+	}
+	for _, dm := range e.DirectMessages {
+		conversations = append(conversations, &Conversation{
+			Type:    ConversationTypeDirectMessage,
+			ID:      dm.ID,
+			Name:    dm.ID,
+			Created: dm.Created,
+			Members: dm.Members,
+		})
+	}
+	for _, t := range e.Teams {
+		for _, c := range t.Channels {
+			conversations = append(conversations, &Conversation{
+				Type:       ConversationTypeChannel,
+				ID:         c.ID,
+				Name:       c.Name,
+				Team:       t.Name,
+				Created:    c.Created,
+				Creator:    c.Creator,
+				IsArchived: c.IsArchived,
+				Members:    c.Members,
+				Topic:      c.Topic,
+				Purpose:    c.Purpose,
+			})
+		}
+		for _, g := range t.Groups {
+			conversations = append(conversations, &Conversation{
+				Type:       ConversationTypeGroup,
+				ID:         g.ID,
+				Name:       g.Name,
+				Team:       t.Name,
+				Created:    g.Created,
+				Creator:    g.Creator,
+				IsArchived: g.IsArchived,
+				Members:    g.Members,
+				Topic:      g.Topic,
+				Purpose:    g.Purpose,
+			})
+		}
+	}
+	for _, c := range conversations {
+		// conversations that cannot be stored at a safe path are left without a prefix
+		c.Prefix, _ = c.ResolvePrefix()
+	}
+	return conversations
+}
+
+// GetDayFiles returns the day files of the conversation sorted by date.
+// Files in the directory of the conversation that are not named YYYY-MM-DD.json are not included.
+func (e *EnterpriseGrid) GetDayFiles(c *Conversation) ([]*DayFile, error) {
+	return e.GetDayFilesBetween(c, time.Time{}, time.Time{})
+}
+
+// GetDayFilesBetween returns the day files of the conversation sorted by date,
+// which could include messages sent at or after since and before until.
+// Either time can be zero for an open range.  The day files are selected by name, so no files are read.
+func (e *EnterpriseGrid) GetDayFilesBetween(c *Conversation, since time.Time, until time.Time) ([]*DayFile, error) {
+	prefix, err := c.ResolvePrefix()
+	if err != nil {
+		return nil, err
+	}
+	first, last := "", ""
+	if !since.IsZero() {
+		first = since.UTC().Format(DayFileDateFormat)
+	}
+	if !until.IsZero() {
+		last = until.Add(-time.Nanosecond).UTC().Format(DayFileDateFormat)
+		if last < first {
+			return make([]*DayFile, 0), nil
+		}
+	}
+	return newDayFiles(prefix, e.Archive.GetDayFiles(prefix, first, last)), nil
+}
+
+// GetConversationMessages returns the messages in the day files of the conversation.
+func (e *EnterpriseGrid) GetConversationMessages(c *Conversation) ([]*Message, error) {
+	return e.GetMessagesBetween(c, time.Time{}, time.Time{})
+}
+
+// GetConversationMessagesContext is GetConversationMessages, but stops with the error of the context if it is canceled.
+func (e *EnterpriseGrid) GetConversationMessagesContext(ctx context.Context, c *Conversation) ([]*Message, error) {
+	return e.GetMessagesBetweenContext(ctx, c, time.Time{}, time.Time{})
+}
+
+// GetMessagesBetween returns the messages of the conversation sent at or after since and before until.
+// Either time can be zero for an open range.  Only the day files in the range are read,
+// so reading a few days from a long-lived channel is fast.  Messages with invalid timestamps are included if their day file is in the range.
+func (e *EnterpriseGrid) GetMessagesBetween(c *Conversation, since time.Time, until time.Time) ([]*Message, error) {
+	return e.GetMessagesBetweenContext(context.Background(), c, since, until)
+}
+
+// GetMessagesBetweenContext is GetMessagesBetween, but stops with the error of the context if it is canceled.
+func (e *EnterpriseGrid) GetMessagesBetweenContext(ctx context.Context, c *Conversation, since time.Time, until time.Time) ([]*Message, error) {
+	return e.getMessages(ctx, c, since, until, nil)
+}
+
+// getMessages returns the messages of the conversation in the range.
+// If onError is not nil, then it is called with each day file that cannot be read, and the file is skipped unless it returns an error.
+// It is also called if the conversation is at an unsafe path, and the conversation is skipped unless it returns an error.
+func (e *EnterpriseGrid) getMessages(ctx context.Context, c *Conversation, since time.Time, until time.Time, onError ErrorFunc) ([]*Message, error) {
+	dayFiles, err := e.GetDayFilesBetween(c, since, until)
+	if err != nil {
+		if onError == nil {
+			return nil, err
+		}
+		if err = onError(c, nil, err); err != nil {
+			return nil, err
+		}
+		return []*Message{}, nil
+	}
+	messages := []*Message{}
+	for _, f := range dayFiles {
+		if err = ctx.Err(); err != nil {
+			return nil, fmt.Errorf("error reading messages for %s %q: %w", c.Type, c.Name, err)
+		}
+		m := make([]*Message, 0)
+		err = e.UnmarshalFile(f.Name, &m)
+		if err != nil {
+			err = fmt.Errorf("error reading messages for %s %q: error unmarshaling message from file %q: %w", c.Type, c.Name, f.Name, err)
+			if onError == nil {
+				return nil, err
+			}
+			if err = onError(c, f, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, message := range m {
+			if inRange(message, since, until) {
+				messages = append(messages, message)
+			}
+		}
+	}
+	return messages, nil
+}
+
+// inRange returns true if the message was sent at or after since and before until, or has an invalid timestamp.
+func inRange(m *Message, since time.Time, until time.Time) bool {
+	if since.IsZero() && until.IsZero() {
+		return true
+	}
+	t, err := ParseTimestamp(m.Timestamp)
+	if err != nil {
+		return true
+	}
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || t.Before(until))
+}
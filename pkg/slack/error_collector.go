@@ -0,0 +1,76 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/deptofdefense/slack-archiver/pkg/ziputil"
+)
+
+// SkippedFile is a day file that was skipped since it could not be read,
+// or a conversation that was skipped since it cannot be stored at a safe path, in which case the file is blank.
+type SkippedFile struct {
+	Type         ConversationType `json:"type"`
+	Conversation string           `json:"conversation"`
+	File         string           `json:"file,omitempty"`
+	Offset       int64            `json:"offset,omitempty"` // the byte offset of the invalid JSON, if known
+	Error        string           `json:"error"`
+}
+
+// ErrorCollector records the day files that cannot be read and the conversations at unsafe paths,
+// so that they are skipped rather than stopping a walk.
+// Use the Collect method as the OnError of the WalkOptions.
+type ErrorCollector struct {
+	MaxErrors int // stop once more than this many day files are skipped, or zero for no limit
+	mutex     sync.Mutex
+	skipped   []*SkippedFile
+}
+
+// Collect records the day file, or the conversation if the day file is nil, and returns nil, so it is skipped,
+// unless the error is not from reading the day file or resolving the path of the conversation,
+// or more than the maximum number of day files and conversations have been skipped.
+func (ec *ErrorCollector) Collect(c *Conversation, f *DayFile, err error) error {
+	skipped := &SkippedFile{
+		Type:         c.Type,
+		Conversation: c.Name,
+	}
+	var fileError *ziputil.FileError
+	switch {
+	case f == nil && errors.Is(err, ErrUnsafePath):
+		skipped.Error = err.Error()
+	case f != nil && errors.As(err, &fileError):
+		skipped.File = f.Name
+		skipped.Offset = fileError.Offset
+		skipped.Error = fileError.Err.Error()
+	default:
+		return err
+	}
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	ec.skipped = append(ec.skipped, skipped)
+	if ec.MaxErrors > 0 && len(ec.skipped) > ec.MaxErrors {
+		return fmt.Errorf("%w: skipped more than %d day files and conversations: %w", ErrTooManyErrors, ec.MaxErrors, err)
+	}
+	return nil
+}
+
+// Skipped returns the day files and conversations that were skipped, sorted by the name of the file.
+func (ec *ErrorCollector) Skipped() []*SkippedFile {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	skipped := make([]*SkippedFile, len(ec.skipped))
+	copy(skipped, ec.skipped)
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].File < skipped[j].File
+	})
+	return skipped
+}
@@ -0,0 +1,30 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"errors"
+
+	"github.com/deptofdefense/slack-archiver/pkg/ziputil"
+)
+
+var (
+	// ErrNotEnterpriseGrid is matched by errors.Is when the archive is not an export of an enterprise grid,
+	// e.g., the export of a single workspace, which does not include org_users.json.
+	ErrNotEnterpriseGrid = errors.New("archive is not an enterprise grid export")
+	// ErrMissingFile is matched by errors.Is when a file is missing from the archive.
+	ErrMissingFile = ziputil.ErrMissingFile
+	// ErrInvalidJSON is matched by errors.Is when a file in the archive is not valid JSON.
+	// Use errors.As with a *ziputil.FileError to get the name of the file and the byte offset of the error.
+	ErrInvalidJSON = ziputil.ErrInvalidJSON
+	// ErrUnsafePath is matched by errors.Is when a conversation cannot be stored at a safe path in the archive,
+	// e.g., a channel named "..", so its day files cannot be read.
+	ErrUnsafePath = errors.New("unsafe path")
+	// ErrTooManyErrors is matched by errors.Is when an ErrorCollector skips more than the maximum number of day files.
+	ErrTooManyErrors = errors.New("too many errors")
+)
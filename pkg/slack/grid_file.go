@@ -0,0 +1,80 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GridFileKind is the kind of records in a file of an enterprise grid.
+type GridFileKind string
+
+const (
+	GridFileKindDirectMessages            GridFileKind = "dms"
+	GridFileKindUsers                     GridFileKind = "users"
+	GridFileKindMultiPartyInstantMessages GridFileKind = "mpims"
+	GridFileKindGroups                    GridFileKind = "groups"
+	GridFileKindIntegrationLogs           GridFileKind = "integration_logs"
+	GridFileKindChannels                  GridFileKind = "channels"
+)
+
+// GridFile is a file of an enterprise grid other than a day file, e.g., "org_users.json" or "teams/<team>/channels.json".
+type GridFile struct {
+	Name string
+	Kind GridFileKind
+	Team string // empty for the files at the root of the archive
+}
+
+// GetGridFiles returns the files with the users, conversations, and integration logs of the enterprise grid,
+// in the order they are read by GetEnterpriseGrid.
+func (e *EnterpriseGrid) GetGridFiles() []*GridFile {
+	files := []*GridFile{
+		{Name: "dms.json", Kind: GridFileKindDirectMessages},
+		{Name: "org_users.json", Kind: GridFileKindUsers},
+		{Name: "mpims.json", Kind: GridFileKindMultiPartyInstantMessages},
+		{Name: "groups.json", Kind: GridFileKindGroups},
+		{Name: "integration_logs.json", Kind: GridFileKindIntegrationLogs},
+	}
+	for _, t := range e.Teams {
+		files = append(
+			files,
+			&GridFile{Name: fmt.Sprintf("teams/%s/channels.json", t.Name), Kind: GridFileKindChannels, Team: t.Name},
+			&GridFile{Name: fmt.Sprintf("teams/%s/groups.json", t.Name), Kind: GridFileKindGroups, Team: t.Name},
+			&GridFile{Name: fmt.Sprintf("teams/%s/users.json", t.Name), Kind: GridFileKindUsers, Team: t.Name},
+		)
+	}
+	return files
+}
+
+// GetRecords returns the original bytes of each record in the named file,
+// e.g., the users in "org_users.json" or the messages in a day file.
+func (e *EnterpriseGrid) GetRecords(name string) ([]json.RawMessage, error) {
+	_, records, err := e.Archive.ReadRecords(name)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RecordKey is the minimal view of a record used to deduplicate and order records without decoding the rest of the record.
+type RecordKey struct {
+	ID        string `json:"id"`
+	Timestamp string `json:"ts"`
+	Updated   int    `json:"updated"`
+}
+
+// UnmarshalRecordKey decodes the id, timestamp, and updated time of a user, conversation, or message.
+func UnmarshalRecordKey(record json.RawMessage) (*RecordKey, error) {
+	key := &RecordKey{}
+	err := json.Unmarshal(record, key)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling record: %w", err)
+	}
+	return key, nil
+}
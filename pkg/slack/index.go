@@ -0,0 +1,87 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"sort"
+	"strings"
+)
+
+// fileIndex is the files in an archive sorted by name, which is built once when the archive is opened.
+// Since the files are sorted, the files that start with a prefix or fall in a range of names are adjacent
+// and found with a binary search, rather than a scan of every file.
+type fileIndex struct {
+	files []*ArchiveFile
+	names map[string]*ArchiveFile
+}
+
+func newFileIndex(files []*ArchiveFile) *fileIndex {
+	sorted := make([]*ArchiveFile, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	names := make(map[string]*ArchiveFile, len(sorted))
+	for _, f := range sorted {
+		if _, ok := names[f.Name]; !ok {
+			names[f.Name] = f
+		}
+	}
+	return &fileIndex{files: sorted, names: names}
+}
+
+// lowerBound returns the position of the first file with a name greater than or equal to the given name.
+func (i *fileIndex) lowerBound(name string) int {
+	return sort.Search(len(i.files), func(j int) bool {
+		return i.files[j].Name >= name
+	})
+}
+
+// exact returns the file with the given name.
+func (i *fileIndex) exact(name string) (*ArchiveFile, bool) {
+	f, ok := i.names[name]
+	return f, ok
+}
+
+// prefix returns the files with names that start with the prefix.
+func (i *fileIndex) prefix(prefix string) []*ArchiveFile {
+	start := i.lowerBound(prefix)
+	end := start + sort.Search(len(i.files)-start, func(j int) bool {
+		return !strings.HasPrefix(i.files[start+j].Name, prefix)
+	})
+	return i.files[start:end]
+}
+
+// between returns the files with names from lo to hi, inclusive.
+func (i *fileIndex) between(lo string, hi string) []*ArchiveFile {
+	start := i.lowerBound(lo)
+	end := start + sort.Search(len(i.files)-start, func(j int) bool {
+		return i.files[start+j].Name > hi
+	})
+	return i.files[start:end]
+}
+
+// directories returns the names of the directories directly under the prefix, which must be blank or end with a slash.
+// Directories are found even if the archive does not include entries for them.
+// After each directory is found, the search skips past every file in it, so the cost depends on the number of directories rather than files.
+func (i *fileIndex) directories(prefix string) []string {
+	directories := make([]string, 0)
+	for j := i.lowerBound(prefix); j < len(i.files) && strings.HasPrefix(i.files[j].Name, prefix); {
+		rest := i.files[j].Name[len(prefix):]
+		slash := strings.Index(rest, "/")
+		if slash <= 0 {
+			j++
+			continue
+		}
+		directory := rest[:slash]
+		directories = append(directories, directory)
+		// '0' is the character after '/', so this is the first name after every name in the directory.
+		j = i.lowerBound(prefix + directory + "0")
+	}
+	return directories
+}
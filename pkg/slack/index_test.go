@@ -0,0 +1,111 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const (
+	benchmarkConversations = 200
+	benchmarkDays          = 365
+)
+
+// newBenchmarkArchive returns an archive with a day file for every day of a year in each conversation.
+func newBenchmarkArchive(b *testing.B) *Archive {
+	b.Helper()
+	fsys := fstest.MapFS{
+		"org_users.json": &fstest.MapFile{Data: []byte("[]")},
+	}
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < benchmarkConversations; i++ {
+		for j := 0; j < benchmarkDays; j++ {
+			name := fmt.Sprintf("C%04d/%s.json", i, start.AddDate(0, 0, j).Format("2006-01-02"))
+			fsys[name] = &fstest.MapFile{Data: []byte("[]")}
+		}
+	}
+	a, err := NewArchive("benchmark", fsys)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return a
+}
+
+// scanFiles returns the files whose names start with the prefix by checking every file in the archive.
+func scanFiles(files []*ArchiveFile, prefix string) []*ArchiveFile {
+	matches := make([]*ArchiveFile, 0)
+	for _, f := range files {
+		if strings.HasPrefix(f.Name, prefix) {
+			matches = append(matches, f)
+		}
+	}
+	return matches
+}
+
+// scanDayFiles returns the day files of a conversation between the dates by checking every file in the archive.
+func scanDayFiles(files []*ArchiveFile, prefix string, since string, until string) []*ArchiveFile {
+	matches := make([]*ArchiveFile, 0)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, prefix) {
+			continue
+		}
+		rest := f.Name[len(prefix):]
+		if !strings.HasSuffix(rest, ".json") || strings.Contains(rest, "/") {
+			continue
+		}
+		day := strings.TrimSuffix(rest, ".json")
+		if day >= since && day <= until {
+			matches = append(matches, f)
+		}
+	}
+	return matches
+}
+
+func BenchmarkGetFiles(b *testing.B) {
+	a := newBenchmarkArchive(b)
+	files := a.GetFiles("")
+	prefix := fmt.Sprintf("C%04d/", benchmarkConversations/2)
+	// the directory of the conversation is included along with its day files
+	if got, expected := len(a.GetFiles(prefix)), len(scanFiles(files, prefix)); got != expected || got != benchmarkDays+1 {
+		b.Fatalf("expecting %d files, but found %d with the index and %d with a scan", benchmarkDays+1, got, expected)
+	}
+	b.Run("Index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = a.GetFiles(prefix)
+		}
+	})
+	b.Run("Scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = scanFiles(files, prefix)
+		}
+	})
+}
+
+func BenchmarkGetDayFiles(b *testing.B) {
+	a := newBenchmarkArchive(b)
+	files := a.GetFiles("")
+	prefix := fmt.Sprintf("C%04d/", benchmarkConversations/2)
+	since, until := "2020-03-01", "2020-03-31"
+	if got, expected := len(a.GetDayFiles(prefix, since, until)), len(scanDayFiles(files, prefix, since, until)); got != expected || got != 31 {
+		b.Fatalf("expecting 31 files, but found %d with the index and %d with a scan", got, expected)
+	}
+	b.Run("Index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = a.GetDayFiles(prefix, since, until)
+		}
+	})
+	b.Run("Scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = scanDayFiles(files, prefix, since, until)
+		}
+	})
+}
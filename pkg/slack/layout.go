@@ -0,0 +1,38 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layout is the layout of the directory files are downloaded to.
+type Layout string
+
+const (
+	// LayoutPartitioned partitions files by creation date, user, file type, and file id,
+	// e.g., year=2020/month=7/day=29/user=U1/filetype=pdf/id=F1/notes.pdf.
+	LayoutPartitioned Layout = "partitioned"
+	// LayoutID stores each file in a directory named by the file id, e.g., F1/notes.pdf.
+	LayoutID Layout = "id"
+)
+
+// Layouts are the names of the supported layouts.
+var Layouts = []string{string(LayoutPartitioned), string(LayoutID)}
+
+// ParseLayout returns the layout with the name, where a blank name is the partitioned layout.
+func ParseLayout(name string) (Layout, error) {
+	switch Layout(name) {
+	case "", LayoutPartitioned:
+		return LayoutPartitioned, nil
+	case LayoutID:
+		return LayoutID, nil
+	}
+	return "", fmt.Errorf("unknown layout %q, expecting one of %s", name, strings.Join(Layouts, ", "))
+}
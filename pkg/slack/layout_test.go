@@ -0,0 +1,45 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"testing"
+)
+
+func TestGetDownloadPath(t *testing.T) {
+	f := MessageFile{
+		ID:                 "F1",
+		User:               "U1",
+		FileType:           "pdf",
+		Created:            1596110400, // 2020-07-30 at noon UTC
+		URLPrivateDownload: "https://files.slack.com/files-pri/T1-F1/download/notes.pdf",
+	}
+	for _, test := range []struct {
+		layout   string
+		expected string
+	}{
+		{layout: "", expected: "year=2020/month=7/day=30/user=U1/filetype=pdf/id=F1/notes.pdf"},
+		{layout: "partitioned", expected: "year=2020/month=7/day=30/user=U1/filetype=pdf/id=F1/notes.pdf"},
+		{layout: "id", expected: "F1/notes.pdf"},
+	} {
+		layout, err := ParseLayout(test.layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.GetDownloadPath(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.expected {
+			t.Errorf("expecting path %q for layout %q, but found %q", test.expected, test.layout, got)
+		}
+	}
+	if _, err := ParseLayout("flat"); err == nil {
+		t.Error("expecting an error for an unknown layout")
+	}
+}
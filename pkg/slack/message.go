@@ -9,6 +9,11 @@ package slack
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 )
 
 type MessageProfile struct {
@@ -35,8 +40,9 @@ type MessageText struct {
 type MessageBlockElement struct {
 	ActionID string                 `json:"action_id"` // if type==button, then action_id is used
 	Type     string                 `json:"type"`
-	Text     json.RawMessage        `json:"text,omitempty"` // could be string or objecty
-	Name     string                 `json:"name,omitempty"` // if type==emoji, then name is used
+	Text     json.RawMessage        `json:"text,omitempty"`    // could be string or objecty
+	Name     string                 `json:"name,omitempty"`    // if type==emoji, then name is used
+	UserID   string                 `json:"user_id,omitempty"` // if type==user, then user_id is used
 	Elements []*MessageBlockElement `json:"elements,omitempty"`
 }
 
@@ -113,15 +119,48 @@ func (f MessageFile) IsTombstone() bool {
 	return f.Mode == "tombstone"
 }
 
+// GetDownloadPath returns the relative path used when downloading the file with the layout.
+func (f MessageFile) GetDownloadPath(layout Layout) (string, error) {
+	u, err := url.Parse(f.URLPrivateDownload)
+	if err != nil {
+		return "", fmt.Errorf("error parsing url for file %q: %w", f.ID, err)
+	}
+
+	filename := u.Path[strings.LastIndex(u.Path, "/")+1:]
+
+	if layout == LayoutID {
+		return path.Join(f.ID, filename), nil
+	}
+
+	created := time.Unix(int64(f.Created), 0)
+	createdYear, createdMonth, createdDay := created.Date()
+
+	return path.Join(
+		fmt.Sprintf("year=%d", createdYear),
+		fmt.Sprintf("month=%d", int(createdMonth)),
+		fmt.Sprintf("day=%d", createdDay),
+		fmt.Sprintf("user=%s", f.User),
+		fmt.Sprintf("filetype=%s", f.FileType),
+		fmt.Sprintf("id=%s", f.ID),
+		filename,
+	), nil
+}
+
 type MessageReply struct {
 	User      string `json:"user"`
 	Timestamp string `json:"ts"`
 }
 
+type MessageEdited struct {
+	User      string `json:"user"`
+	Timestamp string `json:"ts"`
+}
+
 type Message struct {
 	Blocks          []MessageBlock    `json:"blocks"`
 	Files           []MessageFile     `json:"files,omitempty"`
 	ClientMessageID string            `json:"client_msg_id"`
+	Edited          *MessageEdited    `json:"edited,omitempty"`
 	IsLocked        bool              `json:"is_locked"`
 	LatestReply     string            `json:"latest_reply,omitempty"`
 	Reactions       []MessageReaction `json:"reactions,omitempty"`
@@ -141,3 +180,8 @@ type Message struct {
 	UserTeam        string            `json:"user_team"`
 	UserProfile     MessageProfile    `json:"user_profile"`
 }
+
+// IsReply returns true if the message is a reply in a thread started by another message.
+func (m Message) IsReply() bool {
+	return len(m.ThreadTimestamp) > 0 && m.ThreadTimestamp != m.Timestamp
+}
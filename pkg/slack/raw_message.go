@@ -0,0 +1,47 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"github.com/deptofdefense/slack-archiver/pkg/jsonutil"
+)
+
+// WalkRawBlocks calls fn for every block in the raw message and for every object within the block that can hold text:
+// the fields and accessory of the block, and its elements, including nested elements.
+// So fn sees the text and fields of section, header, and context blocks as well as the text of rich text elements.
+// Changes made by fn are written back to the message, and the rest of the message is left as is.
+func WalkRawBlocks(message *jsonutil.Object, fn func(e *jsonutil.Object) error) error {
+	var walk func(e *jsonutil.Object) error
+	walk = func(e *jsonutil.Object) error {
+		if err := fn(e); err != nil {
+			return err
+		}
+		if err := e.UpdateObjects("fields", walk); err != nil {
+			return err
+		}
+		if err := e.UpdateObject("accessory", walk); err != nil {
+			return err
+		}
+		return e.UpdateObjects("elements", walk)
+	}
+	return message.UpdateObjects("blocks", walk)
+}
+
+// TransformRawBlockText replaces the text of the raw block or block element using fn, whether the text is a string or an object with a text field.
+func TransformRawBlockText(e *jsonutil.Object, fn func(text string) string) error {
+	value, ok := e.Get("text")
+	if !ok || len(value) == 0 {
+		return nil
+	}
+	if value[0] != '{' {
+		return e.UpdateString("text", fn)
+	}
+	return e.UpdateObject("text", func(text *jsonutil.Object) error {
+		return text.UpdateString("text", fn)
+	})
+}
@@ -0,0 +1,207 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slacktest
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// Files are the contents of the files of a Slack export by name.
+type Files map[string]string
+
+var (
+	// gridFiles are the metadata files at the root of an enterprise grid export.
+	gridFiles = []string{"org_users.json", "dms.json", "mpims.json", "groups.json", "integration_logs.json"}
+	// teamFiles are the metadata files of each team under teams/.
+	teamFiles = []string{"channels.json", "groups.json", "users.json"}
+)
+
+// MapFS returns the files of an enterprise grid export as a file system.
+// The metadata files of the grid, and of each team with files under teams/, that are not given are empty arrays.
+// Files given with blank contents are left out, e.g., to remove one of the metadata files.
+func MapFS(files Files) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range gridFiles {
+		fsys[name] = &fstest.MapFile{Data: []byte("[]")}
+	}
+	for name := range files {
+		if !strings.HasPrefix(name, "teams/") {
+			continue
+		}
+		if i := strings.Index(name[len("teams/"):], "/"); i > 0 {
+			prefix := name[:len("teams/")+i+1]
+			for _, f := range teamFiles {
+				fsys[prefix+f] = &fstest.MapFile{Data: []byte("[]")}
+			}
+		}
+	}
+	for name, data := range files {
+		if len(data) == 0 {
+			delete(fsys, name)
+			continue
+		}
+		fsys[name] = &fstest.MapFile{Data: []byte(data)}
+	}
+	return fsys
+}
+
+// NewArchive returns an archive that reads the files from MapFS.
+func NewArchive(t testing.TB, files Files) *slack.Archive {
+	t.Helper()
+	a, err := slack.NewArchive("test", MapFS(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// NewGrid returns the enterprise grid read from the files.
+func NewGrid(t testing.TB, files Files) *slack.EnterpriseGrid {
+	t.Helper()
+	e, err := NewArchive(t, files).GetEnterpriseGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// WriteDir writes the files of MapFS to a new temporary directory and returns the path to the directory.
+func WriteDir(t testing.TB, files Files) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, f := range MapFS(files) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.Data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// WriteFile writes the data to a new file with the name in a temporary directory and returns the path to the file.
+func WriteFile(t testing.TB, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Zip returns a zip file with the files of MapFS.
+func Zip(t testing.TB, files Files) []byte {
+	t.Helper()
+	b := &bytes.Buffer{}
+	zw := zip.NewWriter(b)
+	for name, f := range MapFS(files) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(f.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// Unzip returns the contents of the files in the zip file.
+func Unzip(t testing.TB, data []byte) Files {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := Files{}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+// buffer is the destination of an archive written in memory.
+type buffer struct {
+	bytes.Buffer
+}
+
+func (b *buffer) Close() error {
+	return nil
+}
+
+// WriteArchive calls write with a new archive writer and returns the contents of the files in the written archive.
+// The error returned by write is returned as is, so tests can check for it.
+func WriteArchive(t testing.TB, write func(w *slack.ArchiveWriter) error) (Files, error) {
+	t.Helper()
+	out := &buffer{}
+	w := slack.NewArchiveWriter("test", out)
+	if err := write(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return Unzip(t, out.Bytes()), nil
+}
+
+// Decrypter "decrypts" archives whose names end in ".xor" by flipping every bit, or fails with Err if it is not nil.
+type Decrypter struct {
+	Err error
+}
+
+// Encrypted returns true if the name ends in ".xor".
+func (d *Decrypter) Encrypted(name string) bool {
+	return strings.HasSuffix(name, ".xor")
+}
+
+// Decrypt returns the data read from r with every bit flipped.
+func (d *Decrypter) Decrypt(r io.Reader) (io.Reader, error) {
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(Encrypt(data)), nil
+}
+
+// Encrypt returns the data with every bit flipped, which is decrypted by Decrypter.
+func Encrypt(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, c := range data {
+		out[i] = ^c
+	}
+	return out
+}
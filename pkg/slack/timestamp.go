@@ -0,0 +1,56 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimestamp parses a Slack timestamp, e.g., "1595980000.000200", into a time.
+func ParseTimestamp(ts string) (time.Time, error) {
+	seconds, fraction := ts, ""
+	if i := strings.Index(ts, "."); i != -1 {
+		seconds, fraction = ts[:i], ts[i+1:]
+	}
+	s, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing seconds from timestamp %q: %w", ts, err)
+	}
+	ns := int64(0)
+	if len(fraction) > 0 {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		f, err := strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error parsing fraction from timestamp %q: %w", ts, err)
+		}
+		ns = f
+	}
+	return time.Unix(s, ns).UTC(), nil
+}
+
+// CompareTimestamps compares two Slack timestamps numerically, returning -1, 0, or 1.
+// Timestamps that cannot be parsed are compared as strings.
+func CompareTimestamps(a string, b string) int {
+	ta, errA := ParseTimestamp(a)
+	tb, errB := ParseTimestamp(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case ta.Before(tb):
+		return -1
+	case ta.After(tb):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WalkOptions are the options for walking the conversations in an enterprise grid.
+type WalkOptions struct {
+	Parallelism int       // the number of conversations decoded at once, defaults to 1
+	Ordered     bool      // call the walk function in the order of GetConversations
+	Since       time.Time // only include messages sent at or after since, if not zero
+	Until       time.Time // only include messages sent before until, if not zero
+	OnError     ErrorFunc // called with each day file or conversation that cannot be read, which is skipped unless it returns an error
+}
+
+// WalkFunc is called with the messages of each conversation.
+type WalkFunc func(c *Conversation, messages []*Message) error
+
+// ErrorFunc is called with a day file that cannot be read and the error.  If it returns nil, then the day file is skipped.
+// It is also called with a nil day file and an error matching ErrUnsafePath for a conversation that cannot be stored at a safe path,
+// in which case the conversation is skipped if it returns nil.
+// Since conversations are decoded in parallel, it can be called from multiple goroutines at once.
+type ErrorFunc func(c *Conversation, f *DayFile, err error) error
+
+type walkResult struct {
+	index    int
+	messages []*Message
+	err      error
+}
+
+// WalkConversations decodes the messages of each conversation and calls fn with them.
+// Conversations are decoded in parallel, but fn is only called from one goroutine at a time.
+// If ordered, then fn is called in the order of GetConversations, otherwise in the order the conversations are decoded.
+// The number of decoded conversations waiting for fn is bounded, so memory use does not grow with the size of the archive.
+// Walking stops at the first error or when the context is canceled.
+func (e *EnterpriseGrid) WalkConversations(ctx context.Context, options *WalkOptions, fn WalkFunc) error {
+	if options == nil {
+		options = &WalkOptions{}
+	}
+	parallelism := options.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	conversations := e.GetConversations()
+
+	walkContext, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// a slot is held by each conversation from when it is dispatched until fn is called with it
+	slots := make(chan struct{}, 2*parallelism)
+	jobs := make(chan int)
+	results := make(chan walkResult)
+
+	go func() {
+		defer close(jobs)
+		for i := range conversations {
+			select {
+			case slots <- struct{}{}:
+			case <-walkContext.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-walkContext.Done():
+				return
+			}
+		}
+	}()
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := walkResult{index: i}
+				r.messages, r.err = e.getMessages(walkContext, conversations[i], options.Since, options.Until, options.OnError)
+				select {
+				case results <- r:
+				case <-walkContext.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	handle := func(r walkResult) {
+		<-slots
+		if err != nil {
+			return
+		}
+		if r.err != nil {
+			err = r.err
+			cancel()
+			return
+		}
+		if fnError := fn(conversations[r.index], r.messages); fnError != nil {
+			c := conversations[r.index]
+			err = fmt.Errorf("error walking %s %q: %w", c.Type, c.Name, fnError)
+			cancel()
+		}
+	}
+
+	pending := map[int]walkResult{}
+	next := 0
+	for r := range results {
+		if !options.Ordered {
+			handle(r)
+			continue
+		}
+		pending[r.index] = r
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			handle(p)
+			next++
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,92 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package slack_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestWalkGrid returns the test enterprise grid with a channel at an unsafe path and a direct message with an invalid day file.
+func newTestWalkGrid(t *testing.T) *slack.EnterpriseGrid {
+	files := newTestFiles()
+	files["teams/hello/channels.json"] = `[
+		{"id": "C1", "name": "general", "members": ["U1", "U2"]},
+		{"id": "C2", "name": "..", "members": ["U1"]}
+	]`
+	files["D1/2020-07-30.json"] = `[{"type": "message", nope}]`
+	return slacktest.NewGrid(t, files)
+}
+
+func TestWalkConversations(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		parallelism int
+		collect     bool
+		maxErrors   int
+		visited     string
+		skipped     string
+		err         error
+	}{
+		{name: "StopAtInvalidDayFile", parallelism: 1, err: slack.ErrInvalidJSON},
+		{name: "SkipInvalidDayFileAndUnsafePath", parallelism: 1, collect: true, visited: "D1:1,C1:3,C2:0", skipped: "channel ..,dm D1 D1/2020-07-30.json"},
+		{name: "SkipInParallel", parallelism: 4, collect: true, visited: "D1:1,C1:3,C2:0", skipped: "channel ..,dm D1 D1/2020-07-30.json"},
+		{name: "TooManyErrors", parallelism: 1, collect: true, maxErrors: 1, err: slack.ErrTooManyErrors},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestWalkGrid(t)
+			options := &slack.WalkOptions{Parallelism: test.parallelism, Ordered: true}
+			collector := &slack.ErrorCollector{MaxErrors: test.maxErrors}
+			if test.collect {
+				options.OnError = collector.Collect
+			}
+			visited := make([]string, 0)
+			err := e.WalkConversations(context.Background(), options, func(c *slack.Conversation, messages []*slack.Message) error {
+				visited = append(visited, fmt.Sprintf("%s:%d", c.ID, len(messages)))
+				return nil
+			})
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expecting error %v, but found %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(visited, ","); got != test.visited {
+				t.Fatalf("expecting conversations %q, but found %q", test.visited, got)
+			}
+			skipped := make([]string, 0)
+			for _, s := range collector.Skipped() {
+				skipped = append(skipped, strings.TrimSpace(fmt.Sprintf("%s %s %s", s.Type, s.Conversation, s.File)))
+			}
+			if got := strings.Join(skipped, ","); got != test.skipped {
+				t.Fatalf("expecting skipped %q, but found %q", test.skipped, got)
+			}
+		})
+	}
+}
+
+func TestWalkConversationsCanceled(t *testing.T) {
+	e := newTestWalkGrid(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := e.WalkConversations(ctx, &slack.WalkOptions{Parallelism: 2}, func(c *slack.Conversation, messages []*slack.Message) error {
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expecting context.Canceled, but found %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package source
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deptofdefense/slack-archiver/pkg/blob"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// Decrypter decrypts archives that are encrypted at rest.
+type Decrypter = slack.Decrypter
+
+// Options are the options for opening a source.
+type Options struct {
+	Decrypters []Decrypter    // if one reports the source as encrypted, then the source is decrypted with it
+	S3         blob.S3Options // the options for sources that are S3 URLs
+}
+
+// Open opens the Slack export at the location, or reads it from stdin if the location is "-".
+// Locations that are S3 URLs are read from object storage, and the context applies to every read of the archive.
+// Other locations are opened with slack.OpenArchive.
+// If one of the decrypters reports the archive as encrypted, then the archive is decrypted in memory.
+func Open(ctx context.Context, location string, options *Options) (*slack.Archive, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	if _, _, ok := blob.ParseS3URL(location); ok {
+		store, key, err := blob.NewStore(location, options.S3)
+		if err != nil {
+			return nil, err
+		}
+		return OpenBlob(ctx, store, key, options.Decrypters...)
+	}
+
+	return slack.OpenArchive(location, options.Decrypters...)
+}
+
+// OpenBlob opens the named Slack export from the store.  Zip files are read in place with ranged reads.
+// The context applies to every read of the archive from the store.
+// If one of the decrypters reports the archive as encrypted, then the archive is decrypted in memory.
+func OpenBlob(ctx context.Context, store blob.Store, name string, decrypters ...Decrypter) (*slack.Archive, error) {
+	b, err := store.Open(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source %q: %w", name, err)
+	}
+	return slack.OpenArchiveReader(name, b, b.Size(), b, decrypters...)
+}
@@ -0,0 +1,103 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package source
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/blob"
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// testFiles are the files of an export with a single user.
+var testFiles = slacktest.Files{
+	"org_users.json": `[{"id": "U1", "name": "alice"}]`,
+}
+
+func checkTestArchive(t *testing.T, a *slack.Archive) {
+	t.Helper()
+	defer func() { _ = a.Close() }()
+	e, err := a.GetEnterpriseGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.OrganizationUsers) != 1 || e.OrganizationUsers[0].ID != "U1" {
+		t.Fatalf("expecting the user U1, but found %+v", e.OrganizationUsers)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		location string
+	}{
+		{name: "Directory", location: slacktest.WriteDir(t, testFiles)},
+		{name: "Zip", location: slacktest.WriteFile(t, "export.zip", slacktest.Zip(t, testFiles))},
+		{name: "Encrypted", location: slacktest.WriteFile(t, "export.zip.xor", slacktest.Encrypt(slacktest.Zip(t, testFiles)))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := Open(context.Background(), test.location, &Options{Decrypters: []Decrypter{&slacktest.Decrypter{}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTestArchive(t, a)
+		})
+	}
+}
+
+func TestOpenBlob(t *testing.T) {
+	store := blob.NewLocalStore()
+	for _, test := range []struct {
+		name     string
+		location string
+	}{
+		{name: "Zip", location: slacktest.WriteFile(t, "export.zip", slacktest.Zip(t, testFiles))},
+		{name: "Encrypted", location: slacktest.WriteFile(t, "export.zip.xor", slacktest.Encrypt(slacktest.Zip(t, testFiles)))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := OpenBlob(context.Background(), store, test.location, &slacktest.Decrypter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTestArchive(t, a)
+		})
+	}
+}
+
+func TestOpenBlobInvalid(t *testing.T) {
+	store := blob.NewLocalStore()
+	encrypted := slacktest.WriteFile(t, "export.zip.xor", slacktest.Encrypt(slacktest.Zip(t, testFiles)))
+	errDecrypt := errors.New("wrong key")
+	for _, test := range []struct {
+		name      string
+		location  string
+		decrypter *slacktest.Decrypter
+	}{
+		{name: "Missing", location: filepath.Join(t.TempDir(), "missing.zip")},
+		{name: "NotAnArchive", location: slacktest.WriteFile(t, "export.zip", []byte("hello"))},
+		{name: "DecryptError", location: encrypted, decrypter: &slacktest.Decrypter{Err: errDecrypt}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			decrypters := []Decrypter{}
+			if test.decrypter != nil {
+				decrypters = append(decrypters, test.decrypter)
+			}
+			_, err := OpenBlob(context.Background(), store, test.location, decrypters...)
+			if err == nil {
+				t.Fatal("expecting an error for an invalid source")
+			}
+			if test.decrypter != nil && !errors.Is(err, test.decrypter.Err) {
+				t.Fatalf("expecting the error of the decrypter, but found %v", err)
+			}
+		})
+	}
+}
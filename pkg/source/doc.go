@@ -0,0 +1,13 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+// Package source opens the Slack exports read by the commands.
+//
+// A source is a zip file, an extracted directory, a zip or tar file that holds the export, or "-" for stdin.
+// Local sources are opened with slack.OpenArchive, and S3 URLs are read from blob storage with ranged reads.
+// Sources encrypted at rest are decrypted in memory.
+package source
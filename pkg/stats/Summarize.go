@@ -0,0 +1,91 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+type ConversationSummary struct {
+	Type   slack.ConversationType `json:"type"`
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	Team   string                 `json:"team,omitempty"`
+	Counts Counts                 `json:"counts"`
+}
+
+type TeamSummary struct {
+	Name   string `json:"name"`
+	Counts Counts `json:"counts"`
+}
+
+type Summary struct {
+	Total         Counts                 `json:"total"`
+	Teams         []*TeamSummary         `json:"teams"`
+	Conversations []*ConversationSummary `json:"conversations"`
+}
+
+// Summarize computes the statistics for every conversation and team in the enterprise grid in a single pass,
+// while decoding conversations in parallel with the walk options.  Only the day files in the time range of the options are read.
+// Conversations are always summarized in order, so the summary does not depend on the parallelism.
+// The reaction leaderboards and busiest days are limited to the top n entries.
+func Summarize(ctx context.Context, e *slack.EnterpriseGrid, n int, options *slack.WalkOptions) (*Summary, error) {
+	walkOptions := &slack.WalkOptions{}
+	if options != nil {
+		*walkOptions = *options
+	}
+	walkOptions.Ordered = true
+
+	summary := &Summary{
+		Teams:         make([]*TeamSummary, 0),
+		Conversations: make([]*ConversationSummary, 0),
+	}
+
+	total := newCounter()
+	teams := map[string]*counter{}
+	for _, t := range e.GetTeams() {
+		teams[t.Name] = newCounter()
+	}
+
+	err := e.WalkConversations(ctx, walkOptions, func(c *slack.Conversation, messages []*slack.Message) error {
+		conversation := newCounter()
+		team := teams[c.Team]
+		for _, m := range messages {
+			conversation.add(m)
+			total.add(m)
+			if team != nil {
+				team.add(m)
+			}
+		}
+		summary.Conversations = append(summary.Conversations, &ConversationSummary{
+			Type:   c.Type,
+			ID:     c.ID,
+			Name:   c.Name,
+			Team:   c.Team,
+			Counts: conversation.finish(n),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error summarizing conversations: %w", err)
+	}
+
+	for _, t := range e.GetTeams() {
+		summary.Teams = append(summary.Teams, &TeamSummary{
+			Name:   t.Name,
+			Counts: teams[t.Name].finish(n),
+		})
+	}
+
+	summary.Total = total.finish(n)
+
+	return summary, nil
+}
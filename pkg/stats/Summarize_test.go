@@ -0,0 +1,109 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestGrid returns an enterprise grid with a direct message on 2020-07-29 and a channel with messages on 2020-07-29 and 2020-07-30.
+func newTestGrid(t *testing.T) *slack.EnterpriseGrid {
+	return slacktest.NewGrid(t, slacktest.Files{
+		"org_users.json":            `[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}]`,
+		"dms.json":                  `[{"id": "D1", "members": ["U1", "U2"]}]`,
+		"D1/2020-07-29.json":        `[{"type": "message", "user": "U1", "text": "hi", "ts": "1596060000.000100", "reactions": [{"name": "wave", "users": ["U2"], "count": 1}]}]`,
+		"teams/hello/channels.json": `[{"id": "C1", "name": "general", "members": ["U1", "U2"]}]`,
+		"teams/hello/users.json":    `[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}]`,
+		"teams/hello/general/2020-07-29.json": `[
+			{"type": "message", "user": "U2", "text": "files", "ts": "1596060001.000100", "files": [
+				{"id": "F1", "mode": "hosted", "size": 100},
+				{"id": "F2", "mode": "external", "is_external": true, "size": 20},
+				{"id": "F3", "mode": "tombstone", "size": 5}
+			]},
+			{"type": "message", "user": "U2", "text": "again", "ts": "1596060002.000100", "reactions": [{"name": "+1", "users": ["U1"], "count": 1}, {"name": "wave", "users": ["U1"], "count": 1}]}
+		]`,
+		"teams/hello/general/2020-07-30.json": `[{"type": "message", "user": "U1", "text": "later", "ts": "1596150000.000100"}]`,
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	summary, err := Summarize(context.Background(), newTestGrid(t), 1, &slack.WalkOptions{Parallelism: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Conversations) != 2 || len(summary.Teams) != 1 {
+		t.Fatalf("expecting 2 conversations and 1 team, but found %d and %d", len(summary.Conversations), len(summary.Teams))
+	}
+	for _, test := range []struct {
+		name     string
+		counts   Counts
+		expected Counts
+	}{
+		{
+			name:     "Total",
+			counts:   summary.Total,
+			expected: Counts{Messages: 4, ActiveUsers: 2, Files: 3, Bytes: 120, HostedFiles: 1, ExternalFiles: 1, TombstonedFiles: 1},
+		},
+		{
+			name:     "Team",
+			counts:   summary.Teams[0].Counts,
+			expected: Counts{Messages: 3, ActiveUsers: 2, Files: 3, Bytes: 120, HostedFiles: 1, ExternalFiles: 1, TombstonedFiles: 1},
+		},
+		{
+			name:     "DirectMessage",
+			counts:   summary.Conversations[0].Counts,
+			expected: Counts{Messages: 1, ActiveUsers: 1},
+		},
+	} {
+		c, e := test.counts, test.expected
+		if c.Messages != e.Messages || c.ActiveUsers != e.ActiveUsers || c.Files != e.Files || c.Bytes != e.Bytes ||
+			c.HostedFiles != e.HostedFiles || c.ExternalFiles != e.ExternalFiles || c.TombstonedFiles != e.TombstonedFiles {
+			t.Errorf("expecting %s counts of %+v, but found %+v", test.name, e, c)
+		}
+	}
+
+	total := summary.Total
+	if len(total.Reactions) != 1 || total.Reactions[0] != (ReactionCount{Name: "wave", Count: 2}) {
+		t.Errorf("expecting the top reaction to be wave with 2, but found %+v", total.Reactions)
+	}
+	if len(total.BusiestDays) != 1 || total.BusiestDays[0] != (DayCount{Day: "2020-07-29", Messages: 3}) {
+		t.Errorf("expecting the busiest day to be 2020-07-29 with 3 messages, but found %+v", total.BusiestDays)
+	}
+	if first, last := total.First.Format(time.RFC3339), total.Last.Format(time.RFC3339); first != "2020-07-29T22:00:00Z" || last != "2020-07-30T23:00:00Z" {
+		t.Errorf("expecting the first and last messages at 2020-07-29T22:00:00Z and 2020-07-30T23:00:00Z, but found %s and %s", first, last)
+	}
+
+	b := &bytes.Buffer{}
+	if err = summary.WriteTable(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"hello/general", "2020-07-29  2020-07-30", "wave"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expecting the table to contain %q, but found\n%s", s, b.String())
+		}
+	}
+}
+
+// TestSummarizeTimeRange checks that only the messages in the time range are counted.
+func TestSummarizeTimeRange(t *testing.T) {
+	since := time.Date(2020, time.July, 30, 0, 0, 0, 0, time.UTC)
+	summary, err := Summarize(context.Background(), newTestGrid(t), 10, &slack.WalkOptions{Since: since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Total.Messages != 1 || len(summary.Total.Reactions) != 0 {
+		t.Fatalf("expecting 1 message without reactions, but found %+v", summary.Total)
+	}
+}
@@ -0,0 +1,127 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+type ReactionCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type DayCount struct {
+	Day      string `json:"day"`
+	Messages int    `json:"messages"`
+}
+
+// Counts are the statistics for a conversation, team, or the whole archive.
+type Counts struct {
+	Messages        int             `json:"messages"`
+	ActiveUsers     int             `json:"active_users"`
+	Files           int             `json:"files"`
+	Bytes           int64           `json:"bytes"`
+	HostedFiles     int             `json:"hosted_files"`
+	ExternalFiles   int             `json:"external_files"`
+	TombstonedFiles int             `json:"tombstoned_files"`
+	Reactions       []ReactionCount `json:"reactions"`
+	BusiestDays     []DayCount      `json:"busiest_days"`
+	First           *time.Time      `json:"first,omitempty"`
+	Last            *time.Time      `json:"last,omitempty"`
+}
+
+// counter accumulates counts one message at a time.
+type counter struct {
+	counts    Counts
+	users     map[string]struct{}
+	reactions map[string]int
+	days      map[string]int
+}
+
+func newCounter() *counter {
+	return &counter{
+		users:     map[string]struct{}{},
+		reactions: map[string]int{},
+		days:      map[string]int{},
+	}
+}
+
+func (c *counter) add(m *slack.Message) {
+	c.counts.Messages++
+	if len(m.User) > 0 {
+		c.users[m.User] = struct{}{}
+	}
+	for _, f := range m.Files {
+		c.counts.Files++
+		switch {
+		case f.IsTombstone():
+			c.counts.TombstonedFiles++
+		case f.IsExternal:
+			c.counts.ExternalFiles++
+		case f.IsHosted():
+			c.counts.HostedFiles++
+		}
+		if !f.IsTombstone() {
+			c.counts.Bytes += f.Size
+		}
+	}
+	for _, r := range m.Reactions {
+		c.reactions[r.Name] += r.Count
+	}
+	if t, err := slack.ParseTimestamp(m.Timestamp); err == nil {
+		c.days[t.Format(slack.DayFileDateFormat)]++
+		if c.counts.First == nil || t.Before(*c.counts.First) {
+			first := t
+			c.counts.First = &first
+		}
+		if c.counts.Last == nil || t.After(*c.counts.Last) {
+			last := t
+			c.counts.Last = &last
+		}
+	}
+}
+
+// finish returns the counts with the top n reactions and busiest days.
+func (c *counter) finish(n int) Counts {
+	counts := c.counts
+	counts.ActiveUsers = len(c.users)
+
+	counts.Reactions = make([]ReactionCount, 0, len(c.reactions))
+	for name, count := range c.reactions {
+		counts.Reactions = append(counts.Reactions, ReactionCount{Name: name, Count: count})
+	}
+	sort.Slice(counts.Reactions, func(i, j int) bool {
+		if counts.Reactions[i].Count != counts.Reactions[j].Count {
+			return counts.Reactions[i].Count > counts.Reactions[j].Count
+		}
+		return counts.Reactions[i].Name < counts.Reactions[j].Name
+	})
+	if len(counts.Reactions) > n {
+		counts.Reactions = counts.Reactions[:n]
+	}
+
+	counts.BusiestDays = make([]DayCount, 0, len(c.days))
+	for day, count := range c.days {
+		counts.BusiestDays = append(counts.BusiestDays, DayCount{Day: day, Messages: count})
+	}
+	sort.Slice(counts.BusiestDays, func(i, j int) bool {
+		if counts.BusiestDays[i].Messages != counts.BusiestDays[j].Messages {
+			return counts.BusiestDays[i].Messages > counts.BusiestDays[j].Messages
+		}
+		return counts.BusiestDays[i].Day < counts.BusiestDays[j].Day
+	})
+	if len(counts.BusiestDays) > n {
+		counts.BusiestDays = counts.BusiestDays[:n]
+	}
+
+	return counts
+}
@@ -0,0 +1,83 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package stats
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+func formatRange(c Counts) (string, string) {
+	if c.First == nil || c.Last == nil {
+		return "-", "-"
+	}
+	return c.First.Format(slack.DayFileDateFormat), c.Last.Format(slack.DayFileDateFormat)
+}
+
+func formatRow(scope string, name string, c Counts) string {
+	first, last := formatRange(c)
+	return fmt.Sprintf(
+		"%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+		scope,
+		name,
+		c.Messages,
+		c.ActiveUsers,
+		c.Files,
+		c.Bytes,
+		c.HostedFiles,
+		c.ExternalFiles,
+		c.TombstonedFiles,
+		first,
+		last,
+	)
+}
+
+// WriteTable writes the summary as aligned text, with a row per conversation and team,
+// followed by the reaction leaderboard and busiest days for the whole archive.
+func (s *Summary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	lines := []string{"SCOPE\tNAME\tMESSAGES\tUSERS\tFILES\tBYTES\tHOSTED\tEXTERNAL\tTOMBSTONED\tFIRST\tLAST\n"}
+	for _, c := range s.Conversations {
+		name := c.Name
+		if len(c.Team) > 0 {
+			name = c.Team + "/" + c.Name
+		}
+		lines = append(lines, formatRow(string(c.Type), name, c.Counts))
+	}
+	for _, t := range s.Teams {
+		lines = append(lines, formatRow("team", t.Name, t.Counts))
+	}
+	lines = append(lines, formatRow("total", "-", s.Total))
+
+	lines = append(lines, "\n", "REACTION\tCOUNT\n")
+	for _, r := range s.Total.Reactions {
+		lines = append(lines, fmt.Sprintf("%s\t%d\n", r.Name, r.Count))
+	}
+
+	lines = append(lines, "\n", "DAY\tMESSAGES\n")
+	for _, d := range s.Total.BusiestDays {
+		lines = append(lines, fmt.Sprintf("%s\t%d\n", d.Day, d.Messages))
+	}
+
+	_, err := io.WriteString(tw, strings.Join(lines, ""))
+	if err != nil {
+		return fmt.Errorf("error writing table: %w", err)
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return fmt.Errorf("error flushing table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+)
+
+// readMetadataFile decodes the named file of the enterprise grid into v, and returns false
+// and reports the file as invalid if it is missing or cannot be decoded.
+func readMetadataFile(a *slack.Archive, name string, records string, v interface{}, report *Report) bool {
+	err := a.UnmarshalFile(name, v)
+	if err != nil {
+		report.add(&Issue{
+			Severity: SeverityError,
+			Code:     CodeInvalidMetadataFile,
+			Path:     name,
+			Message:  fmt.Sprintf("file is not a valid JSON array of %s: %s", records, err),
+		})
+		return false
+	}
+	return true
+}
+
+// readEnterpriseGrid reads the users and conversations of the enterprise grid like slack.Archive.GetEnterpriseGrid,
+// but reports the files that cannot be decoded and reads the rest of the enterprise grid without them.
+func readEnterpriseGrid(ctx context.Context, a *slack.Archive, report *Report) (*slack.EnterpriseGrid, error) {
+	e := &slack.EnterpriseGrid{Archive: a}
+	if !readMetadataFile(a, "dms.json", "direct messages", &e.DirectMessages, report) {
+		e.DirectMessages = nil
+	}
+	if !readMetadataFile(a, "org_users.json", "users", &e.OrganizationUsers, report) {
+		e.OrganizationUsers = nil
+	}
+	if !readMetadataFile(a, "mpims.json", "multiparty instant messages", &e.MultiPartyInstantMessages, report) {
+		e.MultiPartyInstantMessages = nil
+	}
+	if !readMetadataFile(a, "groups.json", "groups", &e.Groups, report) {
+		e.Groups = nil
+	}
+	if !readMetadataFile(a, "integration_logs.json", "integration logs", &e.IntegrationLogMessages, report) {
+		e.IntegrationLogMessages = nil
+	}
+	for _, name := range a.GetDirectories("teams/") {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("error reading teams: %w", err)
+		}
+		t := &slack.Team{Name: name}
+		if !readMetadataFile(a, fmt.Sprintf("teams/%s/channels.json", name), "channels", &t.Channels, report) {
+			t.Channels = nil
+		}
+		if !readMetadataFile(a, fmt.Sprintf("teams/%s/groups.json", name), "groups", &t.Groups, report) {
+			t.Groups = nil
+		}
+		if !readMetadataFile(a, fmt.Sprintf("teams/%s/users.json", name), "users", &t.Users, report) {
+			t.Users = nil
+		}
+		e.Teams = append(e.Teams, t)
+	}
+	return e, nil
+}
+
+// Validate checks the files of the enterprise grid in the archive for users and conversations that cannot be decoded,
+// and checks the conversations for invalid paths, invalid and unexpected day files, users and members that are not
+// organization users, files without ids, replies without parents, and duplicate timestamps.
+// Conversations in files that cannot be decoded are not checked, but the rest of the archive is.
+// Returns slack.ErrNotEnterpriseGrid if the archive does not include the organization users of an enterprise grid.
+// Validating stops with the error of the context if it is canceled.
+func Validate(ctx context.Context, a *slack.Archive) (*Report, error) {
+	if _, ok := a.GetFile("org_users.json"); !ok {
+		return nil, fmt.Errorf("%w: org_users.json not found", slack.ErrNotEnterpriseGrid)
+	}
+
+	report := &Report{
+		Issues: make([]*Issue, 0),
+	}
+
+	e, err := readEnterpriseGrid(ctx, a, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// Team users are a subset of the organization users, so a user that is only in the users of a team is unknown.
+	users := map[string]struct{}{}
+	for _, u := range e.OrganizationUsers {
+		users[u.ID] = struct{}{}
+	}
+
+	for _, c := range e.GetConversations() {
+		if err = validateConversation(ctx, e, c, users, report); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func validateConversation(ctx context.Context, e *slack.EnterpriseGrid, c *slack.Conversation, users map[string]struct{}, report *Report) error {
+	for _, member := range c.Members {
+		if _, ok := users[member]; !ok {
+			report.add(&Issue{
+				Severity:     SeverityWarning,
+				Code:         CodeUnknownMember,
+				Conversation: c.Name,
+				Message:      fmt.Sprintf("member %q of %s %q is not an organization user", member, c.Type, c.Name),
+			})
+		}
+	}
+
+	timestamps := map[string]string{} // timestamp to path
+	type reply struct {
+		path    string
+		message *slack.Message
+	}
+	replies := make([]reply, 0)
+
+	prefix, err := c.ResolvePrefix()
+	if err != nil {
+		report.add(&Issue{
+			Severity:     SeverityError,
+			Code:         CodeInvalidPath,
+			Conversation: c.Name,
+			Message:      fmt.Sprintf("%s cannot be stored at a safe path: %s", c.Type, err),
+		})
+		return nil
+	}
+
+	for _, f := range e.Archive.GetFiles(prefix) {
+		if err = ctx.Err(); err != nil {
+			return fmt.Errorf("error validating %s %q: %w", c.Type, c.Name, err)
+		}
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+
+		if !slack.IsDayFileName(f.Name[len(prefix):]) {
+			report.add(&Issue{
+				Severity:     SeverityWarning,
+				Code:         CodeUnexpectedFile,
+				Path:         f.Name,
+				Conversation: c.Name,
+				Message:      "file is not a day file named YYYY-MM-DD.json and is ignored",
+			})
+			continue
+		}
+
+		raw := make([]json.RawMessage, 0)
+		err = e.UnmarshalFile(f.Name, &raw)
+		if err != nil {
+			report.add(&Issue{
+				Severity:     SeverityError,
+				Code:         CodeInvalidDayFile,
+				Path:         f.Name,
+				Conversation: c.Name,
+				Message:      fmt.Sprintf("day file is not a valid JSON array: %s", err),
+			})
+			continue
+		}
+
+		for i, r := range raw {
+			m := &slack.Message{}
+			err = json.Unmarshal(r, m)
+			if err != nil {
+				report.add(&Issue{
+					Severity:     SeverityError,
+					Code:         CodeInvalidDayFile,
+					Path:         f.Name,
+					Conversation: c.Name,
+					Message:      fmt.Sprintf("element %d is not a valid message: %s", i, err),
+				})
+				continue
+			}
+
+			if len(m.User) > 0 {
+				if _, ok := users[m.User]; !ok {
+					report.add(&Issue{
+						Severity:     SeverityWarning,
+						Code:         CodeUnknownUser,
+						Path:         f.Name,
+						Conversation: c.Name,
+						Timestamp:    m.Timestamp,
+						Message:      fmt.Sprintf("user %q is not an organization user", m.User),
+					})
+				}
+			}
+
+			for k, file := range m.Files {
+				if len(file.ID) == 0 {
+					report.add(&Issue{
+						Severity:     SeverityError,
+						Code:         CodeFileMissingID,
+						Path:         f.Name,
+						Conversation: c.Name,
+						Timestamp:    m.Timestamp,
+						Message:      fmt.Sprintf("file %d with name %q is missing an id", k, file.Name),
+					})
+				}
+			}
+
+			if previous, ok := timestamps[m.Timestamp]; ok {
+				report.add(&Issue{
+					Severity:     SeverityError,
+					Code:         CodeDuplicateTimestamp,
+					Path:         f.Name,
+					Conversation: c.Name,
+					Timestamp:    m.Timestamp,
+					Message:      fmt.Sprintf("timestamp was already used by a message in %q", previous),
+				})
+			} else {
+				timestamps[m.Timestamp] = f.Name
+			}
+
+			if m.IsReply() {
+				replies = append(replies, reply{path: f.Name, message: m})
+			}
+		}
+	}
+
+	// Check replies once all the messages in the conversation are known,
+	// since a reply can be in a later day file than its parent.
+	for _, r := range replies {
+		if _, ok := timestamps[r.message.ThreadTimestamp]; !ok {
+			report.add(&Issue{
+				Severity:     SeverityWarning,
+				Code:         CodeOrphanReply,
+				Path:         r.path,
+				Conversation: c.Name,
+				Timestamp:    r.message.Timestamp,
+				Message:      fmt.Sprintf("parent message %q of reply is missing", r.message.ThreadTimestamp),
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package validate
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deptofdefense/slack-archiver/pkg/slack"
+	"github.com/deptofdefense/slack-archiver/pkg/slack/slacktest"
+)
+
+// newTestArchive returns an archive where the given files replace the defaults, and files given with blank contents are removed.
+func newTestArchive(t *testing.T, files map[string]string) *slack.Archive {
+	testFiles := slacktest.Files{
+		"org_users.json":                      `[{"id": "U1", "name": "alice"}]`,
+		"dms.json":                            `[{"id": "D1", "members": ["U1"]}]`,
+		"D1/2020-07-29.json":                  `[{"type": "message", "user": "U1", "text": "hi", "ts": "1596060000.000100"}]`,
+		"teams/hello/channels.json":           `[{"id": "C1", "name": "general", "members": ["U1"]}]`,
+		"teams/hello/users.json":              `[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}]`,
+		"teams/hello/general/2020-07-29.json": `[{"type": "message", "user": "U1", "text": "hello", "ts": "1596060000.000100"}]`,
+	}
+	for name, data := range files {
+		testFiles[name] = data
+	}
+	return slacktest.NewArchive(t, testFiles)
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		files    map[string]string
+		expected []string // the code and path of each issue
+	}{
+		{
+			name:     "Valid",
+			expected: []string{},
+		},
+		{
+			name: "InvalidChannels",
+			files: map[string]string{
+				"teams/hello/channels.json": `{"id": "C1"}`,
+				"D1/2020-07-29.json":        `[{"type": "message", "user": "U3", "text": "hi", "ts": "1596060000.000100"}]`,
+			},
+			expected: []string{"invalid_metadata_file teams/hello/channels.json", "unknown_user D1/2020-07-29.json"},
+		},
+		{
+			name:     "InvalidOrganizationUsers",
+			files:    map[string]string{"org_users.json": `[{"id": 1}]`},
+			expected: []string{"invalid_metadata_file org_users.json", "unknown_member ", "unknown_member ", "unknown_user D1/2020-07-29.json", "unknown_user teams/hello/general/2020-07-29.json"},
+		},
+		{
+			name:     "MissingDirectMessages",
+			files:    map[string]string{"dms.json": ""},
+			expected: []string{"invalid_metadata_file dms.json"},
+		},
+		{
+			name: "TeamUser",
+			files: map[string]string{
+				"teams/hello/channels.json":           `[{"id": "C1", "name": "general", "members": ["U1", "U2"]}]`,
+				"teams/hello/general/2020-07-29.json": `[{"type": "message", "user": "U2", "text": "hello", "ts": "1596060000.000100"}]`,
+			},
+			expected: []string{"unknown_member ", "unknown_user teams/hello/general/2020-07-29.json"},
+		},
+		{
+			name: "Messages",
+			files: map[string]string{
+				"teams/hello/general/2020-07-29.json": `[
+					{"type": "message", "user": "U1", "text": "hello", "ts": "1596060000.000100", "files": [{"name": "a.txt"}]},
+					{"type": "message", "user": "U1", "text": "again", "ts": "1596060000.000100"},
+					{"type": "message", "user": "U1", "text": "reply", "ts": "1596060001.000100", "thread_ts": "1596050000.000100"}
+				]`,
+				"teams/hello/general/2020-07-30.json": `{}`,
+				"teams/hello/general/notes.txt":       `notes`,
+			},
+			expected: []string{
+				"duplicate_timestamp teams/hello/general/2020-07-29.json",
+				"file_missing_id teams/hello/general/2020-07-29.json",
+				"invalid_day_file teams/hello/general/2020-07-30.json",
+				"orphan_reply teams/hello/general/2020-07-29.json",
+				"unexpected_file teams/hello/general/notes.txt",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := Validate(context.Background(), newTestArchive(t, test.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			issues := make([]string, 0, len(report.Issues))
+			for _, issue := range report.Issues {
+				issues = append(issues, issue.Code+" "+issue.Path)
+			}
+			sort.Strings(issues)
+			if got, expected := strings.Join(issues, ","), strings.Join(test.expected, ","); got != expected {
+				t.Fatalf("expecting issues %q, but found %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateNotEnterpriseGrid(t *testing.T) {
+	_, err := Validate(context.Background(), newTestArchive(t, map[string]string{"org_users.json": ""}))
+	if !errors.Is(err, slack.ErrNotEnterpriseGrid) {
+		t.Fatalf("expecting ErrNotEnterpriseGrid, but found %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package validate
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+const (
+	CodeInvalidDayFile      = "invalid_day_file"
+	CodeInvalidMetadataFile = "invalid_metadata_file"
+	CodeUnknownUser         = "unknown_user"
+	CodeUnknownMember       = "unknown_member"
+	CodeFileMissingID       = "file_missing_id"
+	CodeOrphanReply         = "orphan_reply"
+	CodeDuplicateTimestamp  = "duplicate_timestamp"
+	CodeInvalidPath         = "invalid_path"
+	CodeUnexpectedFile      = "unexpected_file"
+)
+
+// Issue is a single problem found in an archive.
+type Issue struct {
+	Severity     Severity `json:"severity"`
+	Code         string   `json:"code"`
+	Path         string   `json:"path,omitempty"`         // the path of the file in the archive
+	Conversation string   `json:"conversation,omitempty"` // the name of the conversation
+	Timestamp    string   `json:"ts,omitempty"`           // the timestamp of the message
+	Message      string   `json:"message"`
+}
+
+// Report is the result of validating an archive.
+type Report struct {
+	Errors   int      `json:"errors"`
+	Warnings int      `json:"warnings"`
+	Issues   []*Issue `json:"issues"`
+}
+
+func (r *Report) add(issue *Issue) {
+	switch issue.Severity {
+	case SeverityError:
+		r.Errors++
+	case SeverityWarning:
+		r.Warnings++
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// HasErrors returns true if the report includes any issues with an error severity.
+func (r *Report) HasErrors() bool {
+	return r.Errors > 0
+}
@@ -8,27 +8,41 @@
 package ziputil
 
 import (
-	"archive/zip"
 	"encoding/json"
-	"fmt"
-	"io"
+	"io/fs"
 )
 
-func UnmarshalFile(r *zip.Reader, name string, v interface{}) error {
-	fr, err := r.Open(name)
+// UnmarshalFile reads the named file from the file system and unmarshals it from JSON into v.
+// The file system can be a *zip.Reader, an os.DirFS of an extracted zip file, or any other fs.FS.
+// Errors are returned as a *FileError, which matches ErrMissingFile or ErrInvalidJSON.
+func UnmarshalFile(fsys fs.FS, name string, v interface{}) error {
+	data, err := fs.ReadFile(fsys, name)
 	if err != nil {
-		return fmt.Errorf("error opening file %q in zip file: %w", name, err)
+		return &FileError{Op: opRead, Name: name, Err: err}
 	}
 
-	data, err := io.ReadAll(fr)
+	err = json.Unmarshal(data, v)
 	if err != nil {
-		return fmt.Errorf("error reading file %q in zip file: %w", name, err)
+		return newUnmarshalError(name, err)
 	}
 
-	err = json.Unmarshal(data, v)
+	return nil
+}
+
+// ReadRecords reads the named file from the file system and unmarshals it as a JSON array,
+// returning the contents of the file along with the original bytes of each element.
+// Errors are returned as a *FileError, which matches ErrMissingFile or ErrInvalidJSON.
+func ReadRecords(fsys fs.FS, name string) ([]byte, []json.RawMessage, error) {
+	data, err := fs.ReadFile(fsys, name)
 	if err != nil {
-		return fmt.Errorf("error unmarshaling file %q in zip file: %w", name, err)
+		return nil, nil, &FileError{Op: opRead, Name: name, Err: err}
 	}
 
-	return nil
+	records := make([]json.RawMessage, 0)
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, nil, newUnmarshalError(name, err)
+	}
+
+	return data, records, nil
 }
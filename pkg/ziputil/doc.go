@@ -5,5 +5,5 @@
 //
 // =================================================================
 
-// Package ziputil includes tools for reading and writing from zip files.
+// Package ziputil includes tools for reading and writing from zip files and other file systems.
 package ziputil
@@ -0,0 +1,70 @@
+// =================================================================
+//
+// Work of the U.S. Department of Defense, Defense Digital Service.
+// Released as open source under the MIT License.  See LICENSE file.
+//
+// =================================================================
+
+package ziputil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+var (
+	// ErrMissingFile is matched by errors.Is when a file does not exist in the file system.
+	ErrMissingFile = errors.New("missing file")
+	// ErrInvalidJSON is matched by errors.Is when a file cannot be unmarshaled from JSON.
+	ErrInvalidJSON = errors.New("invalid JSON")
+)
+
+const (
+	opRead      = "reading"
+	opUnmarshal = "unmarshaling"
+)
+
+// FileError is the error of reading or unmarshaling a file.
+// Use errors.As to get the name of the file and the byte offset of the invalid JSON.
+type FileError struct {
+	Op     string // the operation that failed, either "reading" or "unmarshaling"
+	Name   string // the name of the file in the file system
+	Offset int64  // the byte offset in the file where the JSON is invalid, or zero if unknown
+	Err    error  // the underlying error
+}
+
+func newUnmarshalError(name string, err error) *FileError {
+	fe := &FileError{Op: opUnmarshal, Name: name, Err: err}
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &syntaxError) {
+		fe.Offset = syntaxError.Offset
+	} else if errors.As(err, &typeError) {
+		fe.Offset = typeError.Offset
+	}
+	return fe
+}
+
+func (e *FileError) Error() string {
+	if e.Offset > 0 {
+		return fmt.Sprintf("error %s file %q at byte %d: %s", e.Op, e.Name, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("error %s file %q: %s", e.Op, e.Name, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches ErrMissingFile or ErrInvalidJSON.
+func (e *FileError) Is(target error) bool {
+	switch target {
+	case ErrMissingFile:
+		return e.Op == opRead && errors.Is(e.Err, fs.ErrNotExist)
+	case ErrInvalidJSON:
+		return e.Op == opUnmarshal
+	}
+	return false
+}